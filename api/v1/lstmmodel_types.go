@@ -0,0 +1,124 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LSTMModelSpec defines the desired state of LSTMModel
+type LSTMModelSpec struct {
+	// 模型版本，LSTMPredictApp引用的模型版本变化时会重新部署（或热更新）模型
+	// +kubebuilder:validation:MinLength=1
+	Version string `json:"version"`
+	// 模型文件的地址，支持以下几种形式：
+	// http(s)://host/path、oci://registry/repository:tag、pvc://claimName/path、
+	// configmap://name/key、secret://name/key
+	// +kubebuilder:validation:MinLength=1
+	ArtifactURI string `json:"artifactURI"`
+	// 模型文件的sha256校验和（十六进制），http(s)来源必须提供
+	// +kubebuilder:validation:Pattern=`^[a-fA-F0-9]{64}$`
+	// +optional
+	SHA256 string `json:"sha256,omitempty"`
+	// 模型输入的时间窗口长度，即每次预测需要的历史数据点数
+	// +kubebuilder:validation:Minimum=1
+	InputWindowLength int32 `json:"inputWindowLength"`
+	// 模型输入的特征名称，顺序与模型训练时一致
+	// +kubebuilder:validation:MinItems=1
+	FeatureNames []string `json:"featureNames"`
+	// 模型一次预测输出的未来数据点数
+	// +kubebuilder:validation:Minimum=1
+	ForecastHorizon int32 `json:"forecastHorizon"`
+	// 模型在验证集上的评估指标
+	// +optional
+	Metrics *LSTMModelMetrics `json:"metrics,omitempty"`
+}
+
+// LSTMModelMetrics 模型的评估指标，以十进制字符串表示，避免在CRD中使用浮点数
+type LSTMModelMetrics struct {
+	// 均方根误差
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	// +optional
+	RMSE string `json:"rmse,omitempty"`
+	// 平均绝对百分比误差（百分数）
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	// +optional
+	MAPE string `json:"mape,omitempty"`
+}
+
+// LSTMModelStatus defines the observed state of LSTMModel.
+type LSTMModelStatus struct {
+	// conditions represent the current state of the LSTMModel resource.
+	// "Validated" reports whether the spec of the model has passed validation.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// observedGeneration是控制器最近一次校验过的LSTMModel的metadata.generation
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Ready或Failed
+	// +optional
+	Phase string `json:"phase,omitempty"`
+}
+
+// LSTMModel的状态条件类型
+const (
+	// ConditionTypeValidated 表示LSTMModel的Spec已经通过校验，可以被LSTMPredictApp引用
+	ConditionTypeValidated = "Validated"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=lstmmodels,singular=lstmmodel,scope=Namespaced,shortName=lstmm
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.spec.version`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="RMSE",type=string,JSONPath=`.spec.metrics.rmse`,priority=1
+// +kubebuilder:printcolumn:name="MAPE",type=string,JSONPath=`.spec.metrics.mape`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// LSTMModel is the Schema for the lstmmodels API
+type LSTMModel struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty,omitzero"`
+
+	// spec defines the desired state of LSTMModel
+	// +required
+	Spec LSTMModelSpec `json:"spec"`
+
+	// status defines the observed state of LSTMModel
+	// +optional
+	Status LSTMModelStatus `json:"status,omitempty,omitzero"`
+}
+
+// +kubebuilder:object:root=true
+
+// LSTMModelList contains a list of LSTMModel
+type LSTMModelList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []LSTMModel `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&LSTMModel{}, &LSTMModelList{})
+}
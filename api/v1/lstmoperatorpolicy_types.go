@@ -0,0 +1,110 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LSTMOperatorPolicySpec defines the validation and defaulting policy the LSTMPredictApp webhook applies at admission time.
+// 未设置的字段沿用上一层的值：命名空间策略 > 集群默认策略（namespaces为空） > Operator内置的默认值
+// +kubebuilder:validation:XValidation:rule="!has(self.minReplicas) || !has(self.maxReplicas) || self.minReplicas <= self.maxReplicas",message="minReplicas can't > maxReplicas"
+type LSTMOperatorPolicySpec struct {
+	// 策略生效的命名空间，为空时为集群默认策略；同一命名空间匹配多个策略时按名字顺序，后者覆盖前者
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// 副本数的下限与上限，同样约束通过/scale子资源设置的副本数
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxReplicas *int32 `json:"maxReplicas,omitempty"`
+	// 未指定spec.backendAppReplicas时注入的副本数
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	DefaultReplicas *int32 `json:"defaultReplicas,omitempty"`
+
+	// 未指定spec.servicePort时注入的Service端口
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	DefaultServicePort *int32 `json:"defaultServicePort,omitempty"`
+	// Service端口须小于该值
+	// +kubebuilder:validation:Minimum=2
+	// +kubebuilder:validation:Maximum=65536
+	// +optional
+	MaxServicePort *int32 `json:"maxServicePort,omitempty"`
+	// 未指定spec.serviceType时注入的Service类型，须在allowedServiceTypes中
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer
+	// +optional
+	DefaultServiceType corev1.ServiceType `json:"defaultServiceType,omitempty"`
+	// 允许使用的Service类型
+	// +optional
+	AllowedServiceTypes []corev1.ServiceType `json:"allowedServiceTypes,omitempty"`
+
+	// 允许使用的镜像仓库前缀，如registry.example.com或registry.example.com/forecast，为空时不限制；
+	// 不带仓库地址的镜像按docker.io处理
+	// +optional
+	AllowedImageRegistries []string `json:"allowedImageRegistries,omitempty"`
+
+	// 未指定spec.resourcesLimit时注入的资源请求与限制
+	// +optional
+	DefaultResources *corev1.ResourceRequirements `json:"defaultResources,omitempty"`
+	// 每种资源的请求与限制都不能超过的值
+	// +optional
+	MaxResources corev1.ResourceList `json:"maxResources,omitempty"`
+
+	// LSTMPredictApp必须带有的标签（如team、cost-center），值不能为空
+	// +optional
+	RequiredLabels []string `json:"requiredLabels,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=lstmoperatorpolicies,singular=lstmoperatorpolicy,scope=Cluster,shortName=lstmpol
+// +kubebuilder:printcolumn:name="Namespaces",type=string,JSONPath=`.spec.namespaces`
+// +kubebuilder:printcolumn:name="Max Replicas",type=integer,JSONPath=`.spec.maxReplicas`
+// +kubebuilder:printcolumn:name="Registries",type=string,JSONPath=`.spec.allowedImageRegistries`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// LSTMOperatorPolicy is the Schema for the lstmoperatorpolicies API
+type LSTMOperatorPolicy struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty,omitzero"`
+
+	// spec defines the policy applied to LSTMPredictApps
+	// +required
+	Spec LSTMOperatorPolicySpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// LSTMOperatorPolicyList contains a list of LSTMOperatorPolicy
+type LSTMOperatorPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []LSTMOperatorPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&LSTMOperatorPolicy{}, &LSTMOperatorPolicyList{})
+}
@@ -18,7 +18,9 @@ package v1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -44,6 +46,422 @@ type LSTMPredictAppSpec struct {
 	ServicePort int32 `json:"servicePort,omitempty"`
 	// 必填项，但是用户可以不提供，由Webhook进行默认注入
 	ServiceType corev1.ServiceType `json:"serviceType,omitempty"`
+	// 预测服务暴露的多个命名端口（如REST、gRPC、指标），同时设置在容器与Service上。
+	// 设置后不能再使用ContainerPort与ServicePort，这两个字段是只有一个端口时的简写；
+	// 第一个端口是主端口，探针、模型热更新、路由对象以及status.serviceEndPoint都使用主端口
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Ports []PortSpec `json:"ports,omitempty"`
+	// 创建不分配ClusterIP的Headless Service（clusterIP: None），只能与ClusterIP类型一起使用，DNS直接解析到各个Pod
+	// +optional
+	Headless bool `json:"headless,omitempty"`
+	// LoadBalancer类型Service的设置，只能与LoadBalancer类型一起使用
+	// +optional
+	LoadBalancer *LoadBalancerSpec `json:"loadBalancer,omitempty"`
+	// 训练好的LSTM模型文件的来源，为空时模型需要打包在AppImage中
+	// +optional
+	Model *ModelSpec `json:"model,omitempty"`
+	// 引用同一命名空间中的LSTMModel，模型的来源与版本由LSTMModel决定。
+	// 与spec.model同时设置时，spec.model中不能再指定模型来源和版本，只能设置mountPath和reloadEndpoint
+	// +optional
+	ModelRef *LSTMModelReference `json:"modelRef,omitempty"`
+	// 新版本（镜像、模型等Pod模板的变化）的发布策略，为空时使用Deployment自身的滚动更新
+	// +optional
+	Rollout *RolloutSpec `json:"rollout,omitempty"`
+	// 自动扩缩容，设置后控制器为Deployment创建HorizontalPodAutoscaler，副本数由HPA决定，不再使用BackendAppReplicas
+	// +optional
+	Autoscaling *AutoscalingSpec `json:"autoscaling,omitempty"`
+	// 节点排空等自愿中断时保护预测服务的PodDisruptionBudget，副本数大于1时可以为空，由Webhook进行默认注入；
+	// 副本数降到1时控制器删除PodDisruptionBudget，否则唯一的Pod永远无法被驱逐
+	// +optional
+	Disruption *DisruptionSpec `json:"disruption,omitempty"`
+	// 通过Ingress或者Gateway API的HTTPRoute对集群外暴露预测接口，为空时只通过Service暴露
+	// +optional
+	Expose *ExposeSpec `json:"expose,omitempty"`
+	// 限制哪些命名空间与Pod可以访问预测服务，设置后控制器创建选中应用Pod的NetworkPolicy，为空时不限制
+	// +optional
+	NetworkPolicy *NetworkPolicySpec `json:"networkPolicy,omitempty"`
+	// 让Prometheus抓取预测服务自身的指标，开启后控制器创建ServiceMonitor，需要集群中安装了Prometheus Operator
+	// +optional
+	Monitoring *MonitoringSpec `json:"monitoring,omitempty"`
+	// 预测容器的存活、就绪与启动探针，均以HTTP GET访问ContainerPort上的健康检查路径，可以为空，由Webhook进行默认注入
+	// +optional
+	Probes *ProbesSpec `json:"probes,omitempty"`
+	// 删除保护，开启后Webhook拒绝删除该LSTMPredictApp，须先关闭才能删除
+	// +optional
+	DeletionProtection bool `json:"deletionProtection,omitempty"`
+	// 删除LSTMPredictApp之前执行的清理动作，为空时只摘除流量
+	// +optional
+	Cleanup *CleanupSpec `json:"cleanup,omitempty"`
+}
+
+// PortSpec 描述预测服务的一个命名端口
+type PortSpec struct {
+	// 端口名，在容器与Service中使用，须为IANA_SVC_NAME格式（小写字母、数字与'-'，不超过15个字符）
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	ContainerPort int32 `json:"containerPort"`
+	// Service上的端口，为空时与containerPort相同
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	ServicePort int32 `json:"servicePort,omitempty"`
+	// 为空时为TCP
+	// +kubebuilder:validation:Enum=TCP;UDP;SCTP
+	// +optional
+	Protocol corev1.Protocol `json:"protocol,omitempty"`
+	// 应用层协议，例如http、kubernetes.io/h2c、grpc，供服务网格与Ingress控制器识别
+	// +optional
+	AppProtocol *string `json:"appProtocol,omitempty"`
+}
+
+// MonitoringSpec 描述抓取预测服务指标的ServiceMonitor
+type MonitoringSpec struct {
+	// 开启后创建与LSTMPredictApp同名的ServiceMonitor，关闭后删除
+	Enabled bool `json:"enabled"`
+	// 暴露指标的端口名（spec.ports中的name），为空时使用名为metrics的端口，没有时使用主端口
+	// +optional
+	Port string `json:"port,omitempty"`
+	// 指标接口路径，可以为空，由Webhook进行默认注入
+	// +optional
+	Path string `json:"path,omitempty"`
+	// 抓取间隔，为空时使用Prometheus的全局配置
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// 添加到ServiceMonitor上的标签，用于匹配Prometheus的serviceMonitorSelector
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// NetworkPolicySpec 描述允许访问预测服务全部端口的来源，未列出的来源都会被拒绝。
+// 监控系统、Ingress控制器等也需要列在其中
+type NetworkPolicySpec struct {
+	// 允许访问的来源，至少指定一个
+	// +kubebuilder:validation:MinItems=1
+	From []NetworkPolicyPeer `json:"from"`
+	// 开启监控时允许访问指标端口的来源，如Prometheus所在的命名空间；
+	// 为空且指标使用单独的端口时，任何来源都可以访问指标端口，指标使用主端口时只有from中的来源可以访问
+	// +optional
+	MetricsFrom []NetworkPolicyPeer `json:"metricsFrom,omitempty"`
+}
+
+// NetworkPolicyPeer 描述一类允许访问的来源。只指定podSelector时为同一命名空间中的Pod；
+// 同时指定命名空间与podSelector时为这些命名空间中的匹配的Pod
+type NetworkPolicyPeer struct {
+	// 命名空间的名字，是namespaceSelector匹配kubernetes.io/metadata.name标签的简写
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// 按标签选择命名空间，空选择器表示全部命名空间
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// 按标签选择Pod
+	// +optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+}
+
+// DisruptionSpec 描述PodDisruptionBudget，minAvailable与maxUnavailable只能指定一个，可以是整数或者百分比
+type DisruptionSpec struct {
+	// 自愿中断期间至少保持可用的Pod数量
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+	// 自愿中断期间最多不可用的Pod数量
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// LoadBalancerSpec 描述LoadBalancer类型Service的设置
+type LoadBalancerSpec struct {
+	// 负责实现该Service的负载均衡器，为空时由云厂商默认的实现处理
+	// +optional
+	LoadBalancerClass *string `json:"loadBalancerClass,omitempty"`
+	// 允许访问负载均衡器的客户端网段（CIDR），为空时不限制
+	// +optional
+	SourceRanges []string `json:"sourceRanges,omitempty"`
+	// 添加到Service上的注解，例如云厂商负载均衡器的配置
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ExposeType 对外暴露预测接口的方式
+// +kubebuilder:validation:Enum=Ingress;Gateway
+type ExposeType string
+
+const (
+	// ExposeTypeIngress 创建networking.k8s.io/v1 Ingress
+	ExposeTypeIngress ExposeType = "Ingress"
+	// ExposeTypeGateway 创建gateway.networking.k8s.io/v1 HTTPRoute，挂载到已有的Gateway上
+	ExposeTypeGateway ExposeType = "Gateway"
+)
+
+// ExposeSpec 描述对外暴露预测接口的路由对象，路由对象与LSTMPredictApp同名，由控制器创建并拥有，
+// 后端指向LSTMPredictApp的Service
+type ExposeSpec struct {
+	// +kubebuilder:validation:Required
+	Type ExposeType `json:"type"`
+	// 对外暴露的域名
+	// +kubebuilder:validation:Required
+	Host string `json:"host"`
+	// 路径前缀，为空时为"/"
+	// +optional
+	Path string `json:"path,omitempty"`
+	// 存放TLS证书的Secret，只用于Ingress；Gateway的TLS由其监听器终止
+	// +optional
+	TLSSecretName string `json:"tlsSecretName,omitempty"`
+	// 添加到路由对象上的注解，例如Ingress控制器的配置
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Ingress使用的IngressClass，为空时使用集群默认的IngressClass
+	// +optional
+	IngressClassName *string `json:"ingressClassName,omitempty"`
+	// HTTPRoute挂载的Gateway，type为Gateway时必填
+	// +optional
+	Gateway *GatewayReference `json:"gateway,omitempty"`
+}
+
+// GatewayReference 引用HTTPRoute挂载的Gateway及其监听器
+type GatewayReference struct {
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+	// Gateway所在的命名空间，为空时与LSTMPredictApp相同
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// 监听器的名字，为空时挂载到所有允许的监听器
+	// +optional
+	SectionName string `json:"sectionName,omitempty"`
+	// 监听器终止TLS时设为true，status.serviceEndPoint中报告https地址
+	// +optional
+	TLS bool `json:"tls,omitempty"`
+}
+
+// ProbesSpec 描述预测容器的探针。启动探针在模型加载完成之前屏蔽存活与就绪探针，
+// 就绪探针通过之前Service不会把流量转发给该Pod
+type ProbesSpec struct {
+	// 关闭全部探针
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+	// 健康检查接口路径，存活与启动探针使用，可以为空，由Webhook进行默认注入
+	// +optional
+	Path string `json:"path,omitempty"`
+	// 就绪检查接口路径，为空时与path相同
+	// +optional
+	ReadinessPath string `json:"readinessPath,omitempty"`
+	// 以下三项可以为空，由Webhook进行默认注入
+	// +optional
+	Liveness *ProbeTiming `json:"liveness,omitempty"`
+	// +optional
+	Readiness *ProbeTiming `json:"readiness,omitempty"`
+	// 启动探针允许的最长时间为periodSeconds * failureThreshold，应覆盖模型加载的耗时
+	// +optional
+	Startup *ProbeTiming `json:"startup,omitempty"`
+}
+
+// ProbeTiming 探针的检查周期、超时与失败次数
+type ProbeTiming struct {
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	InitialDelaySeconds int32 `json:"initialDelaySeconds,omitempty"`
+	// +kubebuilder:validation:Minimum=1
+	PeriodSeconds int32 `json:"periodSeconds"`
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds int32 `json:"timeoutSeconds"`
+	// +kubebuilder:validation:Minimum=1
+	FailureThreshold int32 `json:"failureThreshold"`
+}
+
+// CleanupSpec 描述删除LSTMPredictApp之前需要执行的清理动作。
+// 控制器先删除Service摘除流量，再依次执行以下动作，全部成功后才移除finalizer，失败时会重试
+type CleanupSpec struct {
+	// 预测服务提供的归档预测日志的接口路径（如/archive），监听在ContainerPort上，控制器逐个调用就绪Pod的该接口
+	// +optional
+	ArchiveEndpoint string `json:"archiveEndpoint,omitempty"`
+	// 外部模型缓存的地址，控制器向该地址发送HTTP DELETE请求，返回2xx或404视为成功
+	// +optional
+	ModelCacheURL string `json:"modelCacheURL,omitempty"`
+}
+
+// AutoscalingSpec 描述HorizontalPodAutoscaler的副本范围与扩缩容指标，至少需要指定一个指标
+type AutoscalingSpec struct {
+	// 可以为空，由Webhook进行默认注入
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+	// 目标CPU平均使用率（相对于requests的百分比）
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetCPUUtilizationPercentage *int32 `json:"targetCPUUtilizationPercentage,omitempty"`
+	// 目标内存平均使用率（相对于requests的百分比）
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetMemoryUtilizationPercentage *int32 `json:"targetMemoryUtilizationPercentage,omitempty"`
+	// Pods类型的自定义指标，如每秒请求数，需要集群中部署了custom metrics API的实现
+	// +optional
+	CustomMetrics []CustomMetricTarget `json:"customMetrics,omitempty"`
+}
+
+// CustomMetricTarget 按每个Pod的平均值扩缩容的自定义指标
+type CustomMetricTarget struct {
+	// 指标名称，如http_requests_per_second
+	Name string `json:"name"`
+	// 每个Pod的目标平均值
+	TargetAverageValue resource.Quantity `json:"targetAverageValue"`
+}
+
+// RolloutStrategy 新版本的发布策略
+// +kubebuilder:validation:Enum=RollingUpdate;Canary;BlueGreen
+type RolloutStrategy string
+
+const (
+	// RolloutStrategyRollingUpdate 直接修改Deployment，由Deployment滚动更新全部副本
+	RolloutStrategyRollingUpdate RolloutStrategy = "RollingUpdate"
+	// RolloutStrategyCanary 先创建"<name>-canary" Deployment承接部分流量，按步骤逐步提升权重，最后再更新全部副本
+	RolloutStrategyCanary RolloutStrategy = "Canary"
+	// RolloutStrategyBlueGreen 先创建与稳定版本副本数相同的"<name>-green" Deployment，并通过"<name>-preview" Service单独暴露，
+	// 晋升后主Service切换到绿色版本，稳定版本更新完成后再切换回来并删除绿色版本
+	RolloutStrategyBlueGreen RolloutStrategy = "BlueGreen"
+)
+
+// RolloutSpec 描述新版本的发布策略
+type RolloutSpec struct {
+	// 可以为空，由Webhook进行默认注入
+	// +optional
+	Strategy RolloutStrategy `json:"strategy,omitempty"`
+	// strategy为Canary时必填
+	// +optional
+	Canary *CanaryStrategy `json:"canary,omitempty"`
+	// strategy为BlueGreen时可选
+	// +optional
+	BlueGreen *BlueGreenStrategy `json:"blueGreen,omitempty"`
+}
+
+// BlueGreenStrategy 描述蓝绿发布的晋升方式。
+// 绿色版本就绪后，给LSTMPredictApp加上"lstmapps.wuyong7240.com/promote: true"注解即可手动晋升
+type BlueGreenStrategy struct {
+	// 绿色版本就绪后经过该时间自动晋升，为空表示只能通过注解手动晋升
+	// +optional
+	AutoPromoteAfter *metav1.Duration `json:"autoPromoteAfter,omitempty"`
+}
+
+// CanaryStrategy 描述金丝雀发布的步骤以及中止条件。
+// 金丝雀与稳定版本的Pod都被同一个Service选中，流量权重通过两者的副本数比例近似实现
+type CanaryStrategy struct {
+	// 金丝雀的权重步骤，权重须严格递增，最后一步结束后全量发布新版本
+	// +kubebuilder:validation:MinItems=1
+	Steps []CanaryStep `json:"steps"`
+	// 可选的指标分析，每一步都会检查，超过阈值时中止发布
+	// +optional
+	Analysis *CanaryAnalysis `json:"analysis,omitempty"`
+}
+
+// CanaryStep 金丝雀发布中的一步
+type CanaryStep struct {
+	// 金丝雀承接的流量百分比
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	Weight int32 `json:"weight"`
+	// 金丝雀就绪后在该权重上停留的时间，为空表示就绪后立即进入下一步
+	// +optional
+	Pause metav1.Duration `json:"pause,omitempty"`
+}
+
+// CanaryAnalysis 通过Prometheus即时查询判断金丝雀是否健康
+type CanaryAnalysis struct {
+	// Prometheus的地址，如http://prometheus.monitoring.svc:9090
+	PrometheusURL string `json:"prometheusURL"`
+	// PromQL查询语句，结果应为单个数值，如金丝雀的错误率
+	Query string `json:"query"`
+	// 查询结果的上限（十进制字符串），超过时中止发布
+	// +kubebuilder:validation:Pattern=`^-?[0-9]+(\.[0-9]+)?$`
+	Threshold string `json:"threshold"`
+}
+
+// LSTMModelReference 引用同一命名空间中的LSTMModel
+type LSTMModelReference struct {
+	// LSTMModel的名字
+	Name string `json:"name"`
+}
+
+// ModelSpec 描述训练好的LSTM模型文件从哪里来，以及挂载到预测容器中的位置。
+// PVC、ConfigMap、Secret、OCI、HTTP五种来源必须且只能指定一种
+type ModelSpec struct {
+	// 模型版本，仅用于标识，通过Pod注解和环境变量MODEL_VERSION告知预测服务
+	// +optional
+	Version string `json:"version,omitempty"`
+	// 模型文件在预测容器中的挂载目录，可以为空，由Webhook进行默认注入
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+	// 预测服务提供的模型热更新接口路径（如/reload），监听在ContainerPort上。
+	// 设置后，仅Version发生变化时控制器会并发调用就绪Pod的该接口完成热更新，而不是滚动重启；
+	// HTTP与OCI来源的新版本先由Sidecar暂存到挂载目录下按版本区分的子目录，此时来源随Version一起变化也不会滚动重启；
+	// 热更新失败或暂存超时时退化为滚动重启。Version不变而来源变化时仍然滚动重启
+	// +optional
+	ReloadEndpoint string `json:"reloadEndpoint,omitempty"`
+
+	// 模型存放在PVC中
+	// +optional
+	PVC *PVCModelSource `json:"pvc,omitempty"`
+	// 模型存放在ConfigMap中，适用于体积较小的模型
+	// +optional
+	ConfigMap *ObjectModelSource `json:"configMap,omitempty"`
+	// 模型存放在Secret中
+	// +optional
+	Secret *ObjectModelSource `json:"secret,omitempty"`
+	// 模型以OCI Artifact的形式存放在镜像仓库中，由Init容器拉取
+	// +optional
+	OCI *OCIModelSource `json:"oci,omitempty"`
+	// 模型通过HTTP下载，由Init容器下载并校验
+	// +optional
+	HTTP *HTTPModelSource `json:"http,omitempty"`
+}
+
+// PVCModelSource 描述存放在PVC中的模型
+type PVCModelSource struct {
+	// PVC的名字，须与LSTMPredictApp在同一命名空间
+	ClaimName string `json:"claimName"`
+	// 模型文件（或目录）在PVC中的相对路径，为空表示PVC根目录
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// ObjectModelSource 描述存放在ConfigMap或Secret中的模型
+type ObjectModelSource struct {
+	// ConfigMap或Secret的名字，须与LSTMPredictApp在同一命名空间
+	Name string `json:"name"`
+	// 模型文件对应的Key，为空表示将所有Key都挂载为文件
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// OCIModelSource 描述以OCI Artifact形式分发的模型
+type OCIModelSource struct {
+	// 模型Artifact的引用，如registry.example.com/models/lstm:v3
+	Artifact string `json:"artifact"`
+	// 模型文件在Artifact中的相对路径，为空表示Artifact中的所有文件
+	// +optional
+	Path string `json:"path,omitempty"`
+	// 执行拉取的Init容器镜像，需要包含oras命令，可以为空，由Webhook进行默认注入
+	// +optional
+	PullerImage string `json:"pullerImage,omitempty"`
+}
+
+// HTTPModelSource 描述通过HTTP下载的模型
+type HTTPModelSource struct {
+	// 模型文件的下载地址，仅支持http和https
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+	// 模型文件的sha256校验和（十六进制），下载后校验不通过则Pod无法启动
+	// +kubebuilder:validation:Pattern=`^[a-fA-F0-9]{64}$`
+	SHA256 string `json:"sha256"`
+	// 模型文件保存的文件名，为空时取URL路径的最后一段
+	// +optional
+	FileName string `json:"fileName,omitempty"`
+	// 执行下载的Init容器镜像，需要包含wget和sha256sum命令，可以为空，由Webhook进行默认注入
+	// +optional
+	DownloaderImage string `json:"downloaderImage,omitempty"`
 }
 
 // LSTMPredictAppStatus defines the observed state of LSTMPredictApp.
@@ -63,16 +481,182 @@ type LSTMPredictAppStatus struct {
 	// - "Degraded": the resource failed to reach or maintain its desired state
 	//
 	// The status of each condition is one of True, False, or Unknown.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// observedGeneration是控制器最近一次处理过的LSTMPredictApp的metadata.generation，
+	// 用于判断控制器是否已经看到了最新的Spec
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// readyReplicas是稳定版本Deployment中已经就绪的副本数
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// serviceEndPoint是预测服务的访问地址，设置了spec.expose时为对集群外暴露的URL
 	// +optional
-	ReadyReplicas   int32       `json:"readyReplicas,omitempty"`
 	ServiceEndPoint string      `json:"serviceEndPoint,omitempty"`
 	Phase           string      `json:"phase,omitempty"`
 	LastUpdateTime  metav1.Time `json:"lastUpdateTime,omitempty"`
+
+	// message汇总应用没有正常运行的原因（取自最主要的异常状态条件），一切正常时为空
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// selector是预测服务Pod的标签选择器（字符串形式），供/scale子资源使用，HPA、KEDA据此找到Pod
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
+	// 模型的加载情况，仅在spec.model.version不为空时记录
+	// +optional
+	Model *ModelStatus `json:"model,omitempty"`
+
+	// 最近一次金丝雀等发布的进度
+	// +optional
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+
+	// 启用自动扩缩容时，HPA观察到的当前副本数与计算出的期望副本数
+	// +optional
+	Autoscaling *AutoscalingStatus `json:"autoscaling,omitempty"`
+
+	// 最近一次达到Running的Pod模板版本，对应名为"<name>-<revision>"的ControllerRevision
+	// +optional
+	LastGoodRevision string `json:"lastGoodRevision,omitempty"`
+	// 最近一次自动回滚的记录，Spec再次变化后清除
+	// +optional
+	Rollback *RollbackStatus `json:"rollback,omitempty"`
+}
+
+// AutoscalingStatus 来自HorizontalPodAutoscaler的副本数
+type AutoscalingStatus struct {
+	CurrentReplicas int32 `json:"currentReplicas"`
+	DesiredReplicas int32 `json:"desiredReplicas"`
+	// +optional
+	LastScaleTime *metav1.Time `json:"lastScaleTime,omitempty"`
+}
+
+// RollbackStatus 记录一次自动回滚
+type RollbackStatus struct {
+	// 未能就绪而被回滚的Pod模板版本，Spec仍然对应该版本时不会再次应用
+	FailedRevision string `json:"failedRevision"`
+	// 回滚到的Pod模板版本
+	Revision string `json:"revision"`
+	// 回滚的时间
+	Time metav1.Time `json:"time"`
+	// 回滚的原因
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// RolloutPhase 发布所处的阶段
+type RolloutPhase string
+
+const (
+	// RolloutPhaseProgressing 正在按步骤发布
+	RolloutPhaseProgressing RolloutPhase = "Progressing"
+	// RolloutPhasePromoting 所有步骤已经通过，正在将新版本更新到全部副本
+	RolloutPhasePromoting RolloutPhase = "Promoting"
+	// RolloutPhasePromoted 新版本已经全量发布
+	RolloutPhasePromoted RolloutPhase = "Promoted"
+	// RolloutPhaseAborted 发布被中止，保持稳定版本，直到Pod模板再次发生变化
+	RolloutPhaseAborted RolloutPhase = "Aborted"
+)
+
+// RolloutStatus 记录一次发布的进度
+type RolloutStatus struct {
+	Strategy RolloutStrategy `json:"strategy,omitempty"`
+	Phase    RolloutPhase    `json:"phase,omitempty"`
+	// 正在发布的Pod模板的哈希
+	Revision string `json:"revision,omitempty"`
+	// 当前所在的步骤（从0开始）以及实际的流量权重
+	CurrentStep   int32 `json:"currentStep,omitempty"`
+	CurrentWeight int32 `json:"currentWeight,omitempty"`
+	// 当前步骤的开始时间，蓝绿发布时为绿色版本就绪的时间
+	// +optional
+	StepStartTime *metav1.Time `json:"stepStartTime,omitempty"`
+	// 蓝绿发布中暴露绿色版本的预览Service
+	// +optional
+	PreviewService string `json:"previewService,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+	// 本次发布每一步的记录
+	// +optional
+	Steps []RolloutStepStatus `json:"steps,omitempty"`
+}
+
+// RolloutStepStatus 发布中一步的记录
+type RolloutStepStatus struct {
+	Step      int32       `json:"step"`
+	Weight    int32       `json:"weight"`
+	StartTime metav1.Time `json:"startTime"`
+	// Running、Passed或Failed
+	Result string `json:"result"`
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ModelStatus 记录期望的模型版本以及每个Pod实际加载的模型版本
+type ModelStatus struct {
+	// 期望加载的模型版本
+	// +optional
+	Version string `json:"version,omitempty"`
+	// 每个Pod当前加载的模型版本
+	// +listType=map
+	// +listMapKey=podName
+	// +optional
+	Pods []PodModelStatus `json:"pods,omitempty"`
 }
 
+// PodModelStatus 记录单个Pod加载的模型版本
+type PodModelStatus struct {
+	PodName string `json:"podName"`
+	// Pod当前加载的模型版本
+	// +optional
+	Version string `json:"version,omitempty"`
+	// 最近一次成功热更新的时间
+	// +optional
+	LastReloadTime *metav1.Time `json:"lastReloadTime,omitempty"`
+	// 开始暂存新版本模型的时间，暂存超时后仍无法热更新则退化为滚动重启
+	// +optional
+	StagingStartTime *metav1.Time `json:"stagingStartTime,omitempty"`
+	// 最近一次热更新失败的原因
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// LSTMPredictApp的状态条件类型
+const (
+	// ConditionTypeAvailable 表示后端Deployment的副本已经全部就绪，应用可以正常提供预测服务
+	ConditionTypeAvailable = "Available"
+	// ConditionTypeProgressing 表示后端Deployment正在创建或滚动更新
+	ConditionTypeProgressing = "Progressing"
+	// ConditionTypeDegraded 表示后端Deployment未能达到或维持期望状态
+	ConditionTypeDegraded = "Degraded"
+	// ConditionTypeServiceReady 表示对外暴露预测服务的Service已经就绪
+	ConditionTypeServiceReady = "ServiceReady"
+	// ConditionTypeModelResolved 表示spec.modelRef引用的LSTMModel存在且已经通过校验
+	ConditionTypeModelResolved = "ModelResolved"
+	// ConditionTypeSynced 表示Deployment与Service已经通过服务端应用写入，没有与其他字段管理者冲突
+	ConditionTypeSynced = "Synced"
+	// ConditionTypeMonitored 表示spec.monitoring要求的ServiceMonitor已经创建，未开启监控时没有该条件
+	ConditionTypeMonitored = "Monitored"
+)
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.backendAppReplicas,statuspath=.status.readyReplicas,selectorpath=.status.selector
 // +kubebuilder:resource:path=lstmpredictapps,singular=lstmpredictapp,scope=Namespaced,shortName=lstmpa
+// +kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.spec.appImage`
+// +kubebuilder:printcolumn:name="Desired",type=integer,JSONPath=`.spec.backendAppReplicas`
+// +kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.serviceType`
+// +kubebuilder:printcolumn:name="Endpoint",type=string,JSONPath=`.status.serviceEndPoint`
+// +kubebuilder:printcolumn:name="Model",type=string,JSONPath=`.status.model.version`
+// +kubebuilder:printcolumn:name="Message",type=string,JSONPath=`.status.message`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // LSTMPredictApp is the Schema for the lstmpredictapps API
 type LSTMPredictApp struct {
@@ -0,0 +1,164 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PredictiveScalerSpec defines the desired state of PredictiveScaler
+// +kubebuilder:validation:XValidation:rule="!has(self.minReplicas) || self.minReplicas <= self.maxReplicas",message="minReplicas can't > maxReplicas"
+type PredictiveScalerSpec struct {
+	// 提供预测的LSTMPredictApp，须与PredictiveScaler在同一命名空间
+	PredictAppRef LSTMPredictAppReference `json:"predictAppRef"`
+	// 被扩缩容的工作负载，须与PredictiveScaler在同一命名空间
+	TargetRef ScaleTargetReference `json:"targetRef"`
+	// 预测接口的完整地址，为空时使用LSTMPredictApp的Service："http://<name>.<namespace>.svc:<servicePort>/predict"，Headless Service使用containerPort
+	// +optional
+	PredictEndpoint string `json:"predictEndpoint,omitempty"`
+	// 作为模型输入的历史指标
+	History MetricHistory `json:"history"`
+	// 每个副本能够承载的指标值（如每个Pod每秒处理的请求数），期望副本数 = ceil(预测值 / targetValuePerReplica)
+	TargetValuePerReplica resource.Quantity `json:"targetValuePerReplica"`
+	// 提前扩容的时间，取未来leadTime内预测值的最大值计算副本数，为空时只看下一个数据点
+	// +kubebuilder:validation:XValidation:rule="duration(self) >= duration('0s')",message="leadTime can't be negative"
+	// +optional
+	LeadTime metav1.Duration `json:"leadTime,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinReplicas int32 `json:"minReplicas,omitempty"`
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+	// 上一次扩容之后，至少经过该时间才会再次扩容
+	// +kubebuilder:validation:XValidation:rule="duration(self) >= duration('0s')",message="scaleUpCooldown can't be negative"
+	// +optional
+	ScaleUpCooldown metav1.Duration `json:"scaleUpCooldown,omitempty"`
+	// 上一次扩缩容之后，至少经过该时间才会缩容，为空时默认5分钟
+	// +kubebuilder:validation:XValidation:rule="duration(self) >= duration('0s')",message="scaleDownCooldown can't be negative"
+	// +optional
+	ScaleDownCooldown *metav1.Duration `json:"scaleDownCooldown,omitempty"`
+	// 两次预测之间的间隔，为空时默认1分钟
+	// +kubebuilder:validation:XValidation:rule="duration(self) > duration('0s')",message="interval must be positive"
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+// LSTMPredictAppReference 引用同一命名空间中的LSTMPredictApp
+type LSTMPredictAppReference struct {
+	// LSTMPredictApp的名字
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// ScaleTargetReference 被扩缩容的工作负载
+type ScaleTargetReference struct {
+	// +kubebuilder:validation:Enum=Deployment;StatefulSet
+	Kind string `json:"kind"`
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// MetricHistory 通过Prometheus范围查询获取的历史指标
+type MetricHistory struct {
+	// Prometheus的地址，如http://prometheus.monitoring.svc:9090
+	// +kubebuilder:validation:MinLength=1
+	PrometheusURL string `json:"prometheusURL"`
+	// PromQL查询语句，结果应为单条时间序列，如目标服务的每秒请求数
+	// +kubebuilder:validation:MinLength=1
+	Query string `json:"query"`
+	// 历史数据点之间的间隔，应与模型训练时的采样间隔一致
+	// +kubebuilder:validation:XValidation:rule="duration(self) > duration('0s')",message="step must be positive"
+	Step metav1.Duration `json:"step"`
+	// 历史数据点的个数，应与模型的输入窗口长度一致
+	// +kubebuilder:validation:Minimum=1
+	Points int32 `json:"points"`
+}
+
+// PredictiveScalerStatus defines the observed state of PredictiveScaler.
+type PredictiveScalerStatus struct {
+	// conditions represent the current state of the PredictiveScaler resource.
+	// "Ready" reports whether the last prediction succeeded.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// 最近一次预测的时间以及未来leadTime内预测值的最大值
+	// +optional
+	LastPredictionTime *metav1.Time `json:"lastPredictionTime,omitempty"`
+	// +optional
+	PredictedValue string `json:"predictedValue,omitempty"`
+
+	// 目标工作负载当前的副本数与根据预测计算出的副本数
+	// +optional
+	CurrentReplicas int32 `json:"currentReplicas,omitempty"`
+	// +optional
+	DesiredReplicas int32 `json:"desiredReplicas,omitempty"`
+	// 最近一次修改目标工作负载副本数的时间
+	// +optional
+	LastScaleTime *metav1.Time `json:"lastScaleTime,omitempty"`
+}
+
+// PredictiveScaler的状态条件类型
+const (
+	// ConditionTypeReady 表示最近一次获取历史指标与预测都成功了
+	ConditionTypeReady = "Ready"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=predictivescalers,singular=predictivescaler,scope=Namespaced,shortName=pscaler
+// +kubebuilder:printcolumn:name="App",type=string,JSONPath=`.spec.predictAppRef.name`
+// +kubebuilder:printcolumn:name="Target",type=string,JSONPath=`.spec.targetRef.name`
+// +kubebuilder:printcolumn:name="Predicted",type=string,JSONPath=`.status.predictedValue`
+// +kubebuilder:printcolumn:name="Current",type=integer,JSONPath=`.status.currentReplicas`
+// +kubebuilder:printcolumn:name="Desired",type=integer,JSONPath=`.status.desiredReplicas`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// PredictiveScaler is the Schema for the predictivescalers API
+type PredictiveScaler struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty,omitzero"`
+
+	// spec defines the desired state of PredictiveScaler
+	// +required
+	Spec PredictiveScalerSpec `json:"spec"`
+
+	// status defines the observed state of PredictiveScaler
+	// +optional
+	Status PredictiveScalerStatus `json:"status,omitempty,omitzero"`
+}
+
+// +kubebuilder:object:root=true
+
+// PredictiveScalerList contains a list of PredictiveScaler
+type PredictiveScalerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PredictiveScaler `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PredictiveScaler{}, &PredictiveScalerList{})
+}
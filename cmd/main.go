@@ -20,6 +20,7 @@ import (
 	"crypto/tls"
 	"flag"
 	"os"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -63,6 +64,8 @@ func main() {
 	var secureMetrics bool
 	var enableHTTP2 bool
 	var tlsOpts []func(*tls.Config)
+	var imagePolicy webhookv1.ImagePolicyOptions
+	var allowedImageRegistries, plainHTTPRegistries string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -80,6 +83,16 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&allowedImageRegistries, "allowed-image-registries", "",
+		"Comma-separated registry prefixes LSTMPredictApp and model fetcher images must come from. Empty allows any registry.")
+	flag.BoolVar(&imagePolicy.ForbidMutableTags, "forbid-mutable-image-tags", false,
+		"If set, LSTMPredictApp images must not use the latest tag or no tag unless pinned by digest.")
+	flag.BoolVar(&imagePolicy.RequireDigest, "require-image-digest", false,
+		"If set, LSTMPredictApp images must be pinned by digest.")
+	flag.BoolVar(&imagePolicy.ResolveDigests, "resolve-image-digests", false,
+		"If set, the mutating webhook resolves LSTMPredictApp image tags to digests so Deployments are pinned.")
+	flag.StringVar(&plainHTTPRegistries, "plain-http-registries", "",
+		"Comma-separated registries accessed over HTTP when resolving image digests, e.g. localhost:5000.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -87,6 +100,12 @@ func main() {
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	if allowedImageRegistries != "" {
+		imagePolicy.AllowedRegistries = strings.Split(allowedImageRegistries, ",")
+	}
+	if plainHTTPRegistries != "" {
+		imagePolicy.PlainHTTPRegistries = strings.Split(plainHTTPRegistries, ",")
+	}
 
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
@@ -180,18 +199,37 @@ func main() {
 	}
 
 	if err := (&controller.LSTMPredictAppReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("lstmpredictapp-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "LSTMPredictApp")
+		os.Exit(1)
+	}
+	if err := (&controller.LSTMModelReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "LSTMPredictApp")
+		setupLog.Error(err, "unable to create controller", "controller", "LSTMModel")
+		os.Exit(1)
+	}
+	if err := (&controller.PredictiveScalerReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PredictiveScaler")
 		os.Exit(1)
 	}
 	// nolint:goconst
 	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
-		if err := webhookv1.SetupLSTMPredictAppWebhookWithManager(mgr); err != nil {
+		if err := webhookv1.SetupLSTMPredictAppWebhookWithManager(mgr, imagePolicy); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "LSTMPredictApp")
 			os.Exit(1)
 		}
+		if err := webhookv1.SetupPredictiveScalerWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "PredictiveScaler")
+			os.Exit(1)
+		}
 	}
 	// +kubebuilder:scaffold:builder
 
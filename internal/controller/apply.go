@@ -0,0 +1,188 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// FieldManager 控制器通过服务端应用（server-side apply）写入子资源时使用的字段管理者
+const FieldManager = "lstmpredictapp-controller"
+
+// ReplicasHandoverFieldManager 启用自动扩缩容时接手Deployment副本数的字段管理者。控制器放弃副本数之前先由它持有当前值，
+// 避免控制器的服务端应用不再包含副本数时该字段被删除、恢复为默认的1
+const ReplicasHandoverFieldManager = "lstmpredictapp-replicas-handover"
+
+// applyConflictError 服务端应用时，控制器期望的字段值与其他字段管理者（HPA、kubectl edit、服务网格注入等）设置的值冲突
+type applyConflictError struct {
+	kind string
+	name string
+	err  error
+}
+
+func (e *applyConflictError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.kind, e.name, e.err)
+}
+
+func (e *applyConflictError) Unwrap() error {
+	return e.err
+}
+
+// notControlledError 同名对象已经存在但不受该LSTMPredictApp控制（如用户自己创建的对象），控制器不会接管、修改或删除它，
+// 与服务端应用的冲突一样交给调用方记录在Synced条件中
+func notControlledError(app *lstmappsv1.LSTMPredictApp, kind, name string) error {
+	return &applyConflictError{kind: kind, name: name, err: fmt.Errorf("already exists and is not controlled by LSTMPredictApp %s", app.Name)}
+}
+
+// isApplyConflict 判断错误是否为服务端应用的字段冲突
+func isApplyConflict(err error) bool {
+	var conflict *applyConflictError
+	return errors.As(err, &conflict)
+}
+
+// applyOwned 以服务端应用的方式创建或更新由LSTMPredictApp拥有的子资源，obj中只包含控制器负责的字段，
+// 其他字段归各自的管理者所有。force为false时不强制获取字段所有权，与其他管理者冲突时返回applyConflictError；
+// force为true时收回被其他管理者修改的字段，用于纠正Pod模板的漂移以及回滚
+func (r *LSTMPredictAppReconciler) applyOwned(ctx context.Context, app *lstmappsv1.LSTMPredictApp, obj client.Object, force bool) error {
+	gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+	if err != nil {
+		return err
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	obj.SetResourceVersion("")
+	obj.SetManagedFields(nil)
+	if err := ctrl.SetControllerReference(app, obj, r.Scheme); err != nil {
+		return err
+	}
+	opts := []client.PatchOption{client.FieldOwner(FieldManager)}
+	if force {
+		opts = append(opts, client.ForceOwnership)
+	}
+	if err := r.Patch(ctx, obj, client.Apply, opts...); err != nil {
+		if apierrors.IsConflict(err) {
+			return &applyConflictError{kind: gvk.Kind, name: obj.GetName(), err: err}
+		}
+		return err
+	}
+	return nil
+}
+
+// deleteOwned 删除由LSTMPredictApp拥有的对象，obj中没有设置名字时与应用同名，不会删除用户自己创建的同名对象
+func (r *LSTMPredictAppReconciler) deleteOwned(ctx context.Context, app *lstmappsv1.LSTMPredictApp, obj client.Object) error {
+	name := obj.GetName()
+	if name == "" {
+		name = app.Name
+	}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: app.Namespace, Name: name}, obj); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !metav1.IsControlledBy(obj, app) {
+		return nil
+	}
+	if err := r.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// deploymentForApply 从计算好的Deployment中取出控制器负责的字段：标签、模板版本注解、副本数、选择器与Pod模板。
+// 启用自动扩缩容时不包含副本数，副本数交给HPA管理
+func deploymentForApply(app *lstmappsv1.LSTMPredictApp, dp *appsv1.Deployment) *appsv1.Deployment {
+	applied := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      dp.Name,
+			Namespace: dp.Namespace,
+			Labels:    app.Labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": app.Name}},
+			Template: *dp.Spec.Template.DeepCopy(),
+		},
+	}
+	if !isAutoscalingEnabled(app) {
+		applied.Spec.Replicas = dp.Spec.Replicas
+	}
+	if revision := dp.Annotations[TemplateHashAnnotation]; revision != "" {
+		applied.Annotations = map[string]string{TemplateHashAnnotation: revision}
+	}
+	return applied
+}
+
+// handOverReplicas 启用自动扩缩容后，控制器仍持有Deployment副本数的所有权时，先以ReplicasHandoverFieldManager应用当前的副本数，
+// 之后控制器的服务端应用不再包含副本数时，该字段保留当前值并由HPA继续调整
+func (r *LSTMPredictAppReconciler) handOverReplicas(ctx context.Context, dp *appsv1.Deployment) error {
+	if dp.Spec.Replicas == nil || !ownsField(dp, FieldManager, "f:spec", "f:replicas") {
+		return nil
+	}
+	handover := &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: appsv1.SchemeGroupVersion.String(), Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: dp.Name, Namespace: dp.Namespace},
+		Spec:       appsv1.DeploymentSpec{Replicas: dp.Spec.Replicas},
+	}
+	if err := r.Patch(ctx, handover, client.Apply, client.FieldOwner(ReplicasHandoverFieldManager)); err != nil {
+		return err
+	}
+	log.FromContext(ctx).Info("Handed the Deployment replicas over to the HPA.", "replicas", *dp.Spec.Replicas)
+	return nil
+}
+
+// ownsField 判断manager是否通过服务端应用持有obj中path对应的字段，path为managedFields中的字段名，如"f:spec"、"f:replicas"
+func ownsField(obj metav1.Object, manager string, path ...string) bool {
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Manager != manager || entry.Operation != metav1.ManagedFieldsOperationApply || entry.FieldsV1 == nil {
+			continue
+		}
+		var fields map[string]any
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		found := true
+		for _, key := range path {
+			child, ok := fields[key].(map[string]any)
+			if !ok {
+				found = false
+				break
+			}
+			fields = child
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// updateSyncedCondition 汇总本轮调谐中服务端应用的冲突，记录在Synced条件中。
+// 冲突需要用户或者其他管理者处理，不会通过重新排队自动消除，子资源再次变化时会重新调谐
+func (r *LSTMPredictAppReconciler) updateSyncedCondition(ctx context.Context, app *lstmappsv1.LSTMPredictApp, conflicts []string) error {
+	var changed bool
+	if len(conflicts) > 0 {
+		changed = setCondition(app, lstmappsv1.ConditionTypeSynced, metav1.ConditionFalse, ReasonApplyConflict, strings.Join(conflicts, "; "))
+	} else {
+		changed = setCondition(app, lstmappsv1.ConditionTypeSynced, metav1.ConditionTrue, ReasonApplied, "All owned resources have been applied")
+	}
+	if !changed {
+		return nil
+	}
+	if err := r.Status().Update(ctx, app); err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		log.FromContext(ctx).Info("Server-side apply conflicts with other field managers.", "conflicts", conflicts)
+		r.event(app, corev1.EventTypeWarning, ReasonApplyConflict, strings.Join(conflicts, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+// conflictingServiceClient 模拟其他字段管理者修改了Service中控制器负责的字段，服务端应用Service时返回冲突
+type conflictingServiceClient struct {
+	client.Client
+}
+
+func (c *conflictingServiceClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if _, ok := obj.(*corev1.Service); ok && patch.Type() == types.ApplyPatchType {
+		return apierrors.NewConflict(schema.GroupResource{Resource: "services"}, obj.GetName(),
+			fmt.Errorf(`Apply failed with 1 conflict: conflict with "kubectl-edit" using v1: .spec.type`))
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+var _ = Describe("Server-side apply of owned resources", func() {
+	const resourceName = "apply-app"
+
+	ctx := context.Background()
+	typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+	BeforeEach(func() {
+		app := &lstmappsv1.LSTMPredictApp{
+			ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+			Spec: lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				ContainerPort:      8080,
+				BackendAppReplicas: ptr.To[int32](1),
+				ServicePort:        80,
+				ServiceType:        corev1.ServiceTypeClusterIP,
+			},
+		}
+		Expect(k8sClient.Create(ctx, app)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		deleteLSTMPredictApp(ctx, typeNamespacedName)
+		meta := metav1.ObjectMeta{Name: resourceName, Namespace: "default"}
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &appsv1.Deployment{ObjectMeta: meta}))).To(Succeed())
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &corev1.Service{ObjectMeta: meta}))).To(Succeed())
+	})
+
+	It("should report Synced when the owned resources are applied", func() {
+		controllerReconciler := &LSTMPredictAppReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).NotTo(HaveOccurred())
+
+		app := &lstmappsv1.LSTMPredictApp{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		Expect(meta.IsStatusConditionTrue(app.Status.Conditions, lstmappsv1.ConditionTypeSynced)).To(BeTrue())
+
+		dp := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, dp)).To(Succeed())
+		Expect(metav1.IsControlledBy(dp, app)).To(BeTrue())
+	})
+
+	It("should surface apply conflicts as a condition instead of requeueing", func() {
+		controllerReconciler := &LSTMPredictAppReconciler{
+			Client: &conflictingServiceClient{Client: k8sClient},
+			Scheme: k8sClient.Scheme(),
+		}
+		result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeZero())
+
+		app := &lstmappsv1.LSTMPredictApp{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		condition := meta.FindStatusCondition(app.Status.Conditions, lstmappsv1.ConditionTypeSynced)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(ReasonApplyConflict))
+		Expect(condition.Message).To(ContainSubstring("kubectl-edit"))
+	})
+})
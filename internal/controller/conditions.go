@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"fmt"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// 状态条件中使用的Reason，便于GitOps工具和kubectl按原因过滤
+const (
+	ReasonDeploymentCreated        = "DeploymentCreated"
+	ReasonMinimumReplicasAvailable = "MinimumReplicasAvailable"
+	ReasonReplicasNotReady         = "ReplicasNotReady"
+	ReasonRollingUpdate            = "RollingUpdate"
+	ReasonRolloutComplete          = "RolloutComplete"
+	ReasonProgressDeadlineExceeded = "ProgressDeadlineExceeded"
+	ReasonReplicaFailure           = "ReplicaFailure"
+	ReasonAsExpected               = "AsExpected"
+	ReasonServiceCreated           = "ServiceCreated"
+	ReasonEndpointAssigned         = "EndpointAssigned"
+	ReasonEndpointPending          = "EndpointPending"
+	ReasonExposeFailed             = "ExposeFailed"
+	ReasonModelResolved            = "ModelResolved"
+	ReasonModelNotFound            = "ModelNotFound"
+	ReasonModelInvalid             = "ModelInvalid"
+	ReasonValidationPassed         = "ValidationPassed"
+	ReasonValidationFailed         = "ValidationFailed"
+	ReasonCanaryRollout            = "CanaryRollout"
+	ReasonBlueGreenRollout         = "BlueGreenRollout"
+	ReasonRolloutAborted           = "RolloutAborted"
+	ReasonRolledBack               = "RolledBack"
+	ReasonApplied                  = "Applied"
+	ReasonApplyConflict            = "ApplyConflict"
+	ReasonDriftCorrected           = "DriftCorrected"
+	ReasonPredicted                = "Predicted"
+	ReasonPredictionFailed         = "PredictionFailed"
+	ReasonTargetNotFound           = "TargetNotFound"
+	ReasonMonitorApplied           = "MonitorApplied"
+	ReasonMonitorCRDNotInstalled   = "CRDNotInstalled"
+	ReasonScaled                   = "Scaled"
+	ReasonAutoscalerCreated        = "AutoscalerCreated"
+	ReasonAutoscalerDeleted        = "AutoscalerDeleted"
+	ReasonRolloutStarted           = "RolloutStarted"
+	ReasonRolloutStep              = "RolloutStep"
+	ReasonRolloutPromoted          = "RolloutPromoted"
+	ReasonReconcileFailed          = "ReconcileFailed"
+	ReasonDeletionProtected        = "DeletionProtected"
+	ReasonCleanupFailed            = "CleanupFailed"
+	ReasonCleanedUp                = "CleanedUp"
+)
+
+// setCondition 设置LSTMPredictApp的某个状态条件，并记录对应的observedGeneration，返回条件是否发生了变化
+func setCondition(app *lstmappsv1.LSTMPredictApp, conditionType string, status metav1.ConditionStatus, reason, message string) bool {
+	return meta.SetStatusCondition(&app.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: app.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// statusMessage 按影响程度依次检查状态条件，汇总应用没有正常运行的原因，一切正常时为空
+func statusMessage(app *lstmappsv1.LSTMPredictApp) string {
+	problems := []struct {
+		conditionType string
+		status        metav1.ConditionStatus
+	}{
+		{lstmappsv1.ConditionTypeModelResolved, metav1.ConditionFalse},
+		{lstmappsv1.ConditionTypeDegraded, metav1.ConditionTrue},
+		{lstmappsv1.ConditionTypeSynced, metav1.ConditionFalse},
+		{lstmappsv1.ConditionTypeAvailable, metav1.ConditionFalse},
+		{lstmappsv1.ConditionTypeServiceReady, metav1.ConditionFalse},
+	}
+	for _, problem := range problems {
+		cond := meta.FindStatusCondition(app.Status.Conditions, problem.conditionType)
+		if cond == nil || cond.Status != problem.status {
+			continue
+		}
+		if cond.Message == "" {
+			return cond.Reason
+		}
+		return fmt.Sprintf("%s: %s", cond.Reason, cond.Message)
+	}
+	return ""
+}
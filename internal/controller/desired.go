@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AppContainerName 运行预测服务的容器的名字
+const AppContainerName = "lstm-predict-app"
+
+// desiredPodTemplate 根据LSTMPredictApp与解析后的模型从零构造Deployment的Pod模板，只包含控制器负责的字段：
+// 标签、镜像、端口、资源、探针、模型的Volume、Init容器、挂载与环境变量。模型版本注解由调用方决定
+func desiredPodTemplate(app *lstmappsv1.LSTMPredictApp, model *lstmappsv1.ModelSpec) corev1.PodTemplateSpec {
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			// app标签是Deployment的选择器，AppNameLabel是所有版本（含金丝雀与蓝绿发布的绿色版本）的Pod共有的标签，
+			// 从没有该标签的版本升级时已有的应用会滚动重启一次，见README中的升级说明
+			Labels: map[string]string{"app": app.Name, AppNameLabel: app.Name},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  AppContainerName,
+					Image: app.Spec.AppImage,
+					Ports: containerPorts(app),
+				},
+			},
+		},
+	}
+	// 对app.Spec.ResoucesLimit为空进行处理，如果为空，不对Pod的资源限制做出定义
+	if !isEmptyResourceRequirements(app.Spec.ResourcesLimit) {
+		template.Spec.Containers[0].Resources = app.Spec.ResourcesLimit
+	}
+	applyProbesToContainer(app, &template.Spec.Containers[0])
+	applyModelToPodSpec(model, &template.Spec)
+	return template
+}
+
+// templateDrift 语义比较期望的Pod模板与集群中的Pod模板，返回不一致的字段路径。
+// 只比较期望模板中出现的字段，API Server填充的默认值以及其他管理者添加的标签、容器、环境变量等不算漂移；
+// 列表按名字（端口按端口号）逐项比较，与服务端应用的合并方式一致
+func templateDrift(desired, live *corev1.PodTemplateSpec) []string {
+	var fields []string
+	fields = append(fields, mapDrift("metadata.labels", desired.Labels, live.Labels)...)
+	fields = append(fields, mapDrift("metadata.annotations", desired.Annotations, live.Annotations)...)
+	fields = append(fields, containersDrift("spec.initContainers", desired.Spec.InitContainers, live.Spec.InitContainers)...)
+	fields = append(fields, containersDrift("spec.containers", desired.Spec.Containers, live.Spec.Containers)...)
+	fields = append(fields, listDrift("spec.volumes", desired.Spec.Volumes, live.Spec.Volumes, func(v corev1.Volume) string { return v.Name })...)
+	return fields
+}
+
+func containersDrift(path string, desired, live []corev1.Container) []string {
+	var fields []string
+	for _, want := range desired {
+		itemPath := fmt.Sprintf("%s[%s]", path, want.Name)
+		got := findByKey(live, want.Name, func(c corev1.Container) string { return c.Name })
+		if got == nil {
+			fields = append(fields, itemPath)
+			continue
+		}
+		if !equality.Semantic.DeepDerivative(want.Image, got.Image) {
+			fields = append(fields, itemPath+".image")
+		}
+		if !equality.Semantic.DeepDerivative(want.Command, got.Command) {
+			fields = append(fields, itemPath+".command")
+		}
+		if !equality.Semantic.DeepDerivative(want.Args, got.Args) {
+			fields = append(fields, itemPath+".args")
+		}
+		if !equality.Semantic.DeepDerivative(want.Resources, got.Resources) {
+			fields = append(fields, itemPath+".resources")
+		}
+		if !equality.Semantic.DeepDerivative(want.LivenessProbe, got.LivenessProbe) {
+			fields = append(fields, itemPath+".livenessProbe")
+		}
+		if !equality.Semantic.DeepDerivative(want.ReadinessProbe, got.ReadinessProbe) {
+			fields = append(fields, itemPath+".readinessProbe")
+		}
+		if !equality.Semantic.DeepDerivative(want.StartupProbe, got.StartupProbe) {
+			fields = append(fields, itemPath+".startupProbe")
+		}
+		fields = append(fields, listDrift(itemPath+".ports", want.Ports, got.Ports, func(p corev1.ContainerPort) string {
+			return strconv.Itoa(int(p.ContainerPort))
+		})...)
+		fields = append(fields, listDrift(itemPath+".env", want.Env, got.Env, func(e corev1.EnvVar) string { return e.Name })...)
+		fields = append(fields, listDrift(itemPath+".volumeMounts", want.VolumeMounts, got.VolumeMounts, func(m corev1.VolumeMount) string { return m.Name })...)
+	}
+	return fields
+}
+
+func listDrift[T any](path string, desired, live []T, key func(T) string) []string {
+	var fields []string
+	for _, want := range desired {
+		got := findByKey(live, key(want), key)
+		if got == nil || !equality.Semantic.DeepDerivative(want, *got) {
+			fields = append(fields, fmt.Sprintf("%s[%s]", path, key(want)))
+		}
+	}
+	return fields
+}
+
+func mapDrift(path string, desired, live map[string]string) []string {
+	var fields []string
+	for k, v := range desired {
+		if got, ok := live[k]; !ok || got != v {
+			fields = append(fields, fmt.Sprintf("%s[%s]", path, k))
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func findByKey[T any](items []T, k string, key func(T) string) *T {
+	for i := range items {
+		if key(items[i]) == k {
+			return &items[i]
+		}
+	}
+	return nil
+}
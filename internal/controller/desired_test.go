@@ -0,0 +1,164 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+var _ = Describe("Deployment drift correction", func() {
+	const resourceName = "drift-app"
+
+	ctx := context.Background()
+	typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+	var (
+		controllerReconciler *LSTMPredictAppReconciler
+		recorder             *record.FakeRecorder
+	)
+
+	reconcileOnce := func() {
+		_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		recorder = record.NewFakeRecorder(10)
+		controllerReconciler = &LSTMPredictAppReconciler{
+			Client:   k8sClient,
+			Scheme:   k8sClient.Scheme(),
+			Recorder: recorder,
+		}
+		app := &lstmappsv1.LSTMPredictApp{
+			ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+			Spec: lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				ContainerPort:      8080,
+				BackendAppReplicas: ptr.To[int32](1),
+				ResourcesLimit: corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+				},
+				ServicePort: 80,
+				ServiceType: corev1.ServiceTypeClusterIP,
+			},
+		}
+		Expect(k8sClient.Create(ctx, app)).To(Succeed())
+		reconcileOnce()
+		// 取走创建Deployment与Service的事件
+		Expect(drainEvents(recorder)).To(ContainElements(ContainSubstring(ReasonDeploymentCreated), ContainSubstring(ReasonServiceCreated)))
+	})
+
+	AfterEach(func() {
+		deleteLSTMPredictApp(ctx, typeNamespacedName)
+		meta := metav1.ObjectMeta{Name: resourceName, Namespace: "default"}
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &appsv1.Deployment{ObjectMeta: meta}))).To(Succeed())
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &corev1.Service{ObjectMeta: meta}))).To(Succeed())
+	})
+
+	It("should leave an unchanged Deployment alone", func() {
+		reconcileOnce()
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("should revert hand edits of the pod template and record the reverted fields", func() {
+		dp := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, dp)).To(Succeed())
+		container := &dp.Spec.Template.Spec.Containers[0]
+		container.Image = "lstm-predict-server:hotfix"
+		container.Ports[0].ContainerPort = 9090
+		container.Resources.Limits[corev1.ResourceCPU] = resource.MustParse("2")
+		Expect(k8sClient.Update(ctx, dp)).To(Succeed())
+
+		reconcileOnce()
+
+		Expect(k8sClient.Get(ctx, typeNamespacedName, dp)).To(Succeed())
+		container = &dp.Spec.Template.Spec.Containers[0]
+		Expect(container.Image).To(Equal("lstm-predict-server:v1.0"))
+		Expect(container.Ports[0].ContainerPort).To(Equal(int32(8080)))
+		Expect(container.Resources.Limits.Cpu().String()).To(Equal("500m"))
+
+		var event string
+		Expect(recorder.Events).To(Receive(&event))
+		Expect(event).To(ContainSubstring(ReasonDriftCorrected))
+		Expect(event).To(ContainSubstring("spec.containers[lstm-predict-app].image"))
+		Expect(event).To(ContainSubstring("spec.containers[lstm-predict-app].resources"))
+		Expect(event).To(ContainSubstring("spec.containers[lstm-predict-app].ports[8080]"))
+	})
+
+	It("should ignore defaulted fields and entries added by other managers", func() {
+		desired := desiredPodTemplate(&lstmappsv1.LSTMPredictApp{
+			ObjectMeta: metav1.ObjectMeta{Name: resourceName},
+			Spec:       lstmappsv1.LSTMPredictAppSpec{AppImage: "lstm-predict-server:v1.0", ContainerPort: 8080},
+		}, nil)
+		live := desired.DeepCopy()
+		live.Labels["sidecar.istio.io/inject"] = "true"
+		live.Spec.Containers[0].ImagePullPolicy = corev1.PullIfNotPresent
+		live.Spec.Containers[0].Ports[0].Protocol = corev1.ProtocolTCP
+		live.Spec.Containers[0].Env = append(live.Spec.Containers[0].Env, corev1.EnvVar{Name: "DEBUG", Value: "1"})
+		Expect(templateDrift(&desired, live)).To(BeEmpty())
+	})
+
+	It("should add HTTP probes on the container port and revert edits to them", func() {
+		app := &lstmappsv1.LSTMPredictApp{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		app.Spec.Probes = &lstmappsv1.ProbesSpec{
+			Path:          "/healthz",
+			ReadinessPath: "/ready",
+			Startup:       &lstmappsv1.ProbeTiming{PeriodSeconds: 10, TimeoutSeconds: 1, FailureThreshold: 60},
+		}
+		Expect(k8sClient.Update(ctx, app)).To(Succeed())
+		reconcileOnce()
+
+		dp := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, dp)).To(Succeed())
+		container := &dp.Spec.Template.Spec.Containers[0]
+		Expect(container.LivenessProbe.HTTPGet.Path).To(Equal("/healthz"))
+		Expect(container.LivenessProbe.HTTPGet.Port.IntValue()).To(Equal(8080))
+		Expect(container.LivenessProbe.PeriodSeconds).To(Equal(defaultLivenessProbe.PeriodSeconds))
+		Expect(container.ReadinessProbe.HTTPGet.Path).To(Equal("/ready"))
+		Expect(container.StartupProbe.FailureThreshold).To(Equal(int32(60)))
+
+		// 添加探针本身会被当作漂移纠正，先取走这次的事件
+		Expect(recorder.Events).To(Receive())
+
+		By("editing the startup probe by hand")
+		container.StartupProbe.FailureThreshold = 3
+		Expect(k8sClient.Update(ctx, dp)).To(Succeed())
+		reconcileOnce()
+
+		Expect(k8sClient.Get(ctx, typeNamespacedName, dp)).To(Succeed())
+		Expect(dp.Spec.Template.Spec.Containers[0].StartupProbe.FailureThreshold).To(Equal(int32(60)))
+		var event string
+		Expect(recorder.Events).To(Receive(&event))
+		Expect(event).To(ContainSubstring("spec.containers[lstm-predict-app].startupProbe"))
+		Expect(event).NotTo(ContainSubstring("livenessProbe"))
+	})
+})
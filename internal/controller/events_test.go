@@ -0,0 +1,126 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+// drainEvents 取走FakeRecorder中已经记录的全部事件
+func drainEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+// forbiddenServiceClient 模拟控制器没有写Service的权限，服务端应用Service时返回Forbidden
+type forbiddenServiceClient struct {
+	client.Client
+}
+
+func (c *forbiddenServiceClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if _, ok := obj.(*corev1.Service); ok {
+		return apierrors.NewForbidden(schema.GroupResource{Resource: "services"}, obj.GetName(), fmt.Errorf("RBAC denied"))
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+var _ = Describe("LSTMPredictApp events", func() {
+	const resourceName = "events-app"
+
+	ctx := context.Background()
+	typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+	var recorder *record.FakeRecorder
+
+	BeforeEach(func() {
+		recorder = record.NewFakeRecorder(20)
+		app := &lstmappsv1.LSTMPredictApp{
+			ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+			Spec: lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				ContainerPort:      8080,
+				BackendAppReplicas: ptr.To[int32](1),
+				ServicePort:        80,
+				ServiceType:        corev1.ServiceTypeClusterIP,
+			},
+		}
+		Expect(k8sClient.Create(ctx, app)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		deleteLSTMPredictApp(ctx, typeNamespacedName)
+		meta := metav1.ObjectMeta{Name: resourceName, Namespace: "default"}
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &appsv1.Deployment{ObjectMeta: meta}))).To(Succeed())
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &corev1.Service{ObjectMeta: meta}))).To(Succeed())
+	})
+
+	It("should record the creation and scaling of the child resources", func() {
+		controllerReconciler := &LSTMPredictAppReconciler{Client: k8sClient, Scheme: k8sClient.Scheme(), Recorder: recorder}
+		_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(drainEvents(recorder)).To(ConsistOf(
+			"Normal DeploymentCreated Created Deployment events-app with 1 replicas",
+			"Normal ServiceCreated Created ClusterIP Service events-app",
+		))
+
+		By("scaling the app")
+		app := &lstmappsv1.LSTMPredictApp{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		app.Spec.BackendAppReplicas = ptr.To[int32](3)
+		Expect(k8sClient.Update(ctx, app)).To(Succeed())
+		_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(drainEvents(recorder)).To(ContainElement("Normal Scaled Scaled Deployment events-app from 1 to 3 replicas"))
+	})
+
+	It("should record a warning when an API call fails", func() {
+		controllerReconciler := &LSTMPredictAppReconciler{
+			Client:   &forbiddenServiceClient{Client: k8sClient},
+			Scheme:   k8sClient.Scheme(),
+			Recorder: recorder,
+		}
+		_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(apierrors.IsForbidden(err)).To(BeTrue())
+		Expect(drainEvents(recorder)).To(ContainElement(And(
+			HavePrefix("Warning "+ReasonReconcileFailed+" Failed to reconcile Service"),
+			ContainSubstring("RBAC denied"),
+		)))
+	})
+})
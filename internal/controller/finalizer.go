@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// AppFinalizer 保证LSTMPredictApp的子资源被垃圾回收之前，控制器有机会执行删除钩子
+const AppFinalizer = "lstmapps.wuyong7240.com/cleanup"
+
+// CleanupClient 执行删除LSTMPredictApp之前与外部系统相关的清理动作，实现须是幂等的，失败后会重试
+type CleanupClient interface {
+	// ArchivePredictionLogs 通知单个Pod归档它的预测日志
+	ArchivePredictionLogs(ctx context.Context, pod *corev1.Pod, port int32, endpoint string) error
+	// DeleteModelCache 删除外部的模型缓存
+	DeleteModelCache(ctx context.Context, url string) error
+}
+
+// HTTPCleanupClient 通过HTTP请求执行清理动作，返回非2xx状态码视为失败
+type HTTPCleanupClient struct {
+	Client *http.Client
+}
+
+var _ CleanupClient = &HTTPCleanupClient{}
+
+// 默认的清理请求超时时间，归档日志可能比较耗时
+const defaultCleanupTimeout = 30 * time.Second
+
+func (h *HTTPCleanupClient) ArchivePredictionLogs(ctx context.Context, pod *corev1.Pod, port int32, endpoint string) error {
+	if pod.Status.PodIP == "" {
+		return fmt.Errorf("pod %s has no IP yet", pod.Name)
+	}
+	url := fmt.Sprintf("http://%s%s", net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(int(port))), endpoint)
+	resp, err := h.do(ctx, http.MethodPost, url)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("archive endpoint %s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+func (h *HTTPCleanupClient) DeleteModelCache(ctx context.Context, url string) error {
+	resp, err := h.do(ctx, http.MethodDelete, url)
+	if err != nil {
+		return err
+	}
+	// 缓存已经不存在也视为成功
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("model cache %s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+func (h *HTTPCleanupClient) do(ctx context.Context, method, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpClient := h.Client
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultCleanupTimeout}
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	_ = resp.Body.Close()
+	return resp, nil
+}
+
+// cleanupClient 返回调谐器使用的CleanupClient，未配置时使用HTTPCleanupClient
+func (r *LSTMPredictAppReconciler) cleanupClient() CleanupClient {
+	if r.CleanupClient != nil {
+		return r.CleanupClient
+	}
+	return &HTTPCleanupClient{}
+}
+
+// finalizeApp 处理正在删除的LSTMPredictApp：依次摘除流量、归档预测日志、删除外部模型缓存，全部成功后移除finalizer。
+// 开启了删除保护时（例如Webhook未启用）保留finalizer，子资源继续运行，直到关闭删除保护
+func (r *LSTMPredictAppReconciler) finalizeApp(ctx context.Context, app *lstmappsv1.LSTMPredictApp) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	if !controllerutil.ContainsFinalizer(app, AppFinalizer) {
+		return ctrl.Result{}, nil
+	}
+	if app.Spec.DeletionProtection {
+		log.Info("The LSTMPredictApp is protected from deletion, keep the finalizer.")
+		r.event(app, corev1.EventTypeWarning, ReasonDeletionProtected, "Deletion is blocked until spec.deletionProtection is set to false")
+		return ctrl.Result{}, nil
+	}
+
+	// 先删除Service，新的请求不会再被转发到预测服务，Pod仍然运行以便归档日志
+	for _, name := range []string{app.Name, previewName(app)} {
+		svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: app.Namespace, Name: name}}
+		if err := r.deleteOwned(ctx, app, svc); err != nil {
+			log.Error(err, "Failed to delete Service to drain traffic, will requeue after a short time.", "Service", name)
+			return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+		}
+	}
+
+	if cleanup := app.Spec.Cleanup; cleanup != nil {
+		if cleanup.ArchiveEndpoint != "" {
+			if err := r.archivePredictionLogs(ctx, app, cleanup.ArchiveEndpoint); err != nil {
+				log.Error(err, "Failed to archive prediction logs, will requeue after a short time.")
+				r.event(app, corev1.EventTypeWarning, ReasonCleanupFailed, fmt.Sprintf("Failed to archive prediction logs: %v", err))
+				return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+			}
+		}
+		if cleanup.ModelCacheURL != "" {
+			if err := r.cleanupClient().DeleteModelCache(ctx, cleanup.ModelCacheURL); err != nil {
+				log.Error(err, "Failed to delete model cache, will requeue after a short time.")
+				r.event(app, corev1.EventTypeWarning, ReasonCleanupFailed, fmt.Sprintf("Failed to delete model cache: %v", err))
+				return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+			}
+		}
+	}
+
+	r.event(app, corev1.EventTypeNormal, ReasonCleanedUp, "Deletion hooks finished, the child resources will be garbage collected")
+	// 只Patch finalizer，不提交Spec，避免对象因为不再满足当前的校验规则而无法删除
+	patch := client.MergeFrom(app.DeepCopy())
+	controllerutil.RemoveFinalizer(app, AppFinalizer)
+	if err := r.Patch(ctx, app, patch); err != nil {
+		log.Error(err, "Failed to remove finalizer, will requeue after a short time.")
+		return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+	}
+	log.Info("The deletion hooks have finished, the finalizer has been removed.")
+	return ctrl.Result{}, nil
+}
+
+// archivePredictionLogs 逐个调用属于该应用的就绪Pod的归档接口
+func (r *LSTMPredictAppReconciler) archivePredictionLogs(ctx context.Context, app *lstmappsv1.LSTMPredictApp, endpoint string) error {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(app.Namespace), client.MatchingLabels{"app": app.Name}); err != nil {
+		return err
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.DeletionTimestamp != nil || !isPodReady(pod) {
+			continue
+		}
+		if err := r.cleanupClient().ArchivePredictionLogs(ctx, pod, primaryPort(app).ContainerPort, endpoint); err != nil {
+			return fmt.Errorf("pod %s: %w", pod.Name, err)
+		}
+	}
+	return nil
+}
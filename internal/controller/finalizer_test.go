@@ -0,0 +1,170 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+// deleteLSTMPredictApp 删除LSTMPredictApp并调谐一次执行删除钩子，使finalizer被移除
+func deleteLSTMPredictApp(ctx context.Context, key types.NamespacedName) {
+	app := &lstmappsv1.LSTMPredictApp{}
+	Expect(k8sClient.Get(ctx, key, app)).To(Succeed())
+	Expect(k8sClient.Delete(ctx, app)).To(Succeed())
+	reconciler := &LSTMPredictAppReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+	_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+	Expect(err).NotTo(HaveOccurred())
+	Expect(errors.IsNotFound(k8sClient.Get(ctx, key, app))).To(BeTrue())
+}
+
+// recordingCleanupClient 记录被调用的清理动作
+type recordingCleanupClient struct {
+	archived     []string
+	deletedCache []string
+}
+
+func (c *recordingCleanupClient) ArchivePredictionLogs(_ context.Context, pod *corev1.Pod, _ int32, _ string) error {
+	c.archived = append(c.archived, pod.Name)
+	return nil
+}
+
+func (c *recordingCleanupClient) DeleteModelCache(_ context.Context, url string) error {
+	c.deletedCache = append(c.deletedCache, url)
+	return nil
+}
+
+var _ = Describe("LSTMPredictApp finalizer", func() {
+	const resourceName = "finalizer-app"
+
+	ctx := context.Background()
+	typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+	var (
+		controllerReconciler *LSTMPredictAppReconciler
+		cleanup              *recordingCleanupClient
+	)
+
+	reconcileOnce := func() {
+		_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		cleanup = &recordingCleanupClient{}
+		controllerReconciler = &LSTMPredictAppReconciler{
+			Client:        k8sClient,
+			Scheme:        k8sClient.Scheme(),
+			CleanupClient: cleanup,
+		}
+		app := &lstmappsv1.LSTMPredictApp{
+			ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+			Spec: lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				ContainerPort:      8080,
+				BackendAppReplicas: ptr.To[int32](1),
+				ServicePort:        80,
+				ServiceType:        corev1.ServiceTypeClusterIP,
+				DeletionProtection: true,
+				Cleanup: &lstmappsv1.CleanupSpec{
+					ArchiveEndpoint: "/archive",
+					ModelCacheURL:   "http://model-cache.example.com/lstm/v1",
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, app)).To(Succeed())
+		reconcileOnce()
+
+		// envtest中没有Deployment控制器，手动创建一个就绪的Pod
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: resourceName + "-pod", Namespace: "default", Labels: map[string]string{"app": resourceName}},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "lstm", Image: "lstm-predict-server:v1.0"}}},
+		}
+		Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+		pod.Status = corev1.PodStatus{
+			PodIP:      "10.0.0.1",
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		}
+		Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		meta := metav1.ObjectMeta{Name: resourceName, Namespace: "default"}
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &appsv1.Deployment{ObjectMeta: meta}))).To(Succeed())
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &corev1.Service{ObjectMeta: meta}))).To(Succeed())
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: resourceName + "-pod", Namespace: "default"}}))).To(Succeed())
+	})
+
+	It("should keep the finalizer while deletion protection is on and run the hooks once it is off", func() {
+		app := &lstmappsv1.LSTMPredictApp{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		Expect(controllerutil.ContainsFinalizer(app, AppFinalizer)).To(BeTrue())
+
+		By("deleting the protected object")
+		Expect(k8sClient.Delete(ctx, app)).To(Succeed())
+		reconcileOnce()
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		Expect(cleanup.archived).To(BeEmpty())
+		Expect(k8sClient.Get(ctx, typeNamespacedName, &corev1.Service{})).To(Succeed())
+
+		By("turning deletion protection off")
+		app.Spec.DeletionProtection = false
+		Expect(k8sClient.Update(ctx, app)).To(Succeed())
+		reconcileOnce()
+
+		Expect(cleanup.archived).To(Equal([]string{resourceName + "-pod"}))
+		Expect(cleanup.deletedCache).To(Equal([]string{"http://model-cache.example.com/lstm/v1"}))
+		Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, &corev1.Service{}))).To(BeTrue())
+		Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, app))).To(BeTrue())
+	})
+
+	It("should not delete a Service with the same name that the app doesn't own", func() {
+		previewKey := types.NamespacedName{Name: resourceName + "-preview", Namespace: "default"}
+		userSvc := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: previewKey.Name, Namespace: previewKey.Namespace},
+			Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80}}},
+		}
+		Expect(k8sClient.Create(ctx, userSvc)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, userSvc))).To(Succeed())
+		})
+
+		app := &lstmappsv1.LSTMPredictApp{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		app.Spec.DeletionProtection = false
+		Expect(k8sClient.Update(ctx, app)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, app)).To(Succeed())
+		reconcileOnce()
+
+		Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, app))).To(BeTrue())
+		Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, &corev1.Service{}))).To(BeTrue())
+		Expect(k8sClient.Get(ctx, previewKey, &corev1.Service{})).To(Succeed())
+	})
+})
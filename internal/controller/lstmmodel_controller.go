@@ -0,0 +1,131 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+// LSTMModelReconciler reconciles a LSTMModel object
+type LSTMModelReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=lstmapps.wuyong7240.com,resources=lstmmodels,verbs=get;list;watch
+// +kubebuilder:rbac:groups=lstmapps.wuyong7240.com,resources=lstmmodels/status,verbs=get;update;patch
+
+// Reconcile 校验LSTMModel的Spec，并将校验结果记录在Validated条件中，
+// LSTMPredictApp只会使用通过校验的LSTMModel
+func (r *LSTMModelReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	lm := &lstmappsv1.LSTMModel{}
+	if err := r.Get(ctx, req.NamespacedName, lm); err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("LSTMModel not found.")
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get the LSTMModel, will requeue after a short time.")
+		return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+	}
+
+	condition := metav1.Condition{
+		Type:               lstmappsv1.ConditionTypeValidated,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: lm.Generation,
+		Reason:             ReasonValidationPassed,
+		Message:            fmt.Sprintf("Model version %s is ready to be referenced", lm.Spec.Version),
+	}
+	lm.Status.Phase = "Ready"
+	if err := validateLSTMModelSpec(&lm.Spec); err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = ReasonValidationFailed
+		condition.Message = err.Error()
+		lm.Status.Phase = "Failed"
+	}
+
+	changed := meta.SetStatusCondition(&lm.Status.Conditions, condition)
+	if !changed && lm.Status.ObservedGeneration == lm.Generation {
+		return ctrl.Result{}, nil
+	}
+	lm.Status.ObservedGeneration = lm.Generation
+	if err := r.Status().Update(ctx, lm); err != nil {
+		log.Error(err, "Failed to update LSTMModel status.")
+		return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+	}
+	log.Info("The LSTMModel status has been updated.", "phase", lm.Status.Phase)
+	return ctrl.Result{}, nil
+}
+
+var (
+	sha256Pattern      = regexp.MustCompile(`^[a-fA-F0-9]{64}$`)
+	featureNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+)
+
+// validateLSTMModelSpec 校验LSTMModel中CRD的OpenAPI校验无法覆盖的部分
+func validateLSTMModelSpec(spec *lstmappsv1.LSTMModelSpec) error {
+	if _, err := modelSourceFromArtifactURI(spec.ArtifactURI, spec.SHA256); err != nil {
+		return err
+	}
+	if spec.SHA256 != "" && !sha256Pattern.MatchString(spec.SHA256) {
+		return fmt.Errorf("sha256 is illegal, need to be 64 hex characters")
+	}
+	if spec.InputWindowLength < 1 {
+		return fmt.Errorf("inputWindowLength can't < 1")
+	}
+	if spec.ForecastHorizon < 1 {
+		return fmt.Errorf("forecastHorizon can't < 1")
+	}
+	if len(spec.FeatureNames) == 0 {
+		return fmt.Errorf("featureNames can't be empty")
+	}
+	seen := map[string]bool{}
+	for _, name := range spec.FeatureNames {
+		if !featureNamePattern.MatchString(name) {
+			return fmt.Errorf("feature name %q is illegal", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("feature name %q is duplicated", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *LSTMModelReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		// 状态更新不会修改generation，只在Spec变化时重新校验
+		For(&lstmappsv1.LSTMModel{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Named("lstmmodel").
+		Complete(r)
+}
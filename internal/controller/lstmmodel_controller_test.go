@@ -0,0 +1,100 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+var _ = Describe("LSTMModel Controller", func() {
+	Context("When reconciling a resource", func() {
+		const resourceName = "test-model"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		AfterEach(func() {
+			resource := &lstmappsv1.LSTMModel{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+
+			By("Cleanup the specific resource instance LSTMModel")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+
+		reconcileModel := func() *lstmappsv1.LSTMModel {
+			controllerReconciler := &LSTMModelReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			model := &lstmappsv1.LSTMModel{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, model)).To(Succeed())
+			return model
+		}
+
+		It("should mark a well-formed model as validated", func() {
+			Expect(k8sClient.Create(ctx, &lstmappsv1.LSTMModel{
+				ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+				Spec: lstmappsv1.LSTMModelSpec{
+					Version:           "v1",
+					ArtifactURI:       "https://models.example.com/lstm/v1.pt",
+					SHA256:            strings.Repeat("b", 64),
+					InputWindowLength: 48,
+					FeatureNames:      []string{"cpu_usage", "request_rate"},
+					ForecastHorizon:   12,
+				},
+			})).To(Succeed())
+
+			model := reconcileModel()
+			Expect(model.Status.Phase).To(Equal("Ready"))
+			Expect(meta.IsStatusConditionTrue(model.Status.Conditions, lstmappsv1.ConditionTypeValidated)).To(BeTrue())
+		})
+
+		It("should reject an HTTP artifact without a checksum", func() {
+			Expect(k8sClient.Create(ctx, &lstmappsv1.LSTMModel{
+				ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+				Spec: lstmappsv1.LSTMModelSpec{
+					Version:           "v1",
+					ArtifactURI:       "https://models.example.com/lstm/v1.pt",
+					InputWindowLength: 48,
+					FeatureNames:      []string{"cpu_usage"},
+					ForecastHorizon:   12,
+				},
+			})).To(Succeed())
+
+			model := reconcileModel()
+			Expect(model.Status.Phase).To(Equal("Failed"))
+			Expect(meta.IsStatusConditionFalse(model.Status.Conditions, lstmappsv1.ConditionTypeValidated)).To(BeTrue())
+		})
+	})
+})
@@ -18,31 +18,47 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"time"
 
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 // LSTMPredictAppReconciler reconciles a LSTMPredictApp object
 type LSTMPredictAppReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// 模型热更新时通知Pod加载新模型，为空时使用HTTPModelReloader
+	ModelReloader ModelReloader
+	// 金丝雀分析时查询指标，为空时使用PrometheusMetricProvider
+	MetricProvider MetricProvider
+	// 记录回滚等需要告知用户的事件，为空时不记录
+	Recorder record.EventRecorder
+	// 删除LSTMPredictApp之前归档预测日志、删除模型缓存，为空时使用HTTPCleanupClient
+	CleanupClient CleanupClient
 }
 
-var CounterReconcileLSTMPredictApp int64
-
 // 通用的重新排队的时间间隔
 const GenericRequeueDuration = 1 * time.Minute
 
@@ -54,6 +70,16 @@ const GenericRequeueDuration = 1 * time.Minute
 // +kubebuilder:rbac:groups=apps,resources=deployments/status,verbs=get
 // +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=services/status,verbs=get
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=lstmapps.wuyong7240.com,resources=lstmmodels,verbs=get;list;watch
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=controllerrevisions,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -64,15 +90,19 @@ const GenericRequeueDuration = 1 * time.Minute
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.21.0/pkg/reconcile
-func (r *LSTMPredictAppReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *LSTMPredictAppReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
 
 	// 如果同时创建三个CRD资源，3个事件会被同时处理，防止日志混乱，加等待
 	<-time.NewTicker(100 * time.Millisecond).C
 	log := log.FromContext(ctx)
 
-	// 用于统计Reconcile被调用了多少次
-	CounterReconcileLSTMPredictApp += 1
-	log.Info("Start LSTMPredictApp Reconcile", "number", CounterReconcileLSTMPredictApp)
+	log.Info("Start LSTMPredictApp Reconcile")
+
+	// 服务端应用与其他字段管理者的冲突不重新排队，汇总后记录在Synced条件中
+	var conflicts []string
+	defer func() {
+		observeReconcile(result, err, conflicts)
+	}()
 
 	// 从上下文中获取CRD对象
 	app := &lstmappsv1.LSTMPredictApp{}
@@ -80,6 +110,7 @@ func (r *LSTMPredictAppReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		// 如果是没找到，不用管
 		if errors.IsNotFound(err) {
 			log.Info("LSTMPredictApp not found.")
+			forgetApp(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 		// 如果不是没找到，那就要重新排队
@@ -87,31 +118,169 @@ func (r *LSTMPredictAppReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
 	}
 
-	// 调谐子资源
-	var result ctrl.Result
-	var err error
+	// 正在删除，执行删除钩子，不再调谐子资源
+	if !app.DeletionTimestamp.IsZero() {
+		forgetApp(req.NamespacedName)
+		return r.finalizeApp(ctx, app)
+	}
+	if patch := client.MergeFrom(app.DeepCopy()); controllerutil.AddFinalizer(app, AppFinalizer) {
+		if err := r.Patch(ctx, app, patch); err != nil {
+			log.Error(err, "Failed to add finalizer, will requeue after a short time.")
+			return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+		}
+	}
+
+	// 调谐子资源，结束时记录应用的阶段与副本数
+	defer observeApp(app)
 
 	// 首先调谐Deployment，作为LSTM预测应用的后端应用
+	rollout := app.Status.Rollout.DeepCopy()
+	start := time.Now()
 	result, err = r.reconcileDeployment(ctx, app)
-	if err != nil {
+	observeStage(StageDeployment, start)
+	r.recordRollout(app, rollout)
+	if isApplyConflict(err) {
+		conflicts = append(conflicts, err.Error())
+	} else if err != nil {
 		log.Error(err, "Failed to reconcile Deployment.")
+		r.eventFailed(app, "Deployment", err)
 		return result, err
 	}
+	// 金丝雀发布需要按时间推进，保留Deployment调谐返回的重新排队时间
+	deploymentResult := result
 
+	start = time.Now()
 	result, err = r.reconcileService(ctx, app)
-	if err != nil {
+	observeStage(StageService, start)
+	if isApplyConflict(err) {
+		conflicts = append(conflicts, err.Error())
+	} else if err != nil {
 		log.Error(err, "Failed to reconcile Service.")
+		r.eventFailed(app, "Service", err)
+		return result, err
+	}
+	// 等待负载均衡器分配地址时同样需要重新排队，取两者中较早的时间
+	if result.RequeueAfter > 0 && (deploymentResult.RequeueAfter == 0 || result.RequeueAfter < deploymentResult.RequeueAfter) {
+		deploymentResult.RequeueAfter = result.RequeueAfter
+	}
+	// 副本数大于1时用PodDisruptionBudget保护预测服务
+	result, err = r.reconcilePDB(ctx, app)
+	if isApplyConflict(err) {
+		conflicts = append(conflicts, err.Error())
+	} else if err != nil {
+		log.Error(err, "Failed to reconcile PodDisruptionBudget.")
+		r.eventFailed(app, "PodDisruptionBudget", err)
+		return result, err
+	}
+	// 按spec.networkPolicy限制访问预测服务的来源
+	result, err = r.reconcileNetworkPolicy(ctx, app)
+	if isApplyConflict(err) {
+		conflicts = append(conflicts, err.Error())
+	} else if err != nil {
+		log.Error(err, "Failed to reconcile NetworkPolicy.")
+		r.eventFailed(app, "NetworkPolicy", err)
+		return result, err
+	}
+	// 开启监控时为预测服务创建ServiceMonitor
+	result, err = r.reconcileMonitoring(ctx, app)
+	if isApplyConflict(err) {
+		conflicts = append(conflicts, err.Error())
+	} else if err != nil {
+		log.Error(err, "Failed to reconcile ServiceMonitor.")
+		r.eventFailed(app, "ServiceMonitor", err)
+		return result, err
+	}
+	// 启用自动扩缩容时为Deployment维护HPA
+	result, err = r.reconcileHPA(ctx, app)
+	if isApplyConflict(err) {
+		conflicts = append(conflicts, err.Error())
+	} else if err != nil {
+		log.Error(err, "Failed to reconcile HorizontalPodAutoscaler.")
+		r.eventFailed(app, "HorizontalPodAutoscaler", err)
+		return result, err
+	}
+	// 蓝绿发布时暴露绿色版本的预览Service
+	result, err = r.reconcilePreviewService(ctx, app)
+	if isApplyConflict(err) {
+		conflicts = append(conflicts, err.Error())
+	} else if err != nil {
+		log.Error(err, "Failed to reconcile preview Service.")
+		r.eventFailed(app, "preview Service", err)
 		return result, err
 	}
+	if err = r.updateSyncedCondition(ctx, app, conflicts); err != nil {
+		log.Error(err, "Failed to update LSTMPredictApp status.")
+		return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+	}
+
+	// 汇总应用没有正常运行的原因，供kubectl get -o wide查看
+	if message := statusMessage(app); message != app.Status.Message {
+		app.Status.Message = message
+		if err = r.Status().Update(ctx, app); err != nil {
+			log.Error(err, "Failed to update LSTMPredictApp status.")
+			return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+		}
+	}
 
 	log.Info("All resources have been reconciled.")
-	return ctrl.Result{}, nil
+	return deploymentResult, nil
+}
+
+// event 记录与LSTMPredictApp相关的事件
+func (r *LSTMPredictAppReconciler) event(app *lstmappsv1.LSTMPredictApp, eventType, reason, message string) {
+	if r.Recorder != nil {
+		r.Recorder.Event(app, eventType, reason, message)
+	}
+}
+
+// eventFailed 调谐某个子资源失败时记录Warning事件，没有权限查看控制器日志的应用负责人也能看到失败原因
+func (r *LSTMPredictAppReconciler) eventFailed(app *lstmappsv1.LSTMPredictApp, kind string, err error) {
+	r.event(app, corev1.EventTypeWarning, ReasonReconcileFailed, fmt.Sprintf("Failed to reconcile %s: %v", kind, err))
+}
+
+// modelRefIndexKey 按spec.modelRef.name索引LSTMPredictApp，用于LSTMModel变化时找到引用它的应用
+const modelRefIndexKey = "spec.modelRef.name"
+
+// findAppsForModel 找到同一命名空间中引用了该LSTMModel的所有LSTMPredictApp
+func (r *LSTMPredictAppReconciler) findAppsForModel(ctx context.Context, obj client.Object) []reconcile.Request {
+	apps := &lstmappsv1.LSTMPredictAppList{}
+	if err := r.List(ctx, apps, client.InNamespace(obj.GetNamespace()), client.MatchingFields{modelRefIndexKey: obj.GetName()}); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list LSTMPredictApps referencing the LSTMModel.", "LSTMModel", obj.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(apps.Items))
+	for _, app := range apps.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&app)})
+	}
+	return requests
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *LSTMPredictAppReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	setupLog := ctrl.Log.WithName("Setup")
-	return ctrl.NewControllerManagedBy(mgr).
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &lstmappsv1.LSTMPredictApp{}, modelRefIndexKey, func(obj client.Object) []string {
+		app := obj.(*lstmappsv1.LSTMPredictApp)
+		if app.Spec.ModelRef == nil {
+			return nil
+		}
+		return []string{app.Spec.ModelRef.Name}
+	}); err != nil {
+		return err
+	}
+	// PodDisruptionBudget、路由对象、NetworkPolicy等由控制器自己维护，只需响应被删除或者Spec被修改
+	ownedPredicate := predicate.Funcs{
+		CreateFunc: func(event event.CreateEvent) bool {
+			return false
+		},
+		DeleteFunc: func(event event.DeleteEvent) bool {
+			setupLog.Info("The LSTMPredictApp owned object has been deleted.", "Kind", fmt.Sprintf("%T", event.Object), "Name", event.Object.GetName())
+			return true
+		},
+		UpdateFunc: func(event event.UpdateEvent) bool {
+			return event.ObjectNew.GetGeneration() != event.ObjectOld.GetGeneration()
+		},
+	}
+	b := ctrl.NewControllerManagedBy(mgr).
 		// 监听CR自定义资源的创建删除与更新
 		For(&lstmappsv1.LSTMPredictApp{}, builder.WithPredicates(predicate.Funcs{
 			CreateFunc: func(event event.CreateEvent) bool {
@@ -119,18 +288,27 @@ func (r *LSTMPredictAppReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				return true
 			},
 			DeleteFunc: func(event event.DeleteEvent) bool {
+				// 删除钩子在设置deletionTimestamp时已经执行完毕，对象真正消失时无需响应
 				setupLog.Info("The LSTMPredictApp has been deleted.", "Name", event.Object.GetName())
 				return false
 			},
 			UpdateFunc: func(event event.UpdateEvent) bool {
-				// 只有当ResourceVersion不同，并且CR的Spec发生变化时，才会触发Reconcile
+				// 只有当ResourceVersion不同，并且CR的Spec或者晋升注解发生变化时，才会触发Reconcile
 				if event.ObjectNew.GetResourceVersion() == event.ObjectOld.GetResourceVersion() {
 					return false
 				}
-				oldSpec := event.ObjectOld.(*lstmappsv1.LSTMPredictApp).Spec
-				newSpec := event.ObjectNew.(*lstmappsv1.LSTMPredictApp).Spec
+				// 开始删除时（设置了deletionTimestamp）需要执行删除钩子
+				if event.ObjectOld.GetDeletionTimestamp().IsZero() && !event.ObjectNew.GetDeletionTimestamp().IsZero() {
+					return true
+				}
+				oldApp := event.ObjectOld.(*lstmappsv1.LSTMPredictApp)
+				newApp := event.ObjectNew.(*lstmappsv1.LSTMPredictApp)
+				// 添加或修改晋升注解时需要立即晋升，等待晋升的蓝绿发布不会定时重新入队
+				if promote, ok := newApp.Annotations[PromoteAnnotation]; ok && promote != oldApp.Annotations[PromoteAnnotation] {
+					return true
+				}
 
-				return !reflect.DeepEqual(oldSpec, newSpec)
+				return !reflect.DeepEqual(oldApp.Spec, newApp.Spec)
 			},
 		})).
 		// 监听因CR资源而产生的Deployment资源
@@ -147,10 +325,11 @@ func (r *LSTMPredictAppReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				if event.ObjectNew.GetResourceVersion() == event.ObjectOld.GetResourceVersion() {
 					return false
 				}
-				oldSpec := event.ObjectOld.(*appsv1.Deployment).Spec
-				newSpec := event.ObjectNew.(*appsv1.Deployment).Spec
+				oldDp := event.ObjectOld.(*appsv1.Deployment)
+				newDp := event.ObjectNew.(*appsv1.Deployment)
 
-				return !reflect.DeepEqual(oldSpec, newSpec)
+				// Deployment的Status变化（副本就绪、滚动进度等）也需要同步到LSTMPredictApp的状态条件中
+				return !reflect.DeepEqual(oldDp.Spec, newDp.Spec) || !reflect.DeepEqual(oldDp.Status, newDp.Status)
 			},
 		})).
 		// 监听因CR资源而产生的Service资源
@@ -167,12 +346,59 @@ func (r *LSTMPredictAppReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				if event.ObjectNew.GetResourceVersion() == event.ObjectOld.GetResourceVersion() {
 					return false
 				}
-				oldSpec := event.ObjectOld.(*corev1.Service).Spec
-				newSpec := event.ObjectNew.(*corev1.Service).Spec
+				oldSvc := event.ObjectOld.(*corev1.Service)
+				newSvc := event.ObjectNew.(*corev1.Service)
 
-				return !reflect.DeepEqual(oldSpec, newSpec)
+				// 负载均衡器分配的地址需要同步到LSTMPredictApp的状态中
+				return !reflect.DeepEqual(oldSvc.Spec, newSvc.Spec) || !reflect.DeepEqual(oldSvc.Status.LoadBalancer, newSvc.Status.LoadBalancer)
+			},
+		})).
+		// 监听因CR资源而产生的HPA，副本数变化需要同步到LSTMPredictApp的状态中
+		Owns(&autoscalingv2.HorizontalPodAutoscaler{}, builder.WithPredicates(predicate.Funcs{
+			CreateFunc: func(event event.CreateEvent) bool {
+				return false
+			},
+			DeleteFunc: func(event event.DeleteEvent) bool {
+				setupLog.Info("The LSTMPredictApp HorizontalPodAutoscaler has been deleted.", "Name", event.Object.GetName())
+				return true
+			},
+			UpdateFunc: func(event event.UpdateEvent) bool {
+				oldHPA := event.ObjectOld.(*autoscalingv2.HorizontalPodAutoscaler)
+				newHPA := event.ObjectNew.(*autoscalingv2.HorizontalPodAutoscaler)
+				return !reflect.DeepEqual(oldHPA.Spec, newHPA.Spec) ||
+					oldHPA.Status.CurrentReplicas != newHPA.Status.CurrentReplicas ||
+					oldHPA.Status.DesiredReplicas != newHPA.Status.DesiredReplicas
 			},
 		})).
-		Named("lstmpredictapp").
-		Complete(r)
+		// 监听因CR资源而产生的PodDisruptionBudget，被删除或者修改时重新调谐
+		Owns(&policyv1.PodDisruptionBudget{}, builder.WithPredicates(ownedPredicate)).
+		// 监听因spec.expose而产生的Ingress
+		Owns(&networkingv1.Ingress{}, builder.WithPredicates(ownedPredicate)).
+		// 监听因spec.networkPolicy而产生的NetworkPolicy，被删除或者修改时重新调谐
+		Owns(&networkingv1.NetworkPolicy{}, builder.WithPredicates(ownedPredicate)).
+		// 监听被引用的LSTMModel，模型版本变化或者通过校验时重新调谐引用它的应用
+		Watches(&lstmappsv1.LSTMModel{}, handler.EnqueueRequestsFromMapFunc(r.findAppsForModel), builder.WithPredicates(predicate.Funcs{
+			CreateFunc: func(event event.CreateEvent) bool {
+				return true
+			},
+			DeleteFunc: func(event event.DeleteEvent) bool {
+				return true
+			},
+			UpdateFunc: func(event event.UpdateEvent) bool {
+				oldModel := event.ObjectOld.(*lstmappsv1.LSTMModel)
+				newModel := event.ObjectNew.(*lstmappsv1.LSTMModel)
+				return !reflect.DeepEqual(oldModel.Spec, newModel.Spec) || !reflect.DeepEqual(oldModel.Status.Conditions, newModel.Status.Conditions)
+			},
+		}))
+	// 集群中安装了Gateway API、Prometheus Operator时才监听HTTPRoute、ServiceMonitor，否则启动时无法建立Informer
+	for _, gvk := range []schema.GroupVersionKind{HTTPRouteGVK, ServiceMonitorGVK} {
+		if _, err := mgr.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+			setupLog.Info("CRD is not installed, the owned objects will not be watched.", "kind", gvk.Kind)
+			continue
+		}
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		b = b.Owns(obj, builder.WithPredicates(ownedPredicate))
+	}
+	return b.Named("lstmpredictapp").Complete(r)
 }
@@ -21,8 +21,11 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -51,7 +54,13 @@ var _ = Describe("LSTMPredictApp Controller", func() {
 						Name:      resourceName,
 						Namespace: "default",
 					},
-					// TODO(user): Specify other spec details if needed.
+					Spec: lstmappsv1.LSTMPredictAppSpec{
+						AppImage:           "lstm-predict-server:v1.0",
+						ContainerPort:      8080,
+						BackendAppReplicas: ptr.To[int32](2),
+						ServicePort:        80,
+						ServiceType:        corev1.ServiceTypeClusterIP,
+					},
 				}
 				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
 			}
@@ -59,12 +68,8 @@ var _ = Describe("LSTMPredictApp Controller", func() {
 
 		AfterEach(func() {
 			// TODO(user): Cleanup logic after each test, like removing the resource instance.
-			resource := &lstmappsv1.LSTMPredictApp{}
-			err := k8sClient.Get(ctx, typeNamespacedName, resource)
-			Expect(err).NotTo(HaveOccurred())
-
 			By("Cleanup the specific resource instance LSTMPredictApp")
-			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			deleteLSTMPredictApp(ctx, typeNamespacedName)
 		})
 		It("should successfully reconcile the resource", func() {
 			By("Reconciling the created resource")
@@ -80,5 +85,46 @@ var _ = Describe("LSTMPredictApp Controller", func() {
 			// TODO(user): Add more specific assertions depending on your controller's reconciliation logic.
 			// Example: If you expect a certain status condition after reconciliation, verify it here.
 		})
+		It("should report conditions and observedGeneration after reconciling", func() {
+			controllerReconciler := &LSTMPredictAppReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("checking the status conditions of the LSTMPredictApp")
+			app := &lstmappsv1.LSTMPredictApp{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+			Expect(app.Status.ObservedGeneration).To(Equal(app.Generation))
+			// envtest中没有Deployment控制器，副本永远不会就绪
+			Expect(meta.IsStatusConditionTrue(app.Status.Conditions, lstmappsv1.ConditionTypeProgressing)).To(BeTrue())
+			Expect(meta.IsStatusConditionFalse(app.Status.Conditions, lstmappsv1.ConditionTypeAvailable)).To(BeTrue())
+			Expect(meta.FindStatusCondition(app.Status.Conditions, lstmappsv1.ConditionTypeServiceReady)).NotTo(BeNil())
+			// /scale子资源通过status.selector找到预测服务的Pod
+			Expect(app.Status.Selector).To(Equal("app=" + resourceName))
+			Expect(app.Status.Message).To(HavePrefix(ReasonDeploymentCreated + ": "))
+		})
+	})
+
+	Context("When summarizing the status", func() {
+		It("should report the most important problem first and clear the message once running", func() {
+			app := &lstmappsv1.LSTMPredictApp{}
+			setCondition(app, lstmappsv1.ConditionTypeAvailable, metav1.ConditionFalse, ReasonReplicasNotReady, "1/3 replicas are ready")
+			setCondition(app, lstmappsv1.ConditionTypeServiceReady, metav1.ConditionFalse, ReasonEndpointPending, "Waiting for the Service endpoint to be assigned")
+			Expect(statusMessage(app)).To(Equal("ReplicasNotReady: 1/3 replicas are ready"))
+
+			setCondition(app, lstmappsv1.ConditionTypeDegraded, metav1.ConditionTrue, ReasonRolledBack, "rolled back to revision 2")
+			Expect(statusMessage(app)).To(Equal("RolledBack: rolled back to revision 2"))
+
+			for _, conditionType := range []string{lstmappsv1.ConditionTypeAvailable, lstmappsv1.ConditionTypeServiceReady} {
+				setCondition(app, conditionType, metav1.ConditionTrue, ReasonAsExpected, "")
+			}
+			setCondition(app, lstmappsv1.ConditionTypeDegraded, metav1.ConditionFalse, ReasonAsExpected, "")
+			Expect(statusMessage(app)).To(BeEmpty())
+		})
 	})
 })
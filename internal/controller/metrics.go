@@ -0,0 +1,176 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+// 调谐结果的取值
+const (
+	ReconcileResultSuccess  = "success"
+	ReconcileResultRequeue  = "requeue"
+	ReconcileResultConflict = "conflict"
+	ReconcileResultError    = "error"
+)
+
+// 调谐阶段的取值
+const (
+	StageDeployment = "deployment"
+	StageService    = "service"
+)
+
+// 控制器自身的指标，注册到controller-runtime的指标Registry中，与controller_runtime_*指标一起通过Manager的metrics端点暴露
+var (
+	reconcileTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "lstmpredictapp_reconcile_total",
+		Help: "Total number of LSTMPredictApp reconciliations by result (success, requeue, conflict or error).",
+	}, []string{"result"})
+
+	reconcileStageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "lstmpredictapp_reconcile_stage_duration_seconds",
+		Help:    "Latency of each LSTMPredictApp reconciliation stage in seconds.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"stage"})
+
+	appsByPhase = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lstmpredictapp_apps",
+		Help: "Number of LSTMPredictApps by status phase.",
+	}, []string{"phase"})
+
+	appDesiredReplicas = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lstmpredictapp_desired_replicas",
+		Help: "Number of prediction server replicas the LSTMPredictApp asks for.",
+	}, []string{"namespace", "name"})
+
+	appReadyReplicas = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lstmpredictapp_ready_replicas",
+		Help: "Number of ready prediction server replicas of the LSTMPredictApp.",
+	}, []string{"namespace", "name"})
+
+	rolloutsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "lstmpredictapp_rollouts_total",
+		Help: "Total number of canary and blue-green rollouts by strategy and phase (Progressing when started, Promoted or Aborted when finished).",
+	}, []string{"strategy", "phase"})
+
+	rollbacksTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "lstmpredictapp_rollbacks_total",
+		Help: "Total number of automatic rollbacks to the last known-good revision.",
+	}, []string{"namespace", "name"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(reconcileTotal, reconcileStageDuration, appsByPhase, appDesiredReplicas, appReadyReplicas, rolloutsTotal, rollbacksTotal)
+}
+
+// observeReconcile 记录一次调谐的结果
+func observeReconcile(result ctrl.Result, err error, conflicts []string) {
+	switch {
+	case err != nil:
+		reconcileTotal.WithLabelValues(ReconcileResultError).Inc()
+	case len(conflicts) > 0:
+		reconcileTotal.WithLabelValues(ReconcileResultConflict).Inc()
+	case result.RequeueAfter > 0:
+		reconcileTotal.WithLabelValues(ReconcileResultRequeue).Inc()
+	default:
+		reconcileTotal.WithLabelValues(ReconcileResultSuccess).Inc()
+	}
+}
+
+// observeStage 记录调谐阶段从start开始的耗时
+func observeStage(stage string, start time.Time) {
+	reconcileStageDuration.WithLabelValues(stage).Observe(time.Since(start).Seconds())
+}
+
+// observeRollout 比较调谐前后的发布状态，发布开始、全量或中止时计数
+func observeRollout(before, after *lstmappsv1.RolloutStatus) {
+	if after == nil {
+		return
+	}
+	switch after.Phase {
+	case lstmappsv1.RolloutPhaseProgressing, lstmappsv1.RolloutPhasePromoted, lstmappsv1.RolloutPhaseAborted:
+	default:
+		return
+	}
+	if before != nil && before.Revision == after.Revision && before.Phase == after.Phase {
+		return
+	}
+	rolloutsTotal.WithLabelValues(string(after.Strategy), string(after.Phase)).Inc()
+}
+
+// appStates 记录每个LSTMPredictApp最近一次调谐后的阶段，用于按阶段统计应用数量；Reconcile可能并发执行，需要加锁
+var appStates = struct {
+	sync.Mutex
+	phases map[types.NamespacedName]string
+}{phases: map[types.NamespacedName]string{}}
+
+// 状态中还没有阶段的应用统计在Unknown中
+const unknownPhase = "Unknown"
+
+// observeApp 记录应用的阶段以及期望与就绪的副本数
+func observeApp(app *lstmappsv1.LSTMPredictApp) {
+	phase := app.Status.Phase
+	if phase == "" {
+		phase = unknownPhase
+	}
+	appDesiredReplicas.WithLabelValues(app.Namespace, app.Name).Set(float64(desiredReplicas(app)))
+	appReadyReplicas.WithLabelValues(app.Namespace, app.Name).Set(float64(app.Status.ReadyReplicas))
+
+	appStates.Lock()
+	defer appStates.Unlock()
+	appStates.phases[types.NamespacedName{Namespace: app.Namespace, Name: app.Name}] = phase
+	updateAppsByPhase()
+}
+
+// forgetApp 应用被删除后移除与它相关的指标
+func forgetApp(key types.NamespacedName) {
+	appDesiredReplicas.DeleteLabelValues(key.Namespace, key.Name)
+	appReadyReplicas.DeleteLabelValues(key.Namespace, key.Name)
+	rollbacksTotal.DeleteLabelValues(key.Namespace, key.Name)
+
+	appStates.Lock()
+	defer appStates.Unlock()
+	if _, ok := appStates.phases[key]; !ok {
+		return
+	}
+	delete(appStates.phases, key)
+	updateAppsByPhase()
+}
+
+// updateAppsByPhase 按appStates重新计算各阶段的应用数量，调用方须持有锁
+func updateAppsByPhase() {
+	counts := map[string]int{}
+	for _, phase := range appStates.phases {
+		counts[phase]++
+	}
+	// 已经没有应用的阶段置0，而不是删除，方便告警规则使用
+	appsByPhase.Reset()
+	for _, phase := range []string{"Running", "Pending", unknownPhase} {
+		appsByPhase.WithLabelValues(phase).Set(0)
+	}
+	for phase, count := range counts {
+		appsByPhase.WithLabelValues(phase).Set(float64(count))
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+var _ = Describe("Operator metrics", func() {
+	const resourceName = "metrics-app"
+
+	ctx := context.Background()
+	typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+	BeforeEach(func() {
+		app := &lstmappsv1.LSTMPredictApp{
+			ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+			Spec: lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				ContainerPort:      8080,
+				BackendAppReplicas: ptr.To[int32](3),
+				ServicePort:        80,
+				ServiceType:        corev1.ServiceTypeClusterIP,
+			},
+		}
+		Expect(k8sClient.Create(ctx, app)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		deleteLSTMPredictApp(ctx, typeNamespacedName)
+		meta := metav1.ObjectMeta{Name: resourceName, Namespace: "default"}
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &appsv1.Deployment{ObjectMeta: meta}))).To(Succeed())
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &corev1.Service{ObjectMeta: meta}))).To(Succeed())
+		forgetApp(typeNamespacedName)
+	})
+
+	It("should count reconciliations and track the replicas and phase of the app", func() {
+		controllerReconciler := &LSTMPredictAppReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		succeeded := testutil.ToFloat64(reconcileTotal.WithLabelValues(ReconcileResultSuccess))
+		pending := testutil.ToFloat64(appsByPhase.WithLabelValues("Pending"))
+
+		_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(testutil.ToFloat64(reconcileTotal.WithLabelValues(ReconcileResultSuccess))).To(Equal(succeeded + 1))
+		Expect(testutil.ToFloat64(appDesiredReplicas.WithLabelValues("default", resourceName))).To(Equal(3.0))
+		Expect(testutil.ToFloat64(appReadyReplicas.WithLabelValues("default", resourceName))).To(BeZero())
+		Expect(testutil.ToFloat64(appsByPhase.WithLabelValues("Pending"))).To(Equal(pending + 1))
+		Expect(testutil.CollectAndCount(reconcileStageDuration)).To(BeNumerically(">=", 2))
+
+		By("forgetting the app once it is gone")
+		forgetApp(typeNamespacedName)
+		Expect(testutil.ToFloat64(appsByPhase.WithLabelValues("Pending"))).To(Equal(pending))
+	})
+
+	It("should count each rollout transition once", func() {
+		counter := rolloutsTotal.WithLabelValues(string(lstmappsv1.RolloutStrategyCanary), string(lstmappsv1.RolloutPhaseProgressing))
+		started := testutil.ToFloat64(counter)
+
+		progressing := &lstmappsv1.RolloutStatus{Strategy: lstmappsv1.RolloutStrategyCanary, Phase: lstmappsv1.RolloutPhaseProgressing, Revision: "abc"}
+		observeRollout(nil, progressing)
+		observeRollout(progressing, progressing.DeepCopy())
+		Expect(testutil.ToFloat64(counter)).To(Equal(started + 1))
+
+		next := progressing.DeepCopy()
+		next.Revision = "def"
+		observeRollout(progressing, next)
+		Expect(testutil.ToFloat64(counter)).To(Equal(started + 2))
+	})
+})
@@ -0,0 +1,470 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// ModelVolumeName 模型文件所在Volume的名字
+	ModelVolumeName = "model"
+	// ModelFetcherContainerName 拉取或下载模型文件的Init容器的名字
+	ModelFetcherContainerName = "model-fetcher"
+	// ModelPathEnvName 告知预测服务模型文件位置的环境变量
+	ModelPathEnvName = "MODEL_PATH"
+	// ModelVersionEnvName 告知预测服务启动时加载的模型版本的环境变量，取值来自Pod的模型版本注解
+	ModelVersionEnvName = "MODEL_VERSION"
+	// ModelVersionAnnotation Pod模板上记录Pod启动时加载的模型版本的注解
+	ModelVersionAnnotation = "lstmapps.wuyong7240.com/model-version"
+	// 暂存热更新时，Pod模板上记录模型来源、sha256校验和、按版本区分的模型目录与模型路径的注解
+	ModelSourceAnnotation = "lstmapps.wuyong7240.com/model-source"
+	ModelSHA256Annotation = "lstmapps.wuyong7240.com/model-sha256"
+	ModelDirAnnotation    = "lstmapps.wuyong7240.com/model-dir"
+	ModelPathAnnotation   = "lstmapps.wuyong7240.com/model-path"
+	// ModelStagerContainerName 暂存新版本模型的Sidecar的名字
+	ModelStagerContainerName = "model-stager"
+	// ModelSourceVolumeName 以Downward API挂载模型来源注解的Volume的名字
+	ModelSourceVolumeName = "model-source"
+	// 模型来源注解在暂存Sidecar中的挂载目录
+	modelSourceMountPath = "/etc/lstm-model"
+
+	// 以下默认值一般由Webhook注入，这里作为兜底
+	DefaultModelMountPath       = "/models"
+	DefaultModelPullerImage     = "ghcr.io/oras-project/oras:v1.2.0"
+	DefaultModelDownloaderImage = "busybox:1.36"
+)
+
+// httpModelDownloadScript 下载模型文件并校验sha256的脚本，参数由环境变量MODEL_URL、MODEL_SHA256、MODEL_FILE传入
+const httpModelDownloadScript = `mkdir -p "$(dirname "$MODEL_FILE")" && wget -O "$MODEL_FILE" "$MODEL_URL" && echo "$MODEL_SHA256  $MODEL_FILE" | sha256sum -c -`
+
+// modelStagerScript 暂存Sidecar的脚本：轮询Downward API挂载的Pod注解，模型目录不存在时先拉取到临时目录，
+// 拉取期间注解没有变化才重命名为按版本区分的目录；只保留当前与上一个版本的目录。%s为拉取命令
+const modelStagerScript = `src=` + modelSourceMountPath + `
+prev=""
+cur=""
+while true; do
+  dir=$(cat "$src/dir")
+  source=$(cat "$src/source")
+  sha256=$(cat "$src/sha256" 2>/dev/null)
+  file=$(cat "$src/path")
+  if [ ! -d "$dir" ]; then
+    tmp="$(dirname "$dir")/.staging"
+    rm -rf "$tmp" && mkdir -p "$tmp"
+    if %s && [ "$(cat "$src/dir")" = "$dir" ] && [ "$(cat "$src/source")" = "$source" ]; then
+      mv "$tmp" "$dir"
+    else
+      rm -rf "$tmp"
+    fi
+  fi
+  if [ -d "$dir" ] && [ "$dir" != "$cur" ]; then
+    if [ -n "$prev" ] && [ "$prev" != "$dir" ]; then
+      rm -rf "$prev"
+    fi
+    prev=$cur
+    cur=$dir
+  fi
+  sleep 5
+done`
+
+// 暂存Sidecar中各来源的拉取命令
+const (
+	httpModelStageCommand = `wget -O "$tmp/${file#"$dir"/}" "$source" && echo "$sha256  $tmp/${file#"$dir"/}" | sha256sum -c -`
+	ociModelStageCommand  = `oras pull "$source" --output "$tmp"`
+)
+
+// modelWiring 需要注入Pod模板的模型相关对象，为nil表示不需要
+type modelWiring struct {
+	// 存放模型的Volume
+	volume *corev1.Volume
+	// 暂存热更新时，以Downward API挂载模型来源注解的Volume
+	sourceVolume *corev1.Volume
+	// 拉取或下载模型文件的Init容器
+	fetcher *corev1.Container
+	// 暂存热更新时，拉取新版本模型的Sidecar
+	stager *corev1.Container
+	// 预测容器挂载模型Volume的位置
+	mount *corev1.VolumeMount
+	// MODEL_PATH环境变量
+	env *corev1.EnvVar
+}
+
+// isStagedModel 判断模型是否通过暂存热更新：HTTP或OCI来源设置了版本与热更新接口时，
+// 来源、校验和与路径都放在Pod注解中，新版本由Sidecar暂存到按版本区分的目录后再调用热更新接口，Pod模板的容器定义保持不变
+func isStagedModel(model *lstmappsv1.ModelSpec) bool {
+	return model != nil && model.Version != "" && model.ReloadEndpoint != "" && (model.HTTP != nil || model.OCI != nil)
+}
+
+// modelMountPath 返回模型的挂载目录
+func modelMountPath(model *lstmappsv1.ModelSpec) string {
+	if model.MountPath == "" {
+		return DefaultModelMountPath
+	}
+	return model.MountPath
+}
+
+// modelAnnotations 返回Pod模板上描述模型的注解：模型版本；暂存热更新时还包括来源、校验和以及按版本区分的目录与模型路径。
+// 没有指定模型版本时返回nil
+func modelAnnotations(model *lstmappsv1.ModelSpec) map[string]string {
+	version := modelVersion(model)
+	if version == "" {
+		return nil
+	}
+	annotations := map[string]string{ModelVersionAnnotation: version}
+	if !isStagedModel(model) {
+		return annotations
+	}
+	dir := path.Join(modelMountPath(model), modelVersionDir(version))
+	annotations[ModelDirAnnotation] = dir
+	switch {
+	case model.HTTP != nil:
+		annotations[ModelSourceAnnotation] = model.HTTP.URL
+		annotations[ModelSHA256Annotation] = strings.ToLower(model.HTTP.SHA256)
+		annotations[ModelPathAnnotation] = path.Join(dir, httpModelFileName(model.HTTP))
+	case model.OCI != nil:
+		annotations[ModelSourceAnnotation] = model.OCI.Artifact
+		annotations[ModelPathAnnotation] = path.Join(dir, model.OCI.Path)
+	}
+	return annotations
+}
+
+// modelAnnotationKeys 控制器管理的全部模型注解
+var modelAnnotationKeys = []string{ModelVersionAnnotation, ModelSourceAnnotation, ModelSHA256Annotation, ModelDirAnnotation, ModelPathAnnotation}
+
+// modelVersionDir 将模型版本转换为可以作为目录名的字符串，不以'.'开头，不包含'/'
+func modelVersionDir(version string) string {
+	dir := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, version)
+	if strings.HasPrefix(dir, ".") {
+		dir = "_" + dir
+	}
+	return dir
+}
+
+// annotationEnv 通过Downward API将Pod注解暴露为环境变量，容器启动时读取Pod当前的注解
+func annotationEnv(name, annotation string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{
+				FieldPath: fmt.Sprintf("metadata.annotations['%s']", annotation),
+			},
+		},
+	}
+}
+
+// desiredModelWiring 根据解析后的模型（见resolveModel），计算需要注入Pod模板的Volume、Init容器以及预测容器的挂载与环境变量。
+// 没有指定模型来源时返回nil
+func desiredModelWiring(model *lstmappsv1.ModelSpec) *modelWiring {
+	if model == nil {
+		return nil
+	}
+
+	mountPath := modelMountPath(model)
+	wiring := &modelWiring{
+		volume: &corev1.Volume{Name: ModelVolumeName},
+		mount:  &corev1.VolumeMount{Name: ModelVolumeName, MountPath: mountPath, ReadOnly: true},
+	}
+	volume := wiring.volume
+	modelPath := mountPath
+	fetcherMounts := []corev1.VolumeMount{{Name: ModelVolumeName, MountPath: mountPath}}
+
+	switch {
+	case model.PVC != nil:
+		volume.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{
+			ClaimName: model.PVC.ClaimName,
+			ReadOnly:  true,
+		}
+		modelPath = path.Join(mountPath, model.PVC.Path)
+	case model.ConfigMap != nil:
+		volume.ConfigMap = &corev1.ConfigMapVolumeSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: model.ConfigMap.Name},
+		}
+		modelPath = path.Join(mountPath, model.ConfigMap.Key)
+	case model.Secret != nil:
+		volume.Secret = &corev1.SecretVolumeSource{SecretName: model.Secret.Name}
+		modelPath = path.Join(mountPath, model.Secret.Key)
+	case model.OCI != nil:
+		// OCI Artifact先由Init容器拉取到共享的emptyDir中，预测容器再以只读方式挂载
+		volume.EmptyDir = &corev1.EmptyDirVolumeSource{}
+		image := model.OCI.PullerImage
+		if image == "" {
+			image = DefaultModelPullerImage
+		}
+		wiring.fetcher = &corev1.Container{
+			Name:         ModelFetcherContainerName,
+			Image:        image,
+			Args:         []string{"pull", model.OCI.Artifact, "--output", mountPath},
+			VolumeMounts: fetcherMounts,
+		}
+		modelPath = path.Join(mountPath, model.OCI.Path)
+		if isStagedModel(model) {
+			// 暂存热更新时Artifact与目录取自Pod注解，拉取到按版本区分的目录中
+			wiring.fetcher.Args = []string{"pull", "$(MODEL_ARTIFACT)", "--output", "$(MODEL_DIR)"}
+			wiring.fetcher.Env = []corev1.EnvVar{
+				annotationEnv("MODEL_ARTIFACT", ModelSourceAnnotation),
+				annotationEnv("MODEL_DIR", ModelDirAnnotation),
+			}
+			wiring.stager = modelStager(image, ociModelStageCommand, fetcherMounts)
+		}
+	case model.HTTP != nil:
+		// 模型文件先由Init容器下载到共享的emptyDir中并校验sha256，校验失败时Init容器退出，Pod不会启动
+		// URL、校验和与文件名通过环境变量传入，脚本中只引用变量，不拼接用户输入
+		volume.EmptyDir = &corev1.EmptyDirVolumeSource{}
+		image := model.HTTP.DownloaderImage
+		if image == "" {
+			image = DefaultModelDownloaderImage
+		}
+		file := path.Join(mountPath, httpModelFileName(model.HTTP))
+		wiring.fetcher = &corev1.Container{
+			Name:    ModelFetcherContainerName,
+			Image:   image,
+			Command: []string{"sh", "-c", httpModelDownloadScript},
+			Env: []corev1.EnvVar{
+				{Name: "MODEL_URL", Value: model.HTTP.URL},
+				{Name: "MODEL_SHA256", Value: strings.ToLower(model.HTTP.SHA256)},
+				{Name: "MODEL_FILE", Value: file},
+			},
+			VolumeMounts: fetcherMounts,
+		}
+		modelPath = file
+		if isStagedModel(model) {
+			// 暂存热更新时URL、校验和与文件路径取自Pod注解，下载到按版本区分的目录中
+			wiring.fetcher.Env = []corev1.EnvVar{
+				annotationEnv("MODEL_URL", ModelSourceAnnotation),
+				annotationEnv("MODEL_SHA256", ModelSHA256Annotation),
+				annotationEnv("MODEL_FILE", ModelPathAnnotation),
+			}
+			wiring.stager = modelStager(image, httpModelStageCommand, fetcherMounts)
+		}
+	default:
+		return nil
+	}
+
+	env := corev1.EnvVar{Name: ModelPathEnvName, Value: modelPath}
+	if wiring.stager != nil {
+		// 模型路径随版本变化，同样取自Pod注解
+		env = annotationEnv(ModelPathEnvName, ModelPathAnnotation)
+		wiring.sourceVolume = modelSourceVolume(model.HTTP != nil)
+	}
+	wiring.env = &env
+	return wiring
+}
+
+// modelStager 构造暂存新版本模型的Sidecar，以原生Sidecar（restartPolicy为Always的Init容器）的方式运行，
+// 与拉取模型的Init容器使用相同的镜像，镜像中还需要包含sh
+func modelStager(image, command string, mounts []corev1.VolumeMount) *corev1.Container {
+	return &corev1.Container{
+		Name:          ModelStagerContainerName,
+		Image:         image,
+		Command:       []string{"sh", "-c", fmt.Sprintf(modelStagerScript, command)},
+		RestartPolicy: ptr.To(corev1.ContainerRestartPolicyAlways),
+		VolumeMounts: append(slices.Clone(mounts), corev1.VolumeMount{
+			Name: ModelSourceVolumeName, MountPath: modelSourceMountPath, ReadOnly: true,
+		}),
+	}
+}
+
+// modelSourceVolume 以Downward API挂载模型来源注解，Pod注解变化后文件内容随之更新
+func modelSourceVolume(withSHA256 bool) *corev1.Volume {
+	items := []corev1.DownwardAPIVolumeFile{
+		{Path: "source", FieldRef: &corev1.ObjectFieldSelector{FieldPath: fmt.Sprintf("metadata.annotations['%s']", ModelSourceAnnotation)}},
+		{Path: "dir", FieldRef: &corev1.ObjectFieldSelector{FieldPath: fmt.Sprintf("metadata.annotations['%s']", ModelDirAnnotation)}},
+		{Path: "path", FieldRef: &corev1.ObjectFieldSelector{FieldPath: fmt.Sprintf("metadata.annotations['%s']", ModelPathAnnotation)}},
+	}
+	if withSHA256 {
+		items = append(items, corev1.DownwardAPIVolumeFile{
+			Path: "sha256", FieldRef: &corev1.ObjectFieldSelector{FieldPath: fmt.Sprintf("metadata.annotations['%s']", ModelSHA256Annotation)},
+		})
+	}
+	return &corev1.Volume{
+		Name:         ModelSourceVolumeName,
+		VolumeSource: corev1.VolumeSource{DownwardAPI: &corev1.DownwardAPIVolumeSource{Items: items}},
+	}
+}
+
+// desiredModelVersionEnv 通过Downward API将Pod上的模型版本注解暴露为MODEL_VERSION环境变量，
+// 这样模型版本变化时只需要修改注解，热更新时Pod模板中的容器定义保持不变
+func desiredModelVersionEnv(model *lstmappsv1.ModelSpec) *corev1.EnvVar {
+	if modelVersion(model) == "" {
+		return nil
+	}
+	env := annotationEnv(ModelVersionEnvName, ModelVersionAnnotation)
+	return &env
+}
+
+// modelVersion 返回模型版本，没有指定模型时返回空
+func modelVersion(model *lstmappsv1.ModelSpec) string {
+	if model == nil {
+		return ""
+	}
+	return model.Version
+}
+
+// httpModelFileName 返回HTTP模型文件保存的文件名，未指定时取URL路径的最后一段
+func httpModelFileName(src *lstmappsv1.HTTPModelSource) string {
+	if src.FileName != "" {
+		return src.FileName
+	}
+	if u, err := url.Parse(src.URL); err == nil {
+		if base := path.Base(u.Path); base != "/" && base != "." && base != ".." {
+			return base
+		}
+	}
+	return "model"
+}
+
+// applyModelToPodSpec 将模型对应的Volume、Init容器、挂载和MODEL_PATH环境变量同步到Pod模板中，返回Pod模板是否发生了变化。
+// 比较时使用DeepDerivative，忽略API Server为已有对象填充的默认值，避免每次调谐都触发更新
+func applyModelToPodSpec(model *lstmappsv1.ModelSpec, podSpec *corev1.PodSpec) bool {
+	wiring := desiredModelWiring(model)
+	if wiring == nil {
+		wiring = &modelWiring{}
+	}
+
+	var changed bool
+	var c bool
+	podSpec.Volumes, c = upsertByName(podSpec.Volumes, ModelVolumeName, wiring.volume, func(v corev1.Volume) string { return v.Name })
+	changed = changed || c
+	podSpec.Volumes, c = upsertByName(podSpec.Volumes, ModelSourceVolumeName, wiring.sourceVolume, func(v corev1.Volume) string { return v.Name })
+	changed = changed || c
+	podSpec.InitContainers, c = upsertByName(podSpec.InitContainers, ModelFetcherContainerName, wiring.fetcher, func(c corev1.Container) string { return c.Name })
+	changed = changed || c
+	podSpec.InitContainers, c = upsertByName(podSpec.InitContainers, ModelStagerContainerName, wiring.stager, func(c corev1.Container) string { return c.Name })
+	changed = changed || c
+
+	container := &podSpec.Containers[0]
+	container.VolumeMounts, c = upsertByName(container.VolumeMounts, ModelVolumeName, wiring.mount, func(m corev1.VolumeMount) string { return m.Name })
+	changed = changed || c
+	container.Env, c = upsertByName(container.Env, ModelPathEnvName, wiring.env, func(e corev1.EnvVar) string { return e.Name })
+	changed = changed || c
+	container.Env, c = upsertByName(container.Env, ModelVersionEnvName, desiredModelVersionEnv(model), func(e corev1.EnvVar) string { return e.Name })
+	changed = changed || c
+
+	return changed
+}
+
+// resolveModel 计算LSTMPredictApp实际使用的模型：没有引用LSTMModel时直接使用spec.model；
+// 引用了LSTMModel时，模型的来源与版本取自LSTMModel，挂载目录与热更新接口取自spec.model。
+// 返回的是新对象，不会修改LSTMPredictApp本身
+func (r *LSTMPredictAppReconciler) resolveModel(ctx context.Context, app *lstmappsv1.LSTMPredictApp) (*lstmappsv1.ModelSpec, error) {
+	if app.Spec.ModelRef == nil {
+		meta.RemoveStatusCondition(&app.Status.Conditions, lstmappsv1.ConditionTypeModelResolved)
+		return app.Spec.Model, nil
+	}
+
+	lm := &lstmappsv1.LSTMModel{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: app.Namespace, Name: app.Spec.ModelRef.Name}, lm); err != nil {
+		if errors.IsNotFound(err) {
+			setCondition(app, lstmappsv1.ConditionTypeModelResolved, metav1.ConditionFalse, ReasonModelNotFound,
+				fmt.Sprintf("LSTMModel %s not found", app.Spec.ModelRef.Name))
+		}
+		return nil, err
+	}
+	if cond := meta.FindStatusCondition(lm.Status.Conditions, lstmappsv1.ConditionTypeValidated); cond == nil ||
+		cond.Status != metav1.ConditionTrue || cond.ObservedGeneration != lm.Generation {
+		message := fmt.Sprintf("LSTMModel %s has not passed validation yet", lm.Name)
+		if cond != nil && cond.Status == metav1.ConditionFalse {
+			message = fmt.Sprintf("LSTMModel %s failed validation: %s", lm.Name, cond.Message)
+		}
+		setCondition(app, lstmappsv1.ConditionTypeModelResolved, metav1.ConditionFalse, ReasonModelInvalid, message)
+		return nil, fmt.Errorf("%s", message)
+	}
+
+	model, err := modelSourceFromArtifactURI(lm.Spec.ArtifactURI, lm.Spec.SHA256)
+	if err != nil {
+		setCondition(app, lstmappsv1.ConditionTypeModelResolved, metav1.ConditionFalse, ReasonModelInvalid, err.Error())
+		return nil, err
+	}
+	model.Version = lm.Spec.Version
+	if app.Spec.Model != nil {
+		model.MountPath = app.Spec.Model.MountPath
+		model.ReloadEndpoint = app.Spec.Model.ReloadEndpoint
+	}
+	setCondition(app, lstmappsv1.ConditionTypeModelResolved, metav1.ConditionTrue, ReasonModelResolved,
+		fmt.Sprintf("Using LSTMModel %s version %s", lm.Name, lm.Spec.Version))
+	return model, nil
+}
+
+// modelSourceFromArtifactURI 将LSTMModel的artifactURI解析为对应的模型来源
+func modelSourceFromArtifactURI(uri, sha256 string) (*lstmappsv1.ModelSpec, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("artifactURI %q is illegal: %w", uri, err)
+	}
+	// pvc://、configmap://、secret://中host部分是对象名，path部分是对象内的相对路径
+	name := u.Host
+	rel := strings.TrimPrefix(u.Path, "/")
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		if u.Host == "" {
+			return nil, fmt.Errorf("artifactURI %q has no host", uri)
+		}
+		if sha256 == "" {
+			return nil, fmt.Errorf("sha256 is required for artifactURI %q", uri)
+		}
+		return &lstmappsv1.ModelSpec{HTTP: &lstmappsv1.HTTPModelSource{URL: uri, SHA256: sha256}}, nil
+	case "oci":
+		ref := strings.TrimPrefix(uri, u.Scheme+"://")
+		if ref == "" {
+			return nil, fmt.Errorf("artifactURI %q has no reference", uri)
+		}
+		return &lstmappsv1.ModelSpec{OCI: &lstmappsv1.OCIModelSource{Artifact: ref}}, nil
+	case "pvc":
+		if name == "" {
+			return nil, fmt.Errorf("artifactURI %q has no claim name", uri)
+		}
+		return &lstmappsv1.ModelSpec{PVC: &lstmappsv1.PVCModelSource{ClaimName: name, Path: rel}}, nil
+	case "configmap":
+		if name == "" {
+			return nil, fmt.Errorf("artifactURI %q has no ConfigMap name", uri)
+		}
+		return &lstmappsv1.ModelSpec{ConfigMap: &lstmappsv1.ObjectModelSource{Name: name, Key: rel}}, nil
+	case "secret":
+		if name == "" {
+			return nil, fmt.Errorf("artifactURI %q has no Secret name", uri)
+		}
+		return &lstmappsv1.ModelSpec{Secret: &lstmappsv1.ObjectModelSource{Name: name, Key: rel}}, nil
+	default:
+		return nil, fmt.Errorf("artifactURI %q has unsupported scheme, should be in {http/https/oci/pvc/configmap/secret}", uri)
+	}
+}
+
+// upsertByName 按名字在列表中插入、替换或删除由控制器管理的元素：desired为nil时删除该元素，
+// 已有元素与desired语义上一致时保持不变
+func upsertByName[T any](items []T, name string, desired *T, nameOf func(T) string) ([]T, bool) {
+	for i := range items {
+		if nameOf(items[i]) != name {
+			continue
+		}
+		if desired == nil {
+			return append(items[:i:i], items[i+1:]...), true
+		}
+		if equality.Semantic.DeepDerivative(*desired, items[i]) {
+			return items, false
+		}
+		items[i] = *desired
+		return items, true
+	}
+	if desired == nil {
+		return items, false
+	}
+	return append(items, *desired), true
+}
@@ -0,0 +1,282 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ModelReloadRequest 调用预测服务热更新接口时发送的请求体
+type ModelReloadRequest struct {
+	Version   string `json:"version"`
+	ModelPath string `json:"modelPath"`
+}
+
+// ModelReloader 负责通知单个Pod加载新版本的模型
+type ModelReloader interface {
+	Reload(ctx context.Context, pod *corev1.Pod, port int32, endpoint string, req ModelReloadRequest) error
+}
+
+// HTTPModelReloader 通过向Pod IP发送HTTP POST请求调用预测服务的热更新接口，返回非2xx状态码视为失败
+type HTTPModelReloader struct {
+	Client *http.Client
+}
+
+var _ ModelReloader = &HTTPModelReloader{}
+
+// 默认的热更新请求超时时间，模型加载可能比较耗时
+const defaultModelReloadTimeout = 30 * time.Second
+
+func (h *HTTPModelReloader) Reload(ctx context.Context, pod *corev1.Pod, port int32, endpoint string, req ModelReloadRequest) error {
+	if pod.Status.PodIP == "" {
+		return fmt.Errorf("pod %s has no IP yet", pod.Name)
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("http://%s%s", net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(int(port))), endpoint)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpClient := h.Client
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultModelReloadTimeout}
+	}
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("reload endpoint %s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// modelReloader 返回调谐器使用的ModelReloader，未配置时使用HTTPModelReloader
+func (r *LSTMPredictAppReconciler) modelReloader() ModelReloader {
+	if r.ModelReloader != nil {
+		return r.ModelReloader
+	}
+	return &HTTPModelReloader{}
+}
+
+const (
+	// 同时热更新的Pod数量上限
+	maxConcurrentModelReloads = 5
+	// 一轮调谐中所有Pod的热更新共享的超时时间
+	modelReloadDeadline = time.Minute
+	// 暂存新版本模型的超时时间，超过后仍无法热更新则退化为滚动重启
+	modelStagingTimeout = 5 * time.Minute
+	// 等待暂存新版本模型时的重新排队间隔
+	modelStagingPollInterval = 10 * time.Second
+)
+
+// reconcileModelVersion 同步Pod模板上的模型注解，并在可以热更新时并发通知就绪Pod加载新版本的模型。
+// templateChanged表示本轮调谐中Pod模板的其他部分已经发生变化（Pod反正要滚动重启）。
+// 返回Pod模板是否需要更新（即需要滚动重启），以及等待暂存新版本模型时的重新排队间隔
+func (r *LSTMPredictAppReconciler) reconcileModelVersion(ctx context.Context, app *lstmappsv1.LSTMPredictApp, model *lstmappsv1.ModelSpec, dp *appsv1.Deployment, templateChanged bool) (bool, time.Duration, error) {
+	log := log.FromContext(ctx)
+
+	desired := modelVersion(model)
+	if desired == "" {
+		app.Status.Model = nil
+		var changed bool
+		for _, key := range modelAnnotationKeys {
+			if _, ok := dp.Spec.Template.Annotations[key]; ok {
+				delete(dp.Spec.Template.Annotations, key)
+				changed = true
+			}
+		}
+		return changed, 0, nil
+	}
+
+	annotations := modelAnnotations(model)
+	current := dp.Spec.Template.Annotations[ModelVersionAnnotation]
+	// 以下情况直接修改注解滚动重启：没有热更新接口；Pod模板的其他部分已经变化；Pod模板上还没有版本注解；版本没有变化而来源变化了
+	if !hasAnnotations(dp.Spec.Template.Annotations, annotations) &&
+		(model.ReloadEndpoint == "" || templateChanged || current == "" || current == desired) {
+		setModelAnnotations(&dp.Spec.Template, annotations)
+		app.Status.Model = &lstmappsv1.ModelStatus{Version: desired}
+		return true, 0, nil
+	}
+
+	// 列出属于该应用的Pod，记录每个Pod加载的模型版本
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(app.Namespace), client.MatchingLabels{"app": app.Name}); err != nil {
+		return false, 0, err
+	}
+	previous := map[string]lstmappsv1.PodModelStatus{}
+	if app.Status.Model != nil {
+		for _, p := range app.Status.Model.Pods {
+			previous[p.PodName] = p
+		}
+	}
+
+	status := &lstmappsv1.ModelStatus{Version: desired}
+	var reloading []*corev1.Pod
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		// Pod加载的版本：优先取热更新记录，否则取Pod启动时的模型版本注解
+		podStatus, ok := previous[pod.Name]
+		if !ok {
+			podStatus = lstmappsv1.PodModelStatus{PodName: pod.Name, Version: pod.Annotations[ModelVersionAnnotation]}
+		}
+		if podStatus.Version != desired && isPodReady(pod) {
+			reloading = append(reloading, pod)
+		}
+		status.Pods = append(status.Pods, podStatus)
+	}
+
+	// 并发热更新，同时进行的数量有上限，所有Pod共享同一个超时时间
+	reloadCtx, cancel := context.WithTimeout(ctx, modelReloadDeadline)
+	defer cancel()
+	podStatuses := map[string]*lstmappsv1.PodModelStatus{}
+	for i := range status.Pods {
+		podStatuses[status.Pods[i].PodName] = &status.Pods[i]
+	}
+	var reloadFailed, staging atomic.Bool
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentModelReloads)
+	for _, pod := range reloading {
+		podStatus := podStatuses[pod.Name]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-reloadCtx.Done():
+				podStatus.Message = reloadCtx.Err().Error()
+				reloadFailed.Store(true)
+				return
+			}
+			waiting, err := r.reloadPodModel(reloadCtx, app, model, pod, annotations, podStatus)
+			switch {
+			case err != nil:
+				log.Error(err, "Failed to reload model, will fall back to a rolling restart.", "pod", pod.Name, "version", desired)
+				podStatus.Message = err.Error()
+				reloadFailed.Store(true)
+			case waiting:
+				log.Info("Waiting for the model to be staged.", "pod", pod.Name, "version", desired)
+				staging.Store(true)
+			default:
+				log.Info("Model has been reloaded.", "pod", pod.Name, "version", desired)
+			}
+		}()
+	}
+	wg.Wait()
+	app.Status.Model = status
+
+	// 任何一个Pod热更新失败，都退化为修改注解滚动重启，新Pod启动时直接加载新版本的模型
+	if reloadFailed.Load() && current != desired {
+		setModelAnnotations(&dp.Spec.Template, annotations)
+		return true, 0, nil
+	}
+	if staging.Load() {
+		return false, modelStagingPollInterval, nil
+	}
+	return false, 0, nil
+}
+
+// reloadPodModel 通知单个Pod加载新版本的模型，成功后更新podStatus。
+// 暂存热更新时先将模型注解写入Pod，由Sidecar暂存新版本的模型，之后再调用热更新接口；
+// 暂存超时之前热更新失败视为仍在暂存，返回true
+func (r *LSTMPredictAppReconciler) reloadPodModel(ctx context.Context, app *lstmappsv1.LSTMPredictApp, model *lstmappsv1.ModelSpec, pod *corev1.Pod, annotations map[string]string, podStatus *lstmappsv1.PodModelStatus) (bool, error) {
+	staged := isStagedModel(model)
+	modelPath := annotations[ModelPathAnnotation]
+	if !staged {
+		modelPath = desiredModelWiring(model).env.Value
+	}
+	if staged {
+		if !hasAnnotations(pod.Annotations, annotations) {
+			patch := client.MergeFrom(pod.DeepCopy())
+			if pod.Annotations == nil {
+				pod.Annotations = map[string]string{}
+			}
+			for k, v := range annotations {
+				pod.Annotations[k] = v
+			}
+			if err := r.Patch(ctx, pod, patch); err != nil {
+				return false, err
+			}
+			podStatus.StagingStartTime = nil
+		}
+		if podStatus.StagingStartTime == nil {
+			now := metav1.Now()
+			podStatus.StagingStartTime = &now
+			podStatus.Message = fmt.Sprintf("Staging model version %s", model.Version)
+			return true, nil
+		}
+	}
+
+	err := r.modelReloader().Reload(ctx, pod, primaryPort(app).ContainerPort, model.ReloadEndpoint,
+		ModelReloadRequest{Version: model.Version, ModelPath: modelPath})
+	if err != nil {
+		if staged && time.Since(podStatus.StagingStartTime.Time) < modelStagingTimeout {
+			podStatus.Message = fmt.Sprintf("Waiting for model version %s to be staged: %v", model.Version, err)
+			return true, nil
+		}
+		return false, err
+	}
+	now := metav1.Now()
+	podStatus.Version = model.Version
+	podStatus.LastReloadTime = &now
+	podStatus.StagingStartTime = nil
+	podStatus.Message = ""
+	return false, nil
+}
+
+// hasAnnotations 判断annotations中的注解是否都已经存在且取值一致
+func hasAnnotations(current, annotations map[string]string) bool {
+	for k, v := range annotations {
+		if got, ok := current[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// setModelAnnotations 将Pod模板上的模型注解设置为annotations，去掉其余的模型注解
+func setModelAnnotations(template *corev1.PodTemplateSpec, annotations map[string]string) {
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	for _, key := range modelAnnotationKeys {
+		if v, ok := annotations[key]; ok {
+			template.Annotations[key] = v
+		} else {
+			delete(template.Annotations, key)
+		}
+	}
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
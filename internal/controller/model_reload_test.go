@@ -0,0 +1,221 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+var _ = Describe("HTTPModelReloader", func() {
+	var (
+		server   *httptest.Server
+		received ModelReloadRequest
+		status   int
+		pod      *corev1.Pod
+		port     int32
+	)
+
+	BeforeEach(func() {
+		status = http.StatusOK
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			defer GinkgoRecover()
+			Expect(req.Method).To(Equal(http.MethodPost))
+			Expect(req.URL.Path).To(Equal("/reload"))
+			Expect(json.NewDecoder(req.Body).Decode(&received)).To(Succeed())
+			w.WriteHeader(status)
+		}))
+
+		host, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+		Expect(err).NotTo(HaveOccurred())
+		p, err := strconv.Atoi(portStr)
+		Expect(err).NotTo(HaveOccurred())
+		port = int32(p)
+		pod = &corev1.Pod{Status: corev1.PodStatus{PodIP: host}}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should post the new model version to the reload endpoint", func() {
+		reloader := &HTTPModelReloader{}
+		Expect(reloader.Reload(ctx, pod, port, "/reload", ModelReloadRequest{Version: "v2", ModelPath: "/models/lstm.pt"})).To(Succeed())
+		Expect(received.Version).To(Equal("v2"))
+		Expect(received.ModelPath).To(Equal("/models/lstm.pt"))
+	})
+
+	It("should fail when the reload endpoint returns a non-2xx status", func() {
+		status = http.StatusInternalServerError
+		reloader := &HTTPModelReloader{}
+		Expect(reloader.Reload(ctx, pod, port, "/reload", ModelReloadRequest{Version: "v2"})).NotTo(Succeed())
+	})
+})
+
+// fakeModelReloader 记录热更新请求以及同时进行的最大请求数
+type fakeModelReloader struct {
+	mu          sync.Mutex
+	delay       time.Duration
+	inFlight    int
+	maxInFlight int
+	requests    map[string]ModelReloadRequest
+}
+
+func (f *fakeModelReloader) Reload(ctx context.Context, pod *corev1.Pod, port int32, endpoint string, req ModelReloadRequest) error {
+	f.mu.Lock()
+	f.inFlight++
+	f.maxInFlight = max(f.maxInFlight, f.inFlight)
+	f.mu.Unlock()
+	time.Sleep(f.delay)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inFlight--
+	f.requests[pod.Name] = req
+	return nil
+}
+
+var _ = Describe("LSTMPredictApp model reload", func() {
+	const resourceName = "reload-app"
+
+	var (
+		reloader             *fakeModelReloader
+		controllerReconciler *LSTMPredictAppReconciler
+		app                  *lstmappsv1.LSTMPredictApp
+		dp                   *appsv1.Deployment
+	)
+
+	createReadyPods := func(n int) {
+		for i := range n {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        fmt.Sprintf("%s-%d", resourceName, i),
+					Namespace:   "default",
+					Labels:      map[string]string{"app": resourceName},
+					Annotations: map[string]string{ModelVersionAnnotation: "v1"},
+				},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: AppContainerName, Image: "lstm-predict-server:v1.0"}}},
+			}
+			Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+			pod.Status = corev1.PodStatus{
+				PodIP:      fmt.Sprintf("10.0.0.%d", i+1),
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			}
+			Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+		}
+	}
+
+	BeforeEach(func() {
+		reloader = &fakeModelReloader{requests: map[string]ModelReloadRequest{}}
+		controllerReconciler = &LSTMPredictAppReconciler{
+			Client:        k8sClient,
+			Scheme:        k8sClient.Scheme(),
+			ModelReloader: reloader,
+		}
+		app = &lstmappsv1.LSTMPredictApp{
+			ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+			Spec: lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				ContainerPort:      8080,
+				BackendAppReplicas: ptr.To[int32](1),
+				ServicePort:        80,
+				ServiceType:        corev1.ServiceTypeClusterIP,
+			},
+		}
+		dp = &appsv1.Deployment{}
+		dp.Spec.Template.Annotations = map[string]string{ModelVersionAnnotation: "v1"}
+	})
+
+	AfterEach(func() {
+		Expect(k8sClient.DeleteAllOf(ctx, &corev1.Pod{}, client.InNamespace("default"),
+			client.MatchingLabels{"app": resourceName})).To(Succeed())
+	})
+
+	It("should reload pods concurrently with a bounded fan-out", func() {
+		reloader.delay = 200 * time.Millisecond
+		createReadyPods(6)
+		model := &lstmappsv1.ModelSpec{
+			Version:        "v2",
+			ReloadEndpoint: "/reload",
+			PVC:            &lstmappsv1.PVCModelSource{ClaimName: "models", Path: "lstm.pt"},
+		}
+
+		start := time.Now()
+		restart, requeueAfter, err := controllerReconciler.reconcileModelVersion(ctx, app, model, dp, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restart).To(BeFalse())
+		Expect(requeueAfter).To(BeZero())
+		Expect(time.Since(start)).To(BeNumerically("<", 6*reloader.delay))
+		Expect(reloader.maxInFlight).To(BeNumerically("<=", maxConcurrentModelReloads))
+		Expect(reloader.requests).To(HaveLen(6))
+		for _, p := range app.Status.Model.Pods {
+			Expect(p.Version).To(Equal("v2"))
+		}
+	})
+
+	It("should stage a new model source before reloading, without a rolling restart", func() {
+		createReadyPods(2)
+		model := &lstmappsv1.ModelSpec{
+			Version:        "v2",
+			ReloadEndpoint: "/reload",
+			HTTP:           &lstmappsv1.HTTPModelSource{URL: "https://models.example.com/v2/lstm.pt", SHA256: strings.Repeat("a", 64)},
+		}
+		dp.Spec.Template.Annotations = modelAnnotations(&lstmappsv1.ModelSpec{
+			Version:        "v1",
+			ReloadEndpoint: "/reload",
+			HTTP:           &lstmappsv1.HTTPModelSource{URL: "https://models.example.com/v1/lstm.pt", SHA256: strings.Repeat("b", 64)},
+		})
+
+		By("writing the new source to the pods for the stager")
+		restart, requeueAfter, err := controllerReconciler.reconcileModelVersion(ctx, app, model, dp, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restart).To(BeFalse())
+		Expect(requeueAfter).To(Equal(modelStagingPollInterval))
+		Expect(reloader.requests).To(BeEmpty())
+		pod := &corev1.Pod{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-0", Namespace: "default"}, pod)).To(Succeed())
+		Expect(pod.Annotations).To(HaveKeyWithValue(ModelSourceAnnotation, "https://models.example.com/v2/lstm.pt"))
+		Expect(pod.Annotations).To(HaveKeyWithValue(ModelPathAnnotation, "/models/v2/lstm.pt"))
+
+		By("reloading from the versioned path on the next reconcile")
+		restart, requeueAfter, err = controllerReconciler.reconcileModelVersion(ctx, app, model, dp, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restart).To(BeFalse())
+		Expect(requeueAfter).To(BeZero())
+		Expect(reloader.requests).To(HaveLen(2))
+		Expect(reloader.requests[resourceName+"-0"]).To(Equal(ModelReloadRequest{Version: "v2", ModelPath: "/models/v2/lstm.pt"}))
+		Expect(dp.Spec.Template.Annotations).To(HaveKeyWithValue(ModelVersionAnnotation, "v1"))
+	})
+})
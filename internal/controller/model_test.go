@@ -0,0 +1,88 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+var _ = Describe("Model wiring", func() {
+	It("should pass the HTTP source to the downloader through env instead of the script", func() {
+		url := "https://models.example.com/lstm.pt?sig=x';reboot;'"
+		model := &lstmappsv1.ModelSpec{HTTP: &lstmappsv1.HTTPModelSource{
+			URL:    url,
+			SHA256: strings.Repeat("A", 64),
+		}}
+
+		wiring := desiredModelWiring(model)
+		fetcher, env := wiring.fetcher, wiring.env
+		Expect(fetcher).NotTo(BeNil())
+		Expect(fetcher.Command).To(Equal([]string{"sh", "-c", httpModelDownloadScript}))
+		Expect(fetcher.Args).To(BeEmpty())
+		Expect(fetcher.Env).To(ConsistOf(
+			corev1.EnvVar{Name: "MODEL_URL", Value: url},
+			corev1.EnvVar{Name: "MODEL_SHA256", Value: strings.Repeat("a", 64)},
+			corev1.EnvVar{Name: "MODEL_FILE", Value: "/models/lstm.pt"},
+		))
+		Expect(env.Value).To(Equal("/models/lstm.pt"))
+	})
+
+	It("should keep the source of a staged model out of the containers", func() {
+		stagedModel := func(version, url string) *lstmappsv1.ModelSpec {
+			return &lstmappsv1.ModelSpec{
+				Version:        version,
+				ReloadEndpoint: "/reload",
+				HTTP:           &lstmappsv1.HTTPModelSource{URL: url, SHA256: strings.Repeat("a", 64)},
+			}
+		}
+		app := &lstmappsv1.LSTMPredictApp{
+			ObjectMeta: metav1.ObjectMeta{Name: "staged-model", Namespace: "default"},
+			Spec: lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				ContainerPort:      8080,
+				BackendAppReplicas: ptr.To[int32](1),
+				ServicePort:        80,
+				ServiceType:        corev1.ServiceTypeClusterIP,
+			},
+		}
+		v1 := stagedModel("v1", "https://models.example.com/v1/lstm.pt")
+		v2 := stagedModel("v2", "https://models.example.com/v2/lstm.pt")
+
+		old, updated := desiredPodTemplate(app, v1), desiredPodTemplate(app, v2)
+		Expect(updated.Spec).To(Equal(old.Spec))
+		Expect(updated.Spec.InitContainers).To(HaveLen(2))
+		Expect(updated.Spec.InitContainers[1].Name).To(Equal(ModelStagerContainerName))
+		Expect(*updated.Spec.InitContainers[1].RestartPolicy).To(Equal(corev1.ContainerRestartPolicyAlways))
+
+		Expect(modelAnnotations(v2)).To(Equal(map[string]string{
+			ModelVersionAnnotation: "v2",
+			ModelSourceAnnotation:  "https://models.example.com/v2/lstm.pt",
+			ModelSHA256Annotation:  strings.Repeat("a", 64),
+			ModelDirAnnotation:     "/models/v2",
+			ModelPathAnnotation:    "/models/v2/lstm.pt",
+		}))
+		Expect(modelVersionDir("../v3/x")).To(Equal("_.._v3_x"))
+	})
+})
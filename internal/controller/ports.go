@@ -0,0 +1,70 @@
+package controller
+
+import (
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// appPorts 返回预测服务的全部端口，第一个是主端口。
+// 使用ContainerPort与ServicePort简写时只有一个未命名的端口，与支持多端口之前生成的容器和Service保持一致
+func appPorts(app *lstmappsv1.LSTMPredictApp) []lstmappsv1.PortSpec {
+	if len(app.Spec.Ports) == 0 {
+		return []lstmappsv1.PortSpec{{
+			ContainerPort: app.Spec.ContainerPort,
+			ServicePort:   app.Spec.ServicePort,
+			Protocol:      corev1.ProtocolTCP,
+		}}
+	}
+	ports := make([]lstmappsv1.PortSpec, 0, len(app.Spec.Ports))
+	for _, port := range app.Spec.Ports {
+		// 以下默认值一般由Webhook注入，这里作为兜底
+		if port.ServicePort == 0 {
+			port.ServicePort = port.ContainerPort
+		}
+		if port.Protocol == "" {
+			port.Protocol = corev1.ProtocolTCP
+		}
+		ports = append(ports, port)
+	}
+	return ports
+}
+
+// primaryPort 返回预测服务的主端口
+func primaryPort(app *lstmappsv1.LSTMPredictApp) lstmappsv1.PortSpec {
+	return appPorts(app)[0]
+}
+
+// containerPorts 构造预测容器的端口列表
+func containerPorts(app *lstmappsv1.LSTMPredictApp) []corev1.ContainerPort {
+	var ports []corev1.ContainerPort
+	for _, port := range appPorts(app) {
+		containerPort := corev1.ContainerPort{Name: port.Name, ContainerPort: port.ContainerPort}
+		// 简写的端口不设置协议，与支持多端口之前生成的Pod模板保持一致，避免升级后滚动重启
+		if port.Name != "" {
+			containerPort.Protocol = port.Protocol
+		}
+		ports = append(ports, containerPort)
+	}
+	return ports
+}
+
+// servicePorts 构造Service的端口列表
+func servicePorts(app *lstmappsv1.LSTMPredictApp) []corev1.ServicePort {
+	var ports []corev1.ServicePort
+	for _, port := range appPorts(app) {
+		ports = append(ports, corev1.ServicePort{
+			Name:        port.Name,
+			Protocol:    port.Protocol,
+			AppProtocol: port.AppProtocol,
+			Port:        port.ServicePort,
+			TargetPort:  intstr.FromInt32(port.ContainerPort),
+		})
+	}
+	return ports
+}
+
+// findServicePort 按端口名在Service中找到对应的端口
+func findServicePort(svc *corev1.Service, name string) *corev1.ServicePort {
+	return findByKey(svc.Spec.Ports, name, func(p corev1.ServicePort) string { return p.Name })
+}
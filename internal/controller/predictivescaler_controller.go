@@ -0,0 +1,281 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+// PredictiveScaler的默认预测间隔与缩容冷却时间
+const (
+	DefaultPredictInterval   = time.Minute
+	DefaultScaleDownCooldown = 5 * time.Minute
+)
+
+// PredictiveScalerReconciler reconciles a PredictiveScaler object
+type PredictiveScalerReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// HistoryProvider 查询历史指标，为空时使用PrometheusMetricProvider
+	HistoryProvider MetricHistoryProvider
+	// Predictor 调用预测服务，为空时使用HTTPPredictor
+	Predictor Predictor
+}
+
+// +kubebuilder:rbac:groups=lstmapps.wuyong7240.com,resources=predictivescalers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=lstmapps.wuyong7240.com,resources=predictivescalers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=lstmapps.wuyong7240.com,resources=lstmpredictapps,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=get;list;watch;update;patch
+
+// Reconcile 定期查询历史指标并调用LSTMPredictApp预测未来的负载，
+// 在负载到来之前按预测值调整目标工作负载的副本数
+func (r *PredictiveScalerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	ps := &lstmappsv1.PredictiveScaler{}
+	if err := r.Get(ctx, req.NamespacedName, ps); err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("PredictiveScaler not found.")
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get the PredictiveScaler, will requeue after a short time.")
+		return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+	}
+	interval := predictInterval(ps)
+
+	// 预测失败时不修改副本数，只记录原因，等下一个周期重试
+	predicted, err := r.predictPeak(ctx, ps)
+	if err != nil {
+		log.Info("Prediction failed, keep the current replicas.", "reason", err.Error())
+		return r.updateStatus(ctx, ps, metav1.ConditionFalse, ReasonPredictionFailed, err.Error(), interval)
+	}
+	now := metav1.Now()
+	ps.Status.LastPredictionTime = &now
+	ps.Status.PredictedValue = strconv.FormatFloat(predicted, 'f', -1, 64)
+
+	target, replicas, err := r.getScaleTarget(ctx, ps)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			msg := fmt.Sprintf("%s %s not found", ps.Spec.TargetRef.Kind, ps.Spec.TargetRef.Name)
+			return r.updateStatus(ctx, ps, metav1.ConditionFalse, ReasonTargetNotFound, msg, interval)
+		}
+		log.Error(err, "Failed to get the scale target, will requeue after a short time.")
+		return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+	}
+
+	current := int32(1)
+	if replicas != nil && *replicas != nil {
+		current = **replicas
+	}
+	desired := predictedReplicas(ps, predicted)
+	ps.Status.CurrentReplicas = current
+	ps.Status.DesiredReplicas = desired
+
+	msg := fmt.Sprintf("Predicted peak %s, %d replicas desired", ps.Status.PredictedValue, desired)
+	if desired != current {
+		if wait := scaleCooldownRemaining(ps, desired > current, now.Time); wait > 0 {
+			msg = fmt.Sprintf("%s, waiting %s for the cooldown", msg, wait.Round(time.Second))
+		} else {
+			patch := client.MergeFrom(target.DeepCopyObject().(client.Object))
+			*replicas = &desired
+			if err := r.Patch(ctx, target, patch); err != nil {
+				log.Error(err, "Failed to scale the target, will requeue after a short time.")
+				return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+			}
+			log.Info("The scale target has been scaled.", "from", current, "to", desired)
+			ps.Status.CurrentReplicas = desired
+			ps.Status.LastScaleTime = &now
+		}
+	}
+	return r.updateStatus(ctx, ps, metav1.ConditionTrue, ReasonPredicted, msg, interval)
+}
+
+// defaultPredictEndpoint 返回LSTMPredictApp主端口上的预测接口地址。Headless Service的域名直接解析为Pod IP，
+// 不经过servicePort到containerPort的映射，此时使用容器端口
+func defaultPredictEndpoint(app *lstmappsv1.LSTMPredictApp) string {
+	port := primaryPort(app)
+	number := port.ServicePort
+	if isHeadless(app) {
+		number = port.ContainerPort
+	}
+	return fmt.Sprintf("http://%s.%s.svc:%d/predict", app.Name, app.Namespace, number)
+}
+
+// predictPeak 查询历史指标并调用预测服务，返回未来leadTime内预测值的最大值
+func (r *PredictiveScalerReconciler) predictPeak(ctx context.Context, ps *lstmappsv1.PredictiveScaler) (float64, error) {
+	endpoint := ps.Spec.PredictEndpoint
+	if endpoint == "" {
+		app := &lstmappsv1.LSTMPredictApp{}
+		err := r.Get(ctx, types.NamespacedName{Namespace: ps.Namespace, Name: ps.Spec.PredictAppRef.Name}, app)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get LSTMPredictApp %s: %w", ps.Spec.PredictAppRef.Name, err)
+		}
+		endpoint = defaultPredictEndpoint(app)
+	}
+
+	history := ps.Spec.History
+	step := history.Step.Duration
+	if step <= 0 {
+		return 0, fmt.Errorf("history step must be positive")
+	}
+	end := time.Now()
+	start := end.Add(-time.Duration(history.Points-1) * step)
+	values, err := r.historyProvider().QueryRange(ctx, history.PrometheusURL, history.Query, start, end, step)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query history: %w", err)
+	}
+	// Prometheus可能多返回一个数据点，只保留模型需要的最近points个
+	if len(values) > int(history.Points) {
+		values = values[len(values)-int(history.Points):]
+	}
+
+	horizon := predictHorizon(ps)
+	predictions, err := r.predictor().Predict(ctx, endpoint, PredictRequest{History: values, Horizon: horizon})
+	if err != nil {
+		return 0, fmt.Errorf("failed to predict: %w", err)
+	}
+	if len(predictions) == 0 {
+		return 0, fmt.Errorf("predictor returned no predictions")
+	}
+	if len(predictions) > int(horizon) {
+		predictions = predictions[:horizon]
+	}
+	peak := predictions[0]
+	for _, v := range predictions[1:] {
+		peak = math.Max(peak, v)
+	}
+	return peak, nil
+}
+
+// getScaleTarget 获取被扩缩容的工作负载，同时返回指向其spec.replicas的指针，便于直接修改
+func (r *PredictiveScalerReconciler) getScaleTarget(ctx context.Context, ps *lstmappsv1.PredictiveScaler) (client.Object, **int32, error) {
+	key := types.NamespacedName{Namespace: ps.Namespace, Name: ps.Spec.TargetRef.Name}
+	switch ps.Spec.TargetRef.Kind {
+	case "Deployment":
+		dp := &appsv1.Deployment{}
+		if err := r.Get(ctx, key, dp); err != nil {
+			return nil, nil, err
+		}
+		return dp, &dp.Spec.Replicas, nil
+	case "StatefulSet":
+		sts := &appsv1.StatefulSet{}
+		if err := r.Get(ctx, key, sts); err != nil {
+			return nil, nil, err
+		}
+		return sts, &sts.Spec.Replicas, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported scale target kind %q", ps.Spec.TargetRef.Kind)
+	}
+}
+
+// updateStatus 设置Ready条件并更新状态，之后按预测间隔重新入队
+func (r *PredictiveScalerReconciler) updateStatus(ctx context.Context, ps *lstmappsv1.PredictiveScaler, status metav1.ConditionStatus, reason, message string, interval time.Duration) (ctrl.Result, error) {
+	meta.SetStatusCondition(&ps.Status.Conditions, metav1.Condition{
+		Type:               lstmappsv1.ConditionTypeReady,
+		Status:             status,
+		ObservedGeneration: ps.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+	ps.Status.ObservedGeneration = ps.Generation
+	if err := r.Status().Update(ctx, ps); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to update PredictiveScaler status.")
+		return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+	}
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
+// predictInterval 返回两次预测之间的间隔
+func predictInterval(ps *lstmappsv1.PredictiveScaler) time.Duration {
+	if ps.Spec.Interval != nil && ps.Spec.Interval.Duration > 0 {
+		return ps.Spec.Interval.Duration
+	}
+	return DefaultPredictInterval
+}
+
+// predictHorizon 返回需要预测的数据点个数，至少覆盖leadTime，最少为1
+func predictHorizon(ps *lstmappsv1.PredictiveScaler) int32 {
+	step := ps.Spec.History.Step.Duration
+	horizon := int32(math.Ceil(float64(ps.Spec.LeadTime.Duration) / float64(step)))
+	return max(horizon, 1)
+}
+
+// predictedReplicas 根据预测值计算期望副本数，并限制在[minReplicas, maxReplicas]之间
+func predictedReplicas(ps *lstmappsv1.PredictiveScaler, predicted float64) int32 {
+	perReplica := ps.Spec.TargetValuePerReplica.AsApproximateFloat64()
+	desired := ps.Spec.MaxReplicas
+	if perReplica > 0 {
+		desired = int32(min(math.Ceil(predicted/perReplica), float64(ps.Spec.MaxReplicas)))
+	}
+	return max(desired, ps.Spec.MinReplicas)
+}
+
+// scaleCooldownRemaining 返回距离冷却结束还需等待的时间，扩容与缩容使用各自的冷却时间
+func scaleCooldownRemaining(ps *lstmappsv1.PredictiveScaler, scaleUp bool, now time.Time) time.Duration {
+	if ps.Status.LastScaleTime == nil {
+		return 0
+	}
+	cooldown := ps.Spec.ScaleUpCooldown.Duration
+	if !scaleUp {
+		cooldown = DefaultScaleDownCooldown
+		if ps.Spec.ScaleDownCooldown != nil {
+			cooldown = ps.Spec.ScaleDownCooldown.Duration
+		}
+	}
+	return ps.Status.LastScaleTime.Add(cooldown).Sub(now)
+}
+
+func (r *PredictiveScalerReconciler) historyProvider() MetricHistoryProvider {
+	if r.HistoryProvider != nil {
+		return r.HistoryProvider
+	}
+	return &PrometheusMetricProvider{}
+}
+
+func (r *PredictiveScalerReconciler) predictor() Predictor {
+	if r.Predictor != nil {
+		return r.Predictor
+	}
+	return &HTTPPredictor{}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PredictiveScalerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		// 状态更新不会修改generation，预测由RequeueAfter定期触发
+		For(&lstmappsv1.PredictiveScaler{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Named("predictivescaler").
+		Complete(r)
+}
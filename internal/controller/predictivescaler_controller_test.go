@@ -0,0 +1,222 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+var _ = Describe("PredictiveScaler Controller", func() {
+	const (
+		resourceName = "predictive-scaler"
+		targetName   = "scaled-frontend"
+	)
+
+	ctx := context.Background()
+	typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+	targetNamespacedName := types.NamespacedName{Name: targetName, Namespace: "default"}
+
+	var (
+		server      *httptest.Server
+		predictions []float64
+		lastRequest PredictRequest
+		reconciler  *PredictiveScalerReconciler
+	)
+
+	reconcileOnce := func() reconcile.Result {
+		result, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).NotTo(HaveOccurred())
+		return result
+	}
+
+	BeforeEach(func() {
+		// 同一个本地HTTP服务同时充当Prometheus与预测服务
+		mux := http.NewServeMux()
+		mux.HandleFunc("/api/v1/query_range", func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[{"metric":{},` +
+				`"values":[[1,"100"],[2,"120"],[3,"140"],[4,"160"]]}]}}`))
+		})
+		mux.HandleFunc("/predict", func(w http.ResponseWriter, r *http.Request) {
+			Expect(json.NewDecoder(r.Body).Decode(&lastRequest)).To(Succeed())
+			_ = json.NewEncoder(w).Encode(PredictResponse{Predictions: predictions})
+		})
+		server = httptest.NewServer(mux)
+		predictions = []float64{250, 480, 900}
+		reconciler = &PredictiveScalerReconciler{
+			Client: k8sClient,
+			Scheme: k8sClient.Scheme(),
+		}
+
+		labels := map[string]string{"app": targetName}
+		target := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: targetName, Namespace: "default"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: ptr.To[int32](2),
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: labels},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "web", Image: "nginx:1.27"}},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, target)).To(Succeed())
+
+		ps := &lstmappsv1.PredictiveScaler{
+			ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+			Spec: lstmappsv1.PredictiveScalerSpec{
+				PredictAppRef:   lstmappsv1.LSTMPredictAppReference{Name: "lstm-app"},
+				TargetRef:       lstmappsv1.ScaleTargetReference{Kind: "Deployment", Name: targetName},
+				PredictEndpoint: server.URL + "/predict",
+				History: lstmappsv1.MetricHistory{
+					PrometheusURL: server.URL,
+					Query:         "sum(rate(http_requests_total[1m]))",
+					Step:          metav1.Duration{Duration: time.Minute},
+					Points:        4,
+				},
+				TargetValuePerReplica: resource.MustParse("100"),
+				LeadTime:              metav1.Duration{Duration: 2 * time.Minute},
+				MinReplicas:           1,
+				MaxReplicas:           10,
+				Interval:              &metav1.Duration{Duration: 30 * time.Second},
+			},
+		}
+		Expect(k8sClient.Create(ctx, ps)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		ps := &lstmappsv1.PredictiveScaler{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, ps)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, ps)).To(Succeed())
+		target := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, targetNamespacedName, target)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, target)).To(Succeed())
+	})
+
+	It("should scale the target to the peak predicted within the lead time", func() {
+		result := reconcileOnce()
+		Expect(result.RequeueAfter).To(Equal(30 * time.Second))
+		Expect(lastRequest.History).To(Equal([]float64{100, 120, 140, 160}))
+		Expect(lastRequest.Horizon).To(Equal(int32(2)))
+
+		target := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, targetNamespacedName, target)).To(Succeed())
+		Expect(*target.Spec.Replicas).To(Equal(int32(5)))
+
+		ps := &lstmappsv1.PredictiveScaler{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, ps)).To(Succeed())
+		Expect(ps.Status.PredictedValue).To(Equal("480"))
+		Expect(ps.Status.DesiredReplicas).To(Equal(int32(5)))
+		Expect(ps.Status.LastScaleTime).NotTo(BeNil())
+		Expect(meta.IsStatusConditionTrue(ps.Status.Conditions, lstmappsv1.ConditionTypeReady)).To(BeTrue())
+	})
+
+	It("should clamp the desired replicas to minReplicas and maxReplicas", func() {
+		predictions = []float64{5000}
+		reconcileOnce()
+		target := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, targetNamespacedName, target)).To(Succeed())
+		Expect(*target.Spec.Replicas).To(Equal(int32(10)))
+
+		ps := &lstmappsv1.PredictiveScaler{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, ps)).To(Succeed())
+		Expect(ps.Status.DesiredReplicas).To(Equal(int32(10)))
+
+		ps.Spec.MinReplicas = 3
+		Expect(predictedReplicas(ps, 0)).To(Equal(int32(3)))
+		ps.Spec.TargetValuePerReplica = resource.MustParse("0")
+		Expect(predictedReplicas(ps, 50)).To(Equal(int32(10)))
+	})
+
+	It("should call the prediction service on the container port when the Service is headless", func() {
+		app := &lstmappsv1.LSTMPredictApp{
+			ObjectMeta: metav1.ObjectMeta{Name: "predict-app", Namespace: "default"},
+			Spec: lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				ContainerPort:      8080,
+				BackendAppReplicas: ptr.To[int32](1),
+				ServicePort:        80,
+				ServiceType:        corev1.ServiceTypeClusterIP,
+			},
+		}
+		Expect(defaultPredictEndpoint(app)).To(Equal("http://predict-app.default.svc:80/predict"))
+
+		app.Spec.Headless = true
+		Expect(defaultPredictEndpoint(app)).To(Equal("http://predict-app.default.svc:8080/predict"))
+	})
+
+	It("should reject minReplicas above maxReplicas and negative durations", func() {
+		ps := &lstmappsv1.PredictiveScaler{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, ps)).To(Succeed())
+		invalid := ps.DeepCopy()
+		invalid.Spec.MinReplicas = 11
+		Expect(k8sClient.Update(ctx, invalid)).To(MatchError(ContainSubstring("minReplicas can't > maxReplicas")))
+
+		invalid = ps.DeepCopy()
+		invalid.Spec.LeadTime = metav1.Duration{Duration: -time.Minute}
+		Expect(k8sClient.Update(ctx, invalid)).To(MatchError(ContainSubstring("leadTime can't be negative")))
+	})
+
+	It("should not scale down before the cooldown expires", func() {
+		reconcileOnce()
+		predictions = []float64{50, 50}
+		reconcileOnce()
+
+		target := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, targetNamespacedName, target)).To(Succeed())
+		Expect(*target.Spec.Replicas).To(Equal(int32(5)))
+
+		ps := &lstmappsv1.PredictiveScaler{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, ps)).To(Succeed())
+		Expect(ps.Status.DesiredReplicas).To(Equal(int32(1)))
+		Expect(meta.FindStatusCondition(ps.Status.Conditions, lstmappsv1.ConditionTypeReady).Message).To(ContainSubstring("cooldown"))
+	})
+
+	It("should keep the replicas when the prediction fails", func() {
+		predictions = nil
+		reconcileOnce()
+
+		target := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, targetNamespacedName, target)).To(Succeed())
+		Expect(*target.Spec.Replicas).To(Equal(int32(2)))
+
+		ps := &lstmappsv1.PredictiveScaler{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, ps)).To(Succeed())
+		condition := meta.FindStatusCondition(ps.Status.Conditions, lstmappsv1.ConditionTypeReady)
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(ReasonPredictionFailed))
+	})
+})
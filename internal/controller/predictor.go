@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// PredictRequest 调用预测服务的预测接口时发送的请求体
+type PredictRequest struct {
+	// 按时间排列的历史数据点，最后一个是最新的
+	History []float64 `json:"history"`
+	// 需要预测的未来数据点个数
+	Horizon int32 `json:"horizon"`
+}
+
+// PredictResponse 预测接口返回的响应体
+type PredictResponse struct {
+	// 按时间排列的未来数据点
+	Predictions []float64 `json:"predictions"`
+}
+
+// Predictor 调用LSTM预测服务，根据历史数据预测未来的数据点
+type Predictor interface {
+	Predict(ctx context.Context, endpoint string, req PredictRequest) ([]float64, error)
+}
+
+// HTTPPredictor 通过HTTP POST调用预测服务的预测接口，返回非2xx状态码视为失败
+type HTTPPredictor struct {
+	Client *http.Client
+}
+
+var _ Predictor = &HTTPPredictor{}
+
+// 默认的预测请求超时时间
+const defaultPredictTimeout = 30 * time.Second
+
+func (h *HTTPPredictor) Predict(ctx context.Context, endpoint string, req PredictRequest) ([]float64, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpClient := h.Client
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultPredictTimeout}
+	}
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("predict endpoint %s returned %s", endpoint, resp.Status)
+	}
+
+	predictResp := &PredictResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(predictResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response from %s: %w", endpoint, err)
+	}
+	if len(predictResp.Predictions) == 0 {
+		return nil, fmt.Errorf("predict endpoint %s returned no predictions", endpoint)
+	}
+	return predictResp.Predictions, nil
+}
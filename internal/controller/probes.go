@@ -0,0 +1,55 @@
+package controller
+
+import (
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// 以下默认值一般由Webhook注入，这里作为兜底
+	DefaultProbePath = "/healthz"
+)
+
+var (
+	defaultLivenessProbe  = lstmappsv1.ProbeTiming{PeriodSeconds: 10, TimeoutSeconds: 1, FailureThreshold: 3}
+	defaultReadinessProbe = lstmappsv1.ProbeTiming{PeriodSeconds: 5, TimeoutSeconds: 1, FailureThreshold: 3}
+	// 启动探针给模型加载留出10分钟
+	defaultStartupProbe = lstmappsv1.ProbeTiming{PeriodSeconds: 10, TimeoutSeconds: 1, FailureThreshold: 60}
+)
+
+// applyProbesToContainer 根据spec.probes为预测容器设置存活、就绪与启动探针，均以HTTP GET访问主端口。
+// 未指定spec.probes（未经过Webhook默认注入的旧对象）或者关闭探针时不设置
+func applyProbesToContainer(app *lstmappsv1.LSTMPredictApp, container *corev1.Container) {
+	probes := app.Spec.Probes
+	if probes == nil || probes.Disabled {
+		return
+	}
+	path := probes.Path
+	if path == "" {
+		path = DefaultProbePath
+	}
+	readinessPath := probes.ReadinessPath
+	if readinessPath == "" {
+		readinessPath = path
+	}
+	port := intstr.FromInt32(primaryPort(app).ContainerPort)
+	container.LivenessProbe = httpProbe(path, port, probes.Liveness, defaultLivenessProbe)
+	container.ReadinessProbe = httpProbe(readinessPath, port, probes.Readiness, defaultReadinessProbe)
+	container.StartupProbe = httpProbe(path, port, probes.Startup, defaultStartupProbe)
+}
+
+func httpProbe(path string, port intstr.IntOrString, timing *lstmappsv1.ProbeTiming, fallback lstmappsv1.ProbeTiming) *corev1.Probe {
+	if timing == nil {
+		timing = &fallback
+	}
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{Path: path, Port: port},
+		},
+		InitialDelaySeconds: timing.InitialDelaySeconds,
+		PeriodSeconds:       timing.PeriodSeconds,
+		TimeoutSeconds:      timing.TimeoutSeconds,
+		FailureThreshold:    timing.FailureThreshold,
+	}
+}
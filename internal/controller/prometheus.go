@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MetricProvider 查询指标的数值，金丝雀分析等需要根据指标做决策的地方都通过它查询
+type MetricProvider interface {
+	// Query 执行即时查询，返回结果中的第一个数值
+	Query(ctx context.Context, address, query string) (float64, error)
+}
+
+// MetricHistoryProvider 查询一段时间内的指标，作为预测模型的输入
+type MetricHistoryProvider interface {
+	// QueryRange 执行范围查询，返回结果中第一条时间序列按时间排列的数值
+	QueryRange(ctx context.Context, address, query string, start, end time.Time, step time.Duration) ([]float64, error)
+}
+
+// PrometheusMetricProvider 通过Prometheus的HTTP API查询指标
+type PrometheusMetricProvider struct {
+	Client *http.Client
+}
+
+var (
+	_ MetricProvider        = &PrometheusMetricProvider{}
+	_ MetricHistoryProvider = &PrometheusMetricProvider{}
+)
+
+// 默认的指标查询超时时间
+const defaultMetricQueryTimeout = 10 * time.Second
+
+// prometheusResponse Prometheus HTTP API的响应，只解析用到的字段
+type prometheusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Value  []any   `json:"value"`
+			Values [][]any `json:"values"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+func (p *PrometheusMetricProvider) Query(ctx context.Context, address, query string) (float64, error) {
+	resp, err := p.get(ctx, address, "/api/v1/query", url.Values{"query": {query}})
+	if err != nil {
+		return 0, err
+	}
+	if len(resp.Data.Result) == 0 || len(resp.Data.Result[0].Value) != 2 {
+		return 0, fmt.Errorf("query %q returned no data", query)
+	}
+	return parseSampleValue(resp.Data.Result[0].Value[1])
+}
+
+func (p *PrometheusMetricProvider) QueryRange(ctx context.Context, address, query string, start, end time.Time, step time.Duration) ([]float64, error) {
+	resp, err := p.get(ctx, address, "/api/v1/query_range", url.Values{
+		"query": {query},
+		"start": {strconv.FormatInt(start.Unix(), 10)},
+		"end":   {strconv.FormatInt(end.Unix(), 10)},
+		"step":  {strconv.FormatFloat(step.Seconds(), 'f', -1, 64)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data.Result) == 0 || len(resp.Data.Result[0].Values) == 0 {
+		return nil, fmt.Errorf("query %q returned no data", query)
+	}
+	values := make([]float64, 0, len(resp.Data.Result[0].Values))
+	for _, sample := range resp.Data.Result[0].Values {
+		if len(sample) != 2 {
+			return nil, fmt.Errorf("unexpected sample %v", sample)
+		}
+		v, err := parseSampleValue(sample[1])
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func (p *PrometheusMetricProvider) get(ctx context.Context, address, apiPath string, params url.Values) (*prometheusResponse, error) {
+	u := strings.TrimSuffix(address, "/") + apiPath + "?" + params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpClient := p.Client
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultMetricQueryTimeout}
+	}
+	httpResp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close() //nolint:errcheck
+
+	resp := &prometheusResponse{}
+	if err := json.NewDecoder(httpResp.Body).Decode(resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response from %s: %w", address, err)
+	}
+	if resp.Status != "success" {
+		return nil, fmt.Errorf("prometheus query failed: %s", resp.Error)
+	}
+	return resp, nil
+}
+
+// parseSampleValue 解析Prometheus样本中以字符串表示的数值
+func parseSampleValue(v any) (float64, error) {
+	s, ok := v.(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected sample value %v", v)
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// metricProvider 返回调谐器使用的MetricProvider，未配置时使用PrometheusMetricProvider
+func (r *LSTMPredictAppReconciler) metricProvider() MetricProvider {
+	if r.MetricProvider != nil {
+		return r.MetricProvider
+	}
+	return &PrometheusMetricProvider{}
+}
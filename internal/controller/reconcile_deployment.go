@@ -2,12 +2,17 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
 	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -15,11 +20,26 @@ import (
 
 func (r *LSTMPredictAppReconciler) reconcileDeployment(ctx context.Context, app *lstmappsv1.LSTMPredictApp) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
+	var result ctrl.Result
+
+	// 解析实际使用的模型：spec.model或者spec.modelRef引用的LSTMModel
+	model, err := r.resolveModel(ctx, app)
+	if err != nil {
+		log.Error(err, "Failed to resolve the model, will requeue after a short time.")
+		if cond := meta.FindStatusCondition(app.Status.Conditions, lstmappsv1.ConditionTypeModelResolved); cond != nil && cond.Status == metav1.ConditionFalse {
+			r.event(app, corev1.EventTypeWarning, cond.Reason, cond.Message)
+		}
+		app.Status.Message = statusMessage(app)
+		if statusErr := r.Status().Update(ctx, app); statusErr != nil {
+			log.Error(statusErr, "Failed to update LSTMPredictApp status.")
+		}
+		return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+	}
 
 	// 先根据LSTMPredictApp中的Namespace和Name信息查询对应的Deployment是否存在
 	var dp = &appsv1.Deployment{}
 	// types.NamespaceedName用于唯一标识Kubernetes集群中的资源,dp是一个指针，如果Get方法成功执行，这个指针指向从API服务器中获取的Deployment对象
-	err := r.Get(ctx, types.NamespacedName{
+	err = r.Get(ctx, types.NamespacedName{
 		Namespace: app.Namespace,
 		Name:      app.Name,
 	}, dp)
@@ -28,34 +48,153 @@ func (r *LSTMPredictAppReconciler) reconcileDeployment(ctx context.Context, app
 	if err == nil {
 		log.Info("The Deployment has already exist.")
 
-		// 属性更新
-		var isChanged bool = false
-		dp.Spec.Template.Spec.Containers[0].Image = app.Spec.AppImage
-		dp.Spec.Template.Spec.Containers[0].Ports[0].ContainerPort = app.Spec.ContainerPort
-		if *app.Spec.BackendAppReplicas != *dp.Spec.Replicas {
-			dp.Spec.Replicas = app.Spec.BackendAppReplicas
-			isChanged = true
+		// 启用自动扩缩容后先将副本数交给HPA，之后的服务端应用不再包含副本数
+		if isAutoscalingEnabled(app) {
+			if err = r.handOverReplicas(ctx, dp); err != nil {
+				log.Error(err, "Failed to hand the Deployment replicas over to the HPA, will requeue after a short time.")
+				return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+			}
+		}
+
+		// 新版本超过progressDeadlineSeconds仍未就绪时，回滚到最近一次就绪的版本
+		if _, err = r.rollbackDeployment(ctx, app, dp); err != nil {
+			if isApplyConflict(err) {
+				return ctrl.Result{}, err
+			}
+			log.Error(err, "Failed to roll back Deployment, will requeue after a short time.")
+			return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+		}
+
+		// 属性更新：从零构造期望的Pod模板，与集群中的Pod模板语义比较，得出漂移的字段（Spec变化或者被手动修改）
+		stable := dp.DeepCopy()
+		dp.Spec.Template = desiredPodTemplate(app, model)
+		// 模型注解先沿用当前值，由reconcileModelVersion决定热更新还是修改注解滚动重启
+		current := map[string]string{}
+		for key := range modelAnnotations(model) {
+			if v, ok := stable.Spec.Template.Annotations[key]; ok {
+				current[key] = v
+			}
+		}
+		if len(current) > 0 {
+			setModelAnnotations(&dp.Spec.Template, current)
+		}
+		drift := templateDrift(&dp.Spec.Template, &stable.Spec.Template)
+		// 期望模板中去掉的字段（如删除了模型来源）不会出现在漂移中，通过模板版本发现
+		templateChanged := len(drift) > 0 || computeTemplateHash(&dp.Spec.Template) != deploymentRevision(stable)
+		// 模型版本：可以热更新时逐个通知Pod加载新模型，否则修改Pod模板注解滚动重启
+		var needRestart bool
+		var modelRequeueAfter time.Duration
+		if needRestart, modelRequeueAfter, err = r.reconcileModelVersion(ctx, app, model, dp, templateChanged); err != nil {
+			log.Error(err, "Failed to reconcile model version, will requeue after a short time.")
+			return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+		}
+		if needRestart {
+			templateChanged = true
+		}
+		// 已经被回滚的版本不再应用，直到Spec再次变化
+		revision := computeTemplateHash(&dp.Spec.Template)
+		rolledBack := app.Status.Rollback != nil && app.Status.Rollback.FailedRevision == revision
+		// keepStable表示本轮保持集群中原有的Pod模板
+		var keepStable bool
+		if templateChanged && rolledBack {
+			keepStable = true
+		} else if app.Status.Rollback != nil && app.Status.Rollback.FailedRevision != revision {
+			app.Status.Rollback = nil
+		}
+
+		// 发布策略：金丝雀或蓝绿发布时稳定版本保持原有的Pod模板，新版本先由单独的Deployment承载，晋升后再更新稳定版本
+		replicas := desiredReplicas(app)
+		if templateChanged && !keepStable && (isCanaryRollout(app) || isBlueGreenRollout(app)) {
+			var rollout rolloutResult
+			if isCanaryRollout(app) {
+				rollout, err = r.reconcileCanary(ctx, app, &dp.Spec.Template)
+			} else {
+				rollout, err = r.reconcileBlueGreen(ctx, app, &dp.Spec.Template)
+			}
+			if err != nil {
+				if isApplyConflict(err) {
+					return ctrl.Result{}, err
+				}
+				log.Error(err, "Failed to reconcile rollout, will requeue after a short time.")
+				return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+			}
+			keepStable = !rollout.promote
+			replicas = rollout.stableReplicas
+			result.RequeueAfter = rollout.requeueAfter
+		} else if result.RequeueAfter, err = r.finishRollout(ctx, app, dp); err != nil {
+			log.Error(err, "Failed to clean up rollout Deployment, will requeue after a short time.")
+			return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+		}
+		if keepStable {
+			dp.Spec.Template = stable.Spec.Template
+		}
+		// 启用自动扩缩容时副本数由HPA管理，不再覆盖
+		if isAutoscalingEnabled(app) {
+			replicas = *stable.Spec.Replicas
+		}
+		dp.Spec.Replicas = &replicas
+		templateUpdated := templateChanged && !keepStable
+		if templateUpdated {
+			setDeploymentRevision(dp, revision)
+		}
+
+		// 服务端应用只写入控制器负责的字段，与其他管理者冲突时交给调用方记录在Synced条件中
+		// 被手动修改的Pod模板字段强制收回所有权，恢复为期望值
+		correctDrift := templateUpdated && len(drift) > 0
+		applied := deploymentForApply(app, dp)
+		if err = r.applyOwned(ctx, app, applied, correctDrift); err != nil {
+			if isApplyConflict(err) {
+				return ctrl.Result{}, err
+			}
+			log.Error(err, "Failed to apply Deployment, will requeue, after a short time.")
+			return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+		}
+		// 服务端应用返回的是应用后的完整对象，其中的generation用于判断滚动是否完成
+		dp = applied
+		if correctDrift {
+			log.Info("LSTMPredictApp Deployment Update Success!", "fields", drift)
+			r.event(app, corev1.EventTypeNormal, ReasonDriftCorrected,
+				fmt.Sprintf("Deployment fields reconciled to the desired state: %s", strings.Join(drift, ", ")))
 		}
-		if !isEmptyResourceRequirements(app.Spec.ResourcesLimit) {
-			dp.Spec.Template.Spec.Containers[0].Resources = app.Spec.ResourcesLimit
+		if stable.Spec.Replicas != nil && *stable.Spec.Replicas != replicas {
+			r.event(app, corev1.EventTypeNormal, ReasonScaled,
+				fmt.Sprintf("Scaled Deployment %s from %d to %d replicas", dp.Name, *stable.Spec.Replicas, replicas))
 		}
-		if isChanged {
-			if err = r.Update(ctx, dp); err != nil {
-				log.Error(err, "Failed to Update Deployment, will requeue, after a short time.")
+
+		// 稳定版本全部就绪后记录为回滚的目标，本轮刚刚更新了Pod模板时还需要等待新版本就绪
+		// 同名的ControllerRevision不受该应用控制时不影响状态的更新，冲突在最后返回给调用方
+		var revisionConflict error
+		if !templateUpdated {
+			if err = r.recordGoodRevision(ctx, app, dp, replicas); isApplyConflict(err) {
+				revisionConflict = err
+			} else if err != nil {
+				log.Error(err, "Failed to record the known-good revision, will requeue after a short time.")
 				return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
 			}
-			log.Info("LSTMPredictApp Deployment Update Success!")
+		}
+
+		// 等待暂存新版本模型时按较短的间隔重新排队
+		if modelRequeueAfter > 0 && (result.RequeueAfter == 0 || modelRequeueAfter < result.RequeueAfter) {
+			result.RequeueAfter = modelRequeueAfter
 		}
 
 		// 状态更新
 		// 更新当前已经Ready的副本数量
 		app.Status.ReadyReplicas = dp.Status.ReadyReplicas
+		app.Status.Selector = podSelector(app)
 		// 如果副本数量达到了要求的数量，则CR的状态中Phase变为running，否则是Pending
-		if dp.Status.ReadyReplicas == *app.Spec.BackendAppReplicas {
+		if dp.Status.ReadyReplicas == replicas {
 			app.Status.Phase = "Running"
 		} else {
 			app.Status.Phase = "Pending"
 		}
+		// 根据Deployment自身的状态条件计算LSTMPredictApp的Available/Progressing/Degraded条件
+		setDeploymentConditions(app, dp, replicas)
+		setRolloutConditions(app, templateChanged)
+		if rolledBack {
+			setCondition(app, lstmappsv1.ConditionTypeDegraded, metav1.ConditionTrue, ReasonRolledBack, app.Status.Rollback.Message)
+		}
+		app.Status.ObservedGeneration = app.Generation
 		// 每次更新都会触发Reconcile，所以在这里更新最近一次更新时间
 		app.Status.LastUpdateTime = metav1.Now()
 
@@ -66,7 +205,7 @@ func (r *LSTMPredictAppReconciler) reconcileDeployment(ctx context.Context, app
 			return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
 		}
 		log.Info("The LSTMPredictApp status has been updated.")
-		return ctrl.Result{}, nil
+		return result, revisionConflict
 	}
 
 	// 如果不是NotFound的错误，即发生了其他错误，结束本轮调谐，一段时间后重试
@@ -81,53 +220,138 @@ func (r *LSTMPredictAppReconciler) reconcileDeployment(ctx context.Context, app
 	newDp.SetNamespace(app.Namespace)
 	newDp.SetLabels(app.Labels)
 
-	// 对app.Spec.BackendAppReplicas为空时赋默认值处理值
-
+	// 启用自动扩缩容时以最小副本数创建，之后由HPA调整
+	replicas := desiredReplicas(app)
 	newDp.Spec = appsv1.DeploymentSpec{
-		Replicas: app.Spec.BackendAppReplicas,
+		Replicas: &replicas,
 		Selector: &metav1.LabelSelector{
-			MatchLabels: map[string]string{"app": app.Name},
-		},
-		Template: corev1.PodTemplateSpec{
-			ObjectMeta: metav1.ObjectMeta{
-				Labels: map[string]string{"app": app.Name},
-			},
-			Spec: corev1.PodSpec{
-				Containers: []corev1.Container{
-					{
-						Name:  "lstm-predict-app",
-						Image: app.Spec.AppImage,
-						Ports: []corev1.ContainerPort{
-							{ContainerPort: app.Spec.ContainerPort},
-						},
-					},
-				},
-			},
+			MatchLabels: podLabels(app),
 		},
+		// 指定了模型来源时，模型Volume、Init容器与MODEL_PATH环境变量也在Pod模板中
+		Template: desiredPodTemplate(app, model),
 	}
 
-	// 对app.Spec.ResoucesLimit为空进行处理，如果为空，不对Pod的资源限制做出定义
-	if !isEmptyResourceRequirements(app.Spec.ResourcesLimit) {
-		newDp.Spec.Template.Spec.Containers[0].Resources = app.Spec.ResourcesLimit
+	if version := modelVersion(model); version != "" {
+		setModelAnnotations(&newDp.Spec.Template, modelAnnotations(model))
+		app.Status.Model = &lstmappsv1.ModelStatus{Version: version}
 	}
 
-	// 用于建立App里擦同与Deployment之间的父子关系：Kubernetes通过owner Reference实现级联删除，当LSTMPredictApp被删除时，Kubernetes
-	// 会自动删除它创建的Deployment; r.scheme用来识别资源类型的Scheme，确保类型正确
-	if err := ctrl.SetControllerReference(app, newDp, r.Scheme); err != nil {
-		log.Error(err, "Failed to SetControllerReference, will requeue after a short time.")
-		return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
-	}
+	setDeploymentRevision(newDp, computeTemplateHash(&newDp.Spec.Template))
 
-	// 在集群中创建Deployment：调用客户端的Create方法，将newDp提交到Kubernetes API Server
-	if err := r.Create(ctx, newDp); err != nil {
+	// 在集群中创建Deployment：以服务端应用的方式提交到Kubernetes API Server，
+	// applyOwned会建立App与Deployment之间的父子关系（owner Reference），当LSTMPredictApp被删除时，Kubernetes会自动删除它创建的Deployment
+	// 启用自动扩缩容时同样以最小副本数创建，之后的调谐中再交给HPA
+	applied := deploymentForApply(app, newDp)
+	applied.Spec.Replicas = &replicas
+	if err := r.applyOwned(ctx, app, applied, false); err != nil {
+		if isApplyConflict(err) {
+			return ctrl.Result{}, err
+		}
 		log.Error(err, "Failed to create Deployment, will requeue, after a short time.")
 		return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
 	}
 
 	log.Info("The Deployment has been created.")
+	r.event(app, corev1.EventTypeNormal, ReasonDeploymentCreated, fmt.Sprintf("Created Deployment %s with %d replicas", newDp.Name, replicas))
+
+	// Deployment刚刚创建，副本还没有就绪，应用处于Progressing状态
+	app.Status.Phase = "Pending"
+	app.Status.ReadyReplicas = 0
+	app.Status.Selector = podSelector(app)
+	setCondition(app, lstmappsv1.ConditionTypeAvailable, metav1.ConditionFalse, ReasonDeploymentCreated, "Deployment has been created, waiting for replicas to become ready")
+	setCondition(app, lstmappsv1.ConditionTypeProgressing, metav1.ConditionTrue, ReasonDeploymentCreated, "Deployment has been created")
+	setCondition(app, lstmappsv1.ConditionTypeDegraded, metav1.ConditionFalse, ReasonAsExpected, "")
+	app.Status.ObservedGeneration = app.Generation
+	app.Status.LastUpdateTime = metav1.Now()
+	if err := r.Status().Update(ctx, app); err != nil {
+		log.Error(err, "Failed to update LSTMPredictApp status.")
+		return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+	}
 	return ctrl.Result{}, nil
 }
 
+// setDeploymentConditions 根据Deployment的状态和状态条件，计算LSTMPredictApp的Available、Progressing和Degraded条件，
+// desired为Deployment应有的副本数（金丝雀发布时小于spec中的副本数）
+func setDeploymentConditions(app *lstmappsv1.LSTMPredictApp, dp *appsv1.Deployment, desired int32) {
+
+	var progressing, replicaFailure, available *appsv1.DeploymentCondition
+	for i := range dp.Status.Conditions {
+		cond := &dp.Status.Conditions[i]
+		switch cond.Type {
+		case appsv1.DeploymentProgressing:
+			progressing = cond
+		case appsv1.DeploymentReplicaFailure:
+			replicaFailure = cond
+		case appsv1.DeploymentAvailable:
+			available = cond
+		}
+	}
+
+	// Deployment控制器在超过progressDeadlineSeconds仍未完成滚动时，会把Progressing条件的Reason置为ProgressDeadlineExceeded
+	deadlineExceeded := progressing != nil && progressing.Status == corev1.ConditionFalse &&
+		progressing.Reason == ReasonProgressDeadlineExceeded
+	// Deployment控制器已经观察到最新的Spec，且新旧副本替换完毕
+	rolledOut := dp.Status.ObservedGeneration >= dp.Generation &&
+		dp.Status.UpdatedReplicas == desired &&
+		dp.Status.Replicas == desired &&
+		dp.Status.ReadyReplicas == desired
+
+	replicasMessage := fmt.Sprintf("%d/%d replicas are ready", dp.Status.ReadyReplicas, desired)
+	if dp.Status.ReadyReplicas >= desired && (available == nil || available.Status == corev1.ConditionTrue) {
+		setCondition(app, lstmappsv1.ConditionTypeAvailable, metav1.ConditionTrue, ReasonMinimumReplicasAvailable, replicasMessage)
+	} else {
+		setCondition(app, lstmappsv1.ConditionTypeAvailable, metav1.ConditionFalse, ReasonReplicasNotReady, replicasMessage)
+	}
+
+	switch {
+	case deadlineExceeded:
+		setCondition(app, lstmappsv1.ConditionTypeProgressing, metav1.ConditionFalse, ReasonProgressDeadlineExceeded, progressing.Message)
+	case !rolledOut:
+		setCondition(app, lstmappsv1.ConditionTypeProgressing, metav1.ConditionTrue, ReasonRollingUpdate,
+			fmt.Sprintf("%d/%d replicas have been updated", dp.Status.UpdatedReplicas, desired))
+	default:
+		setCondition(app, lstmappsv1.ConditionTypeProgressing, metav1.ConditionFalse, ReasonRolloutComplete, "Deployment has successfully progressed")
+	}
+
+	switch {
+	case deadlineExceeded:
+		setCondition(app, lstmappsv1.ConditionTypeDegraded, metav1.ConditionTrue, ReasonProgressDeadlineExceeded, progressing.Message)
+	case replicaFailure != nil && replicaFailure.Status == corev1.ConditionTrue:
+		setCondition(app, lstmappsv1.ConditionTypeDegraded, metav1.ConditionTrue, ReasonReplicaFailure, replicaFailure.Message)
+	default:
+		setCondition(app, lstmappsv1.ConditionTypeDegraded, metav1.ConditionFalse, ReasonAsExpected, "")
+	}
+}
+
+// setRolloutConditions 金丝雀或蓝绿发布进行中或者被中止时，覆盖由稳定版本Deployment计算出的Progressing和Degraded条件
+func setRolloutConditions(app *lstmappsv1.LSTMPredictApp, templateChanged bool) {
+	st := app.Status.Rollout
+	if st == nil || !templateChanged {
+		return
+	}
+	switch st.Phase {
+	case lstmappsv1.RolloutPhaseProgressing, lstmappsv1.RolloutPhasePromoting:
+		reason := ReasonCanaryRollout
+		if st.Strategy == lstmappsv1.RolloutStrategyBlueGreen {
+			reason = ReasonBlueGreenRollout
+		}
+		setCondition(app, lstmappsv1.ConditionTypeProgressing, metav1.ConditionTrue, reason, st.Message)
+	case lstmappsv1.RolloutPhaseAborted:
+		setCondition(app, lstmappsv1.ConditionTypeProgressing, metav1.ConditionFalse, ReasonRolloutAborted, st.Message)
+		setCondition(app, lstmappsv1.ConditionTypeDegraded, metav1.ConditionTrue, ReasonRolloutAborted, st.Message)
+	}
+}
+
+// podLabels Deployment用来选择预测服务Pod的标签
+func podLabels(app *lstmappsv1.LSTMPredictApp) map[string]string {
+	return map[string]string{"app": app.Name}
+}
+
+// podSelector 字符串形式的Pod选择器，记录在status.selector中供/scale子资源使用
+func podSelector(app *lstmappsv1.LSTMPredictApp) string {
+	return labels.SelectorFromSet(podLabels(app)).String()
+}
+
 func isEmptyResourceRequirements(r corev1.ResourceRequirements) bool {
 	return len(r.Limits) == 0 && len(r.Requests) == 0
 }
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// HTTPRouteGVK Gateway API的HTTPRoute，集群中不一定安装了Gateway API的CRD，因此以unstructured的方式读写
+var HTTPRouteGVK = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "HTTPRoute"}
+
+// errGatewayNotSpecified type为Gateway却没有指定挂载的Gateway，一般由Webhook拒绝，这里作为兜底
+var errGatewayNotSpecified = stderrors.New("spec.expose.gateway is required when spec.expose.type is Gateway")
+
+// exposePath 返回路由的路径前缀，为空时为"/"
+func exposePath(expose *lstmappsv1.ExposeSpec) string {
+	if expose.Path == "" {
+		return "/"
+	}
+	return expose.Path
+}
+
+// exposeURL 返回对集群外暴露的预测接口地址
+func exposeURL(expose *lstmappsv1.ExposeSpec) string {
+	scheme := "http"
+	if (expose.Type == lstmappsv1.ExposeTypeIngress && expose.TLSSecretName != "") ||
+		(expose.Type == lstmappsv1.ExposeTypeGateway && expose.Gateway != nil && expose.Gateway.TLS) {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, expose.Host, exposePath(expose))
+}
+
+// reconcileExpose 按spec.expose创建或更新与LSTMPredictApp同名的Ingress或HTTPRoute，并删除不再需要的路由对象。
+// 与其他字段管理者冲突时返回applyConflictError；集群中没有安装Gateway API时返回NoKindMatch错误；
+// 没有指定Gateway时返回errGatewayNotSpecified
+func (r *LSTMPredictAppReconciler) reconcileExpose(ctx context.Context, app *lstmappsv1.LSTMPredictApp) error {
+	expose := app.Spec.Expose
+	if expose == nil || expose.Type != lstmappsv1.ExposeTypeIngress {
+		if err := r.deleteOwned(ctx, app, &networkingv1.Ingress{}); err != nil {
+			return err
+		}
+	}
+	if expose == nil || expose.Type != lstmappsv1.ExposeTypeGateway {
+		route := &unstructured.Unstructured{}
+		route.SetGroupVersionKind(HTTPRouteGVK)
+		// 没有安装Gateway API时也就不存在需要删除的HTTPRoute
+		if err := r.deleteOwned(ctx, app, route); err != nil && !meta.IsNoMatchError(err) {
+			return err
+		}
+	}
+
+	switch {
+	case expose == nil:
+		return nil
+	case expose.Type == lstmappsv1.ExposeTypeGateway:
+		if expose.Gateway == nil {
+			return errGatewayNotSpecified
+		}
+		return r.applyOwned(ctx, app, httpRouteForApply(app), false)
+	default:
+		return r.applyOwned(ctx, app, ingressForApply(app), false)
+	}
+}
+
+// ingressForApply 构造Ingress中控制器负责的字段：标签、注解、IngressClass、TLS以及指向Service的规则
+func ingressForApply(app *lstmappsv1.LSTMPredictApp) *networkingv1.Ingress {
+	expose := app.Spec.Expose
+	pathType := networkingv1.PathTypePrefix
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        app.Name,
+			Namespace:   app.Namespace,
+			Labels:      app.Labels,
+			Annotations: expose.Annotations,
+		},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: expose.IngressClassName,
+			Rules: []networkingv1.IngressRule{{
+				Host: expose.Host,
+				IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{{
+							Path:     exposePath(expose),
+							PathType: &pathType,
+							Backend: networkingv1.IngressBackend{
+								Service: &networkingv1.IngressServiceBackend{
+									Name: app.Name,
+									Port: networkingv1.ServiceBackendPort{Number: primaryPort(app).ServicePort},
+								},
+							},
+						}},
+					},
+				},
+			}},
+		},
+	}
+	if expose.TLSSecretName != "" {
+		ingress.Spec.TLS = []networkingv1.IngressTLS{{Hosts: []string{expose.Host}, SecretName: expose.TLSSecretName}}
+	}
+	return ingress
+}
+
+// httpRouteForApply 构造HTTPRoute中控制器负责的字段：标签、注解、挂载的Gateway、域名以及指向Service的规则
+func httpRouteForApply(app *lstmappsv1.LSTMPredictApp) *unstructured.Unstructured {
+	expose := app.Spec.Expose
+	parentRef := map[string]any{"name": expose.Gateway.Name}
+	if expose.Gateway.Namespace != "" {
+		parentRef["namespace"] = expose.Gateway.Namespace
+	}
+	if expose.Gateway.SectionName != "" {
+		parentRef["sectionName"] = expose.Gateway.SectionName
+	}
+
+	route := &unstructured.Unstructured{Object: map[string]any{
+		"spec": map[string]any{
+			"parentRefs": []any{parentRef},
+			"hostnames":  []any{expose.Host},
+			"rules": []any{map[string]any{
+				"matches": []any{map[string]any{
+					"path": map[string]any{"type": "PathPrefix", "value": exposePath(expose)},
+				}},
+				"backendRefs": []any{map[string]any{
+					"name": app.Name,
+					"port": int64(primaryPort(app).ServicePort),
+				}},
+			}},
+		},
+	}}
+	route.SetGroupVersionKind(HTTPRouteGVK)
+	route.SetName(app.Name)
+	route.SetNamespace(app.Namespace)
+	route.SetLabels(app.Labels)
+	route.SetAnnotations(expose.Annotations)
+	return route
+}
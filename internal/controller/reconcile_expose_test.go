@@ -0,0 +1,136 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+var _ = Describe("LSTMPredictApp exposure", func() {
+	const resourceName = "expose-app"
+
+	ctx := context.Background()
+	typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+	var controllerReconciler *LSTMPredictAppReconciler
+
+	reconcileOnce := func() {
+		_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		controllerReconciler = &LSTMPredictAppReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		app := &lstmappsv1.LSTMPredictApp{
+			ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+			Spec: lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				ContainerPort:      8080,
+				BackendAppReplicas: ptr.To[int32](1),
+				ServicePort:        80,
+				ServiceType:        corev1.ServiceTypeClusterIP,
+				Expose: &lstmappsv1.ExposeSpec{
+					Type:          lstmappsv1.ExposeTypeIngress,
+					Host:          "predict.example.com",
+					Path:          "/lstm",
+					TLSSecretName: "predict-tls",
+					Annotations:   map[string]string{"nginx.ingress.kubernetes.io/proxy-body-size": "8m"},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, app)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		deleteLSTMPredictApp(ctx, typeNamespacedName)
+		meta := metav1.ObjectMeta{Name: resourceName, Namespace: "default"}
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &appsv1.Deployment{ObjectMeta: meta}))).To(Succeed())
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &corev1.Service{ObjectMeta: meta}))).To(Succeed())
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &networkingv1.Ingress{ObjectMeta: meta}))).To(Succeed())
+	})
+
+	It("should create an Ingress for the Service and report the external URL", func() {
+		reconcileOnce()
+
+		app := &lstmappsv1.LSTMPredictApp{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		Expect(app.Status.ServiceEndPoint).To(Equal("https://predict.example.com/lstm"))
+		Expect(meta.IsStatusConditionTrue(app.Status.Conditions, lstmappsv1.ConditionTypeServiceReady)).To(BeTrue())
+
+		ingress := &networkingv1.Ingress{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, ingress)).To(Succeed())
+		Expect(metav1.IsControlledBy(ingress, app)).To(BeTrue())
+		Expect(ingress.Annotations).To(HaveKeyWithValue("nginx.ingress.kubernetes.io/proxy-body-size", "8m"))
+		Expect(ingress.Spec.TLS).To(ConsistOf(networkingv1.IngressTLS{Hosts: []string{"predict.example.com"}, SecretName: "predict-tls"}))
+		path := ingress.Spec.Rules[0].HTTP.Paths[0]
+		Expect(path.Path).To(Equal("/lstm"))
+		Expect(path.Backend.Service.Name).To(Equal(resourceName))
+		Expect(path.Backend.Service.Port.Number).To(Equal(int32(80)))
+
+		By("removing spec.expose")
+		app.Spec.Expose = nil
+		Expect(k8sClient.Update(ctx, app)).To(Succeed())
+		reconcileOnce()
+
+		Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, ingress))).To(BeTrue())
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		Expect(app.Status.ServiceEndPoint).To(Equal("expose-app.default.svc.cluster.local:80"))
+	})
+
+	It("should build an HTTPRoute attached to the Gateway listener", func() {
+		app := &lstmappsv1.LSTMPredictApp{
+			ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+			Spec: lstmappsv1.LSTMPredictAppSpec{
+				ServicePort: 80,
+				Expose: &lstmappsv1.ExposeSpec{
+					Type:    lstmappsv1.ExposeTypeGateway,
+					Host:    "predict.example.com",
+					Gateway: &lstmappsv1.GatewayReference{Name: "public", Namespace: "gateways", SectionName: "https", TLS: true},
+				},
+			},
+		}
+		route := httpRouteForApply(app)
+		Expect(route.GroupVersionKind()).To(Equal(HTTPRouteGVK))
+
+		parentRefs, _, _ := unstructured.NestedSlice(route.Object, "spec", "parentRefs")
+		Expect(parentRefs).To(ConsistOf(map[string]any{"name": "public", "namespace": "gateways", "sectionName": "https"}))
+		hostnames, _, _ := unstructured.NestedStringSlice(route.Object, "spec", "hostnames")
+		Expect(hostnames).To(Equal([]string{"predict.example.com"}))
+		rules, _, _ := unstructured.NestedSlice(route.Object, "spec", "rules")
+		Expect(rules).To(HaveLen(1))
+		backendRefs, _, _ := unstructured.NestedSlice(rules[0].(map[string]any), "backendRefs")
+		Expect(backendRefs).To(ConsistOf(map[string]any{"name": resourceName, "port": int64(80)}))
+
+		Expect(exposeURL(app.Spec.Expose)).To(Equal("https://predict.example.com/"))
+	})
+})
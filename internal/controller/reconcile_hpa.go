@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// isAutoscalingEnabled 判断LSTMPredictApp的副本数是否由HPA管理
+func isAutoscalingEnabled(app *lstmappsv1.LSTMPredictApp) bool {
+	return app.Spec.Autoscaling != nil
+}
+
+// minReplicas 返回自动扩缩容的最小副本数，未设置时为1
+func minReplicas(autoscaling *lstmappsv1.AutoscalingSpec) int32 {
+	if autoscaling.MinReplicas != nil {
+		return *autoscaling.MinReplicas
+	}
+	return 1
+}
+
+// desiredReplicas 返回应用期望的总副本数：启用自动扩缩容时取HPA计算出的副本数，否则取BackendAppReplicas
+func desiredReplicas(app *lstmappsv1.LSTMPredictApp) int32 {
+	if !isAutoscalingEnabled(app) {
+		return *app.Spec.BackendAppReplicas
+	}
+	if st := app.Status.Autoscaling; st != nil && st.DesiredReplicas > 0 {
+		return st.DesiredReplicas
+	}
+	return minReplicas(app.Spec.Autoscaling)
+}
+
+// desiredHPASpec 根据spec.autoscaling构造以同名Deployment为目标的HPA
+func desiredHPASpec(app *lstmappsv1.LSTMPredictApp) autoscalingv2.HorizontalPodAutoscalerSpec {
+	autoscaling := app.Spec.Autoscaling
+	min := minReplicas(autoscaling)
+	spec := autoscalingv2.HorizontalPodAutoscalerSpec{
+		ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Name:       app.Name,
+		},
+		MinReplicas: &min,
+		MaxReplicas: autoscaling.MaxReplicas,
+	}
+	resourceMetric := func(name corev1.ResourceName, utilization int32) autoscalingv2.MetricSpec {
+		return autoscalingv2.MetricSpec{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name: name,
+				Target: autoscalingv2.MetricTarget{
+					Type:               autoscalingv2.UtilizationMetricType,
+					AverageUtilization: &utilization,
+				},
+			},
+		}
+	}
+	if autoscaling.TargetCPUUtilizationPercentage != nil {
+		spec.Metrics = append(spec.Metrics, resourceMetric(corev1.ResourceCPU, *autoscaling.TargetCPUUtilizationPercentage))
+	}
+	if autoscaling.TargetMemoryUtilizationPercentage != nil {
+		spec.Metrics = append(spec.Metrics, resourceMetric(corev1.ResourceMemory, *autoscaling.TargetMemoryUtilizationPercentage))
+	}
+	for _, metric := range autoscaling.CustomMetrics {
+		value := metric.TargetAverageValue.DeepCopy()
+		spec.Metrics = append(spec.Metrics, autoscalingv2.MetricSpec{
+			Type: autoscalingv2.PodsMetricSourceType,
+			Pods: &autoscalingv2.PodsMetricSource{
+				Metric: autoscalingv2.MetricIdentifier{Name: metric.Name},
+				Target: autoscalingv2.MetricTarget{
+					Type:         autoscalingv2.AverageValueMetricType,
+					AverageValue: &value,
+				},
+			},
+		})
+	}
+	return spec
+}
+
+// hpaForApply 构造HPA中控制器负责的字段：标签以及根据spec.autoscaling计算的Spec
+func hpaForApply(app *lstmappsv1.LSTMPredictApp) *autoscalingv2.HorizontalPodAutoscaler {
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      app.Name,
+			Namespace: app.Namespace,
+			Labels:    app.Labels,
+		},
+		Spec: desiredHPASpec(app),
+	}
+}
+
+// reconcileHPA 启用自动扩缩容时创建或更新同名的HPA并同步其副本数，关闭时删除该应用创建的HPA
+func (r *LSTMPredictAppReconciler) reconcileHPA(ctx context.Context, app *lstmappsv1.LSTMPredictApp) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: app.Namespace, Name: app.Name}, hpa)
+	if err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "Failed to get HorizontalPodAutoscaler, will requeue after a short time.")
+		return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+	}
+	exists := err == nil
+	// 同名的HPA不受该应用控制时（如启用自动扩缩容之前用户自己创建的HPA），不会修改或删除它
+	controlled := exists && metav1.IsControlledBy(hpa, app)
+
+	// 关闭了自动扩缩容，删除HPA，副本数重新由BackendAppReplicas决定
+	if !isAutoscalingEnabled(app) {
+		if controlled {
+			if err := r.Delete(ctx, hpa); err != nil && !errors.IsNotFound(err) {
+				log.Error(err, "Failed to delete HorizontalPodAutoscaler, will requeue after a short time.")
+				return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+			}
+			log.Info("The HorizontalPodAutoscaler has been deleted.")
+			r.event(app, corev1.EventTypeNormal, ReasonAutoscalerDeleted, fmt.Sprintf("Deleted HorizontalPodAutoscaler %s, replicas are managed by spec.backendAppReplicas again", hpa.Name))
+		}
+		if app.Status.Autoscaling != nil {
+			app.Status.Autoscaling = nil
+			if err := r.Status().Update(ctx, app); err != nil {
+				log.Error(err, "Failed to update LSTMPredictApp status.")
+				return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if exists && !controlled {
+		return ctrl.Result{}, notControlledError(app, "HorizontalPodAutoscaler", app.Name)
+	}
+
+	// 以服务端应用的方式创建或更新HPA，只写入控制器负责的字段，与其他管理者冲突时交给调用方记录在Synced条件中
+	applied := hpaForApply(app)
+	if err := r.applyOwned(ctx, app, applied, false); err != nil {
+		if isApplyConflict(err) {
+			return ctrl.Result{}, err
+		}
+		log.Error(err, "Failed to apply HorizontalPodAutoscaler, will requeue, after a short time.")
+		return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+	}
+	if !exists {
+		log.Info("The HorizontalPodAutoscaler has been created.")
+		r.event(app, corev1.EventTypeNormal, ReasonAutoscalerCreated,
+			fmt.Sprintf("Created HorizontalPodAutoscaler %s with %d to %d replicas", applied.Name, *applied.Spec.MinReplicas, applied.Spec.MaxReplicas))
+	}
+	// 服务端应用返回的是应用后的完整对象，包含HPA的状态
+	hpa = applied
+
+	// 将HPA的副本数同步到LSTMPredictApp的状态中
+	status := &lstmappsv1.AutoscalingStatus{
+		CurrentReplicas: hpa.Status.CurrentReplicas,
+		DesiredReplicas: hpa.Status.DesiredReplicas,
+		LastScaleTime:   hpa.Status.LastScaleTime,
+	}
+	if !equality.Semantic.DeepEqual(status, app.Status.Autoscaling) {
+		if previous := app.Status.Autoscaling; previous != nil && previous.DesiredReplicas != status.DesiredReplicas && status.DesiredReplicas > 0 {
+			r.event(app, corev1.EventTypeNormal, ReasonScaled,
+				fmt.Sprintf("HorizontalPodAutoscaler scaled the app from %d to %d replicas", previous.DesiredReplicas, status.DesiredReplicas))
+		}
+		app.Status.Autoscaling = status
+		if err := r.Status().Update(ctx, app); err != nil {
+			log.Error(err, "Failed to update LSTMPredictApp status.")
+			return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+		}
+	}
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+// conflictingHPAClient 模拟其他字段管理者修改了HPA中控制器负责的字段，服务端应用HPA时返回冲突
+type conflictingHPAClient struct {
+	client.Client
+}
+
+func (c *conflictingHPAClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if _, ok := obj.(*autoscalingv2.HorizontalPodAutoscaler); ok && patch.Type() == types.ApplyPatchType {
+		return apierrors.NewConflict(schema.GroupResource{Group: "autoscaling", Resource: "horizontalpodautoscalers"}, obj.GetName(),
+			fmt.Errorf(`Apply failed with 1 conflict: conflict with "kubectl-edit" using autoscaling/v2: .spec.maxReplicas`))
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+var _ = Describe("HorizontalPodAutoscaler management", func() {
+	const resourceName = "autoscaling-app"
+
+	ctx := context.Background()
+	typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+	var controllerReconciler *LSTMPredictAppReconciler
+
+	reconcileOnce := func() {
+		_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		controllerReconciler = &LSTMPredictAppReconciler{
+			Client: k8sClient,
+			Scheme: k8sClient.Scheme(),
+		}
+		app := &lstmappsv1.LSTMPredictApp{
+			ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+			Spec: lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				ContainerPort:      8080,
+				BackendAppReplicas: ptr.To[int32](1),
+				ServicePort:        80,
+				ServiceType:        corev1.ServiceTypeClusterIP,
+				Autoscaling: &lstmappsv1.AutoscalingSpec{
+					MinReplicas:                    ptr.To[int32](2),
+					MaxReplicas:                    20,
+					TargetCPUUtilizationPercentage: ptr.To[int32](70),
+					CustomMetrics: []lstmappsv1.CustomMetricTarget{
+						{Name: "http_requests_per_second", TargetAverageValue: resource.MustParse("100")},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, app)).To(Succeed())
+		reconcileOnce()
+	})
+
+	AfterEach(func() {
+		deleteLSTMPredictApp(ctx, typeNamespacedName)
+		// envtest中没有垃圾回收，手动删除子资源
+		meta := metav1.ObjectMeta{Name: resourceName, Namespace: "default"}
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &appsv1.Deployment{ObjectMeta: meta}))).To(Succeed())
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &corev1.Service{ObjectMeta: meta}))).To(Succeed())
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &autoscalingv2.HorizontalPodAutoscaler{ObjectMeta: meta}))).To(Succeed())
+	})
+
+	It("should create an HPA targeting the Deployment", func() {
+		hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, hpa)).To(Succeed())
+		Expect(hpa.Spec.ScaleTargetRef.Name).To(Equal(resourceName))
+		Expect(*hpa.Spec.MinReplicas).To(Equal(int32(2)))
+		Expect(hpa.Spec.MaxReplicas).To(Equal(int32(20)))
+		Expect(hpa.Spec.Metrics).To(HaveLen(2))
+
+		dp := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, dp)).To(Succeed())
+		Expect(*dp.Spec.Replicas).To(Equal(int32(2)))
+	})
+
+	It("should release the Deployment replicas to the HPA", func() {
+		reconcileOnce()
+		dp := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, dp)).To(Succeed())
+		Expect(ownsField(dp, FieldManager, "f:spec", "f:replicas")).To(BeFalse())
+		Expect(*dp.Spec.Replicas).To(Equal(int32(2)))
+		Expect(deploymentForApply(&lstmappsv1.LSTMPredictApp{Spec: lstmappsv1.LSTMPredictAppSpec{
+			Autoscaling: &lstmappsv1.AutoscalingSpec{MaxReplicas: 20},
+		}}, dp).Spec.Replicas).To(BeNil())
+	})
+
+	It("should leave the replicas chosen by the HPA alone", func() {
+		dp := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, dp)).To(Succeed())
+		dp.Spec.Replicas = ptr.To[int32](7)
+		Expect(k8sClient.Update(ctx, dp)).To(Succeed())
+
+		hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, hpa)).To(Succeed())
+		hpa.Status = autoscalingv2.HorizontalPodAutoscalerStatus{CurrentReplicas: 2, DesiredReplicas: 7}
+		Expect(k8sClient.Status().Update(ctx, hpa)).To(Succeed())
+
+		reconcileOnce()
+		Expect(k8sClient.Get(ctx, typeNamespacedName, dp)).To(Succeed())
+		Expect(*dp.Spec.Replicas).To(Equal(int32(7)))
+
+		app := &lstmappsv1.LSTMPredictApp{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		Expect(app.Status.Autoscaling).NotTo(BeNil())
+		Expect(app.Status.Autoscaling.DesiredReplicas).To(Equal(int32(7)))
+	})
+
+	It("should neither update nor delete an HPA it does not control", func() {
+		By("replacing the HPA with one created by the user")
+		hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, hpa)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, hpa)).To(Succeed())
+		Expect(k8sClient.Create(ctx, &autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: resourceName},
+				MinReplicas:    ptr.To[int32](3),
+				MaxReplicas:    5,
+			},
+		})).To(Succeed())
+		reconcileOnce()
+
+		hpa = &autoscalingv2.HorizontalPodAutoscaler{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, hpa)).To(Succeed())
+		Expect(hpa.Spec.MaxReplicas).To(Equal(int32(5)))
+		app := &lstmappsv1.LSTMPredictApp{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		condition := apimeta.FindStatusCondition(app.Status.Conditions, lstmappsv1.ConditionTypeSynced)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Message).To(ContainSubstring("not controlled by LSTMPredictApp"))
+
+		By("turning autoscaling off")
+		app.Spec.Autoscaling = nil
+		Expect(k8sClient.Update(ctx, app)).To(Succeed())
+		reconcileOnce()
+		Expect(k8sClient.Get(ctx, typeNamespacedName, &autoscalingv2.HorizontalPodAutoscaler{})).To(Succeed())
+	})
+
+	It("should surface a conflict on the HPA fields instead of overwriting them", func() {
+		controllerReconciler.Client = &conflictingHPAClient{Client: k8sClient}
+		reconcileOnce()
+
+		app := &lstmappsv1.LSTMPredictApp{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		condition := apimeta.FindStatusCondition(app.Status.Conditions, lstmappsv1.ConditionTypeSynced)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Message).To(ContainSubstring("HorizontalPodAutoscaler"))
+		Expect(condition.Message).To(ContainSubstring("kubectl-edit"))
+	})
+})
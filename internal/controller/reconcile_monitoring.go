@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"context"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ServiceMonitorGVK Prometheus Operator的ServiceMonitor，集群中不一定安装了其CRD，因此以unstructured的方式读写
+var ServiceMonitorGVK = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "ServiceMonitor"}
+
+const (
+	// MetricsPortName 未指定spec.monitoring.port时优先使用的端口名
+	MetricsPortName = "metrics"
+
+	// 以下默认值一般由Webhook注入，这里作为兜底
+	DefaultMetricsPath = "/metrics"
+)
+
+// reconcileMonitoring 开启spec.monitoring时维护与LSTMPredictApp同名的ServiceMonitor，关闭后删除，结果记录在Monitored条件中。
+// 集群中没有安装Prometheus Operator时跳过，不重新排队；与其他字段管理者冲突时返回applyConflictError
+func (r *LSTMPredictAppReconciler) reconcileMonitoring(ctx context.Context, app *lstmappsv1.LSTMPredictApp) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	var changed bool
+	var applyErr error
+	if monitoring := app.Spec.Monitoring; monitoring == nil || !monitoring.Enabled {
+		monitor := &unstructured.Unstructured{}
+		monitor.SetGroupVersionKind(ServiceMonitorGVK)
+		if err := r.deleteOwned(ctx, app, monitor); err != nil && !meta.IsNoMatchError(err) {
+			log.Error(err, "Failed to delete ServiceMonitor, will requeue after a short time.")
+			return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+		}
+		changed = meta.RemoveStatusCondition(&app.Status.Conditions, lstmappsv1.ConditionTypeMonitored)
+	} else {
+		applyErr = r.applyOwned(ctx, app, serviceMonitorForApply(app), false)
+		switch {
+		case applyErr == nil:
+			changed = setCondition(app, lstmappsv1.ConditionTypeMonitored, metav1.ConditionTrue, ReasonMonitorApplied, "ServiceMonitor has been applied")
+		case meta.IsNoMatchError(applyErr):
+			// 安装Prometheus Operator之后修改Spec或者重启控制器即可恢复
+			changed = setCondition(app, lstmappsv1.ConditionTypeMonitored, metav1.ConditionFalse, ReasonMonitorCRDNotInstalled,
+				"ServiceMonitor CRD is not installed, install the Prometheus Operator to scrape the prediction server")
+			if changed {
+				r.event(app, corev1.EventTypeWarning, ReasonMonitorCRDNotInstalled, "ServiceMonitor CRD is not installed, skip creating the ServiceMonitor")
+			}
+			applyErr = nil
+		case isApplyConflict(applyErr):
+		default:
+			log.Error(applyErr, "Failed to apply ServiceMonitor, will requeue after a short time.")
+			return ctrl.Result{RequeueAfter: GenericRequeueDuration}, applyErr
+		}
+	}
+
+	if changed {
+		if err := r.Status().Update(ctx, app); err != nil {
+			log.Error(err, "Failed to update LSTMPredictApp status.")
+			return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+		}
+	}
+	return ctrl.Result{}, applyErr
+}
+
+// serviceMonitorForApply 构造ServiceMonitor中控制器负责的字段：标签、选中应用Service的选择器以及指标端点
+func serviceMonitorForApply(app *lstmappsv1.LSTMPredictApp) *unstructured.Unstructured {
+	monitoring := app.Spec.Monitoring
+	path := monitoring.Path
+	if path == "" {
+		path = DefaultMetricsPath
+	}
+	endpoint := map[string]any{"path": path}
+	// 未命名的端口（ContainerPort简写）只能通过容器端口号指定
+	port := metricsPort(app)
+	if port.Name != "" {
+		endpoint["port"] = port.Name
+	} else {
+		endpoint["targetPort"] = int64(port.ContainerPort)
+	}
+	if monitoring.Interval != nil {
+		endpoint["interval"] = monitoring.Interval.Duration.String()
+	}
+
+	monitor := &unstructured.Unstructured{Object: map[string]any{
+		"spec": map[string]any{
+			"selector": map[string]any{
+				"matchLabels": map[string]any{AppNameLabel: app.Name},
+			},
+			"namespaceSelector": map[string]any{
+				"matchNames": []any{app.Namespace},
+			},
+			"endpoints": []any{endpoint},
+		},
+	}}
+	monitor.SetGroupVersionKind(ServiceMonitorGVK)
+	monitor.SetName(app.Name)
+	monitor.SetNamespace(app.Namespace)
+	labels := map[string]string{}
+	for k, v := range app.Labels {
+		labels[k] = v
+	}
+	for k, v := range monitoring.Labels {
+		labels[k] = v
+	}
+	monitor.SetLabels(labels)
+	return monitor
+}
+
+// metricsPort 返回暴露指标的端口：spec.monitoring.port指定的端口，否则为名为metrics的端口，都没有时为主端口
+func metricsPort(app *lstmappsv1.LSTMPredictApp) lstmappsv1.PortSpec {
+	ports := appPorts(app)
+	name := app.Spec.Monitoring.Port
+	if name == "" {
+		name = MetricsPortName
+	}
+	if port := findByKey(ports, name, func(p lstmappsv1.PortSpec) string { return p.Name }); port != nil {
+		return *port
+	}
+	return ports[0]
+}
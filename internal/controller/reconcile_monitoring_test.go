@@ -0,0 +1,149 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+// noServiceMonitorClient 模拟集群中没有安装Prometheus Operator，读写ServiceMonitor时返回NoKindMatchError
+type noServiceMonitorClient struct {
+	client.Client
+}
+
+func (c *noServiceMonitorClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if obj.GetObjectKind().GroupVersionKind() == ServiceMonitorGVK {
+		return &meta.NoKindMatchError{GroupKind: ServiceMonitorGVK.GroupKind(), SearchedVersions: []string{ServiceMonitorGVK.Version}}
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func (c *noServiceMonitorClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if obj.GetObjectKind().GroupVersionKind() == ServiceMonitorGVK {
+		return &meta.NoKindMatchError{GroupKind: ServiceMonitorGVK.GroupKind(), SearchedVersions: []string{ServiceMonitorGVK.Version}}
+	}
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+var _ = Describe("LSTMPredictApp monitoring", func() {
+	const resourceName = "monitoring-app"
+
+	ctx := context.Background()
+	typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+	newApp := func() *lstmappsv1.LSTMPredictApp {
+		return &lstmappsv1.LSTMPredictApp{
+			ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default", Labels: map[string]string{"team": "forecast"}},
+			Spec: lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				BackendAppReplicas: ptr.To[int32](1),
+				ServiceType:        corev1.ServiceTypeClusterIP,
+				Ports: []lstmappsv1.PortSpec{
+					{Name: "http", ContainerPort: 8080, ServicePort: 80},
+					{Name: "metrics", ContainerPort: 9090},
+				},
+				Monitoring: &lstmappsv1.MonitoringSpec{
+					Enabled:  true,
+					Path:     "/stats",
+					Interval: &metav1.Duration{Duration: 30 * time.Second},
+					Labels:   map[string]string{"release": "prometheus"},
+				},
+			},
+		}
+	}
+
+	It("should scrape the metrics port of the app Service", func() {
+		monitor := serviceMonitorForApply(newApp())
+		Expect(monitor.GroupVersionKind()).To(Equal(ServiceMonitorGVK))
+		Expect(monitor.GetLabels()).To(Equal(map[string]string{"team": "forecast", "release": "prometheus"}))
+
+		matchLabels, _, _ := unstructured.NestedStringMap(monitor.Object, "spec", "selector", "matchLabels")
+		Expect(matchLabels).To(Equal(map[string]string{AppNameLabel: resourceName}))
+		endpoints, _, _ := unstructured.NestedSlice(monitor.Object, "spec", "endpoints")
+		Expect(endpoints).To(Equal([]any{map[string]any{"port": "metrics", "path": "/stats", "interval": "30s"}}))
+	})
+
+	It("should fall back to the container port when the app only uses the shorthand port", func() {
+		app := newApp()
+		app.Spec.Ports = nil
+		app.Spec.ContainerPort = 8080
+		app.Spec.ServicePort = 80
+		app.Spec.Monitoring = &lstmappsv1.MonitoringSpec{Enabled: true}
+
+		endpoints, _, _ := unstructured.NestedSlice(serviceMonitorForApply(app).Object, "spec", "endpoints")
+		Expect(endpoints).To(Equal([]any{map[string]any{"targetPort": int64(8080), "path": DefaultMetricsPath}}))
+	})
+
+	Context("When the ServiceMonitor CRD is not installed", func() {
+		BeforeEach(func() {
+			Expect(k8sClient.Create(ctx, newApp())).To(Succeed())
+		})
+
+		AfterEach(func() {
+			deleteLSTMPredictApp(ctx, typeNamespacedName)
+			meta := metav1.ObjectMeta{Name: resourceName, Namespace: "default"}
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &appsv1.Deployment{ObjectMeta: meta}))).To(Succeed())
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &corev1.Service{ObjectMeta: meta}))).To(Succeed())
+		})
+
+		It("should skip the ServiceMonitor and report it in the Monitored condition", func() {
+			controllerReconciler := &LSTMPredictAppReconciler{
+				Client: &noServiceMonitorClient{Client: k8sClient},
+				Scheme: k8sClient.Scheme(),
+			}
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeZero())
+
+			app := &lstmappsv1.LSTMPredictApp{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+			condition := meta.FindStatusCondition(app.Status.Conditions, lstmappsv1.ConditionTypeMonitored)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(ReasonMonitorCRDNotInstalled))
+
+			By("labeling the Service so that a ServiceMonitor can select it")
+			svc := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, svc)).To(Succeed())
+			Expect(svc.Labels).To(HaveKeyWithValue(AppNameLabel, resourceName))
+			Expect(svc.Labels).To(HaveKeyWithValue("team", "forecast"))
+
+			By("disabling monitoring")
+			app.Spec.Monitoring.Enabled = false
+			Expect(k8sClient.Update(ctx, app)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+			Expect(meta.FindStatusCondition(app.Status.Conditions, lstmappsv1.ConditionTypeMonitored)).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// reconcileNetworkPolicy 设置了spec.networkPolicy时维护与LSTMPredictApp同名的NetworkPolicy，去掉后删除。
+// 与其他字段管理者冲突时返回applyConflictError
+func (r *LSTMPredictAppReconciler) reconcileNetworkPolicy(ctx context.Context, app *lstmappsv1.LSTMPredictApp) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	if app.Spec.NetworkPolicy == nil {
+		policy := &networkingv1.NetworkPolicy{}
+		err := r.Get(ctx, types.NamespacedName{Namespace: app.Namespace, Name: app.Name}, policy)
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		if err != nil {
+			log.Error(err, "Failed to get NetworkPolicy, will requeue after a short time.")
+			return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+		}
+		if !metav1.IsControlledBy(policy, app) {
+			return ctrl.Result{}, nil
+		}
+		if err := r.Delete(ctx, policy); err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "Failed to delete NetworkPolicy, will requeue after a short time.")
+			return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+		}
+		log.Info("The NetworkPolicy has been deleted.")
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.applyOwned(ctx, app, networkPolicyForApply(app), false); err != nil {
+		if isApplyConflict(err) {
+			return ctrl.Result{}, err
+		}
+		log.Error(err, "Failed to apply NetworkPolicy, will requeue, after a short time.")
+		return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// networkPolicyForApply 构造NetworkPolicy中控制器负责的字段：选中应用Pod的选择器，只允许spec.networkPolicy.from访问全部端口的入站规则，
+// 以及开启监控时允许访问指标端口的入站规则。选择器使用所有版本的Pod都带有的AppNameLabel，覆盖稳定版本、金丝雀与蓝绿发布的绿色版本
+func networkPolicyForApply(app *lstmappsv1.LSTMPredictApp) *networkingv1.NetworkPolicy {
+	var ports []networkingv1.NetworkPolicyPort
+	for _, port := range appPorts(app) {
+		ports = append(ports, networkPolicyPort(port))
+	}
+	ingress := []networkingv1.NetworkPolicyIngressRule{{
+		Ports: ports,
+		From:  networkPolicyPeers(app.Spec.NetworkPolicy.From),
+	}}
+	// 指标使用单独的端口时总是允许抓取，使用主端口时只有列出了metricsFrom才额外放开
+	if app.Spec.Monitoring != nil && app.Spec.Monitoring.Enabled {
+		port := metricsPort(app)
+		if len(app.Spec.NetworkPolicy.MetricsFrom) > 0 || port.Name != appPorts(app)[0].Name {
+			ingress = append(ingress, networkingv1.NetworkPolicyIngressRule{
+				Ports: []networkingv1.NetworkPolicyPort{networkPolicyPort(port)},
+				From:  networkPolicyPeers(app.Spec.NetworkPolicy.MetricsFrom),
+			})
+		}
+	}
+
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      app.Name,
+			Namespace: app.Namespace,
+			Labels:    app.Labels,
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{AppNameLabel: app.Name}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress:     ingress,
+		},
+	}
+}
+
+func networkPolicyPort(port lstmappsv1.PortSpec) networkingv1.NetworkPolicyPort {
+	protocol := port.Protocol
+	number := intstr.FromInt32(port.ContainerPort)
+	return networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &number}
+}
+
+// networkPolicyPeers 将spec.networkPolicy中的来源转换为NetworkPolicy的来源，namespace是namespaceSelector的简写
+func networkPolicyPeers(from []lstmappsv1.NetworkPolicyPeer) []networkingv1.NetworkPolicyPeer {
+	var peers []networkingv1.NetworkPolicyPeer
+	for _, f := range from {
+		peer := networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: f.NamespaceSelector,
+			PodSelector:       f.PodSelector,
+		}
+		if f.Namespace != "" {
+			peer.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{corev1.LabelMetadataName: f.Namespace}}
+		}
+		peers = append(peers, peer)
+	}
+	return peers
+}
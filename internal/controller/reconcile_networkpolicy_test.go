@@ -0,0 +1,129 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+var _ = Describe("LSTMPredictApp NetworkPolicy", func() {
+	const resourceName = "netpol-app"
+
+	ctx := context.Background()
+	typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+	var controllerReconciler *LSTMPredictAppReconciler
+
+	reconcileOnce := func() {
+		_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		controllerReconciler = &LSTMPredictAppReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		app := &lstmappsv1.LSTMPredictApp{
+			ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+			Spec: lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				BackendAppReplicas: ptr.To[int32](1),
+				ServiceType:        corev1.ServiceTypeClusterIP,
+				Ports: []lstmappsv1.PortSpec{
+					{Name: "http", ContainerPort: 8080, ServicePort: 80},
+					{Name: "grpc", ContainerPort: 9090},
+				},
+				NetworkPolicy: &lstmappsv1.NetworkPolicySpec{From: []lstmappsv1.NetworkPolicyPeer{
+					{Namespace: "forecasting"},
+				}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, app)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		deleteLSTMPredictApp(ctx, typeNamespacedName)
+		meta := metav1.ObjectMeta{Name: resourceName, Namespace: "default"}
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &appsv1.Deployment{ObjectMeta: meta}))).To(Succeed())
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &corev1.Service{ObjectMeta: meta}))).To(Succeed())
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &networkingv1.NetworkPolicy{ObjectMeta: meta}))).To(Succeed())
+	})
+
+	It("should only admit the allow-listed peers to the app ports and follow allow-list changes", func() {
+		reconcileOnce()
+
+		app := &lstmappsv1.LSTMPredictApp{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		policy := &networkingv1.NetworkPolicy{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, policy)).To(Succeed())
+		Expect(metav1.IsControlledBy(policy, app)).To(BeTrue())
+		Expect(policy.Spec.PodSelector.MatchLabels).To(Equal(map[string]string{AppNameLabel: resourceName}))
+		dp := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, dp)).To(Succeed())
+		Expect(dp.Spec.Template.Labels).To(HaveKeyWithValue(AppNameLabel, resourceName))
+		Expect(policy.Spec.PolicyTypes).To(Equal([]networkingv1.PolicyType{networkingv1.PolicyTypeIngress}))
+		Expect(policy.Spec.Ingress).To(HaveLen(1))
+		rule := policy.Spec.Ingress[0]
+		Expect(rule.Ports).To(HaveLen(2))
+		Expect(rule.Ports[0].Port.IntValue()).To(Equal(8080))
+		Expect(rule.Ports[1].Port.IntValue()).To(Equal(9090))
+		Expect(rule.From).To(HaveLen(1))
+		Expect(rule.From[0].NamespaceSelector.MatchLabels).To(Equal(map[string]string{corev1.LabelMetadataName: "forecasting"}))
+
+		By("adding a pod selector to the allow-list")
+		app.Spec.NetworkPolicy.From = append(app.Spec.NetworkPolicy.From, lstmappsv1.NetworkPolicyPeer{
+			PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"role": "scheduler"}},
+		})
+		Expect(k8sClient.Update(ctx, app)).To(Succeed())
+		reconcileOnce()
+		Expect(k8sClient.Get(ctx, typeNamespacedName, policy)).To(Succeed())
+		Expect(policy.Spec.Ingress[0].From).To(HaveLen(2))
+		Expect(policy.Spec.Ingress[0].From[1].PodSelector.MatchLabels).To(Equal(map[string]string{"role": "scheduler"}))
+
+		By("admitting the scrapers listed in metricsFrom to the metrics port")
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		app.Spec.Ports = append(app.Spec.Ports, lstmappsv1.PortSpec{Name: MetricsPortName, ContainerPort: 9100})
+		app.Spec.Monitoring = &lstmappsv1.MonitoringSpec{Enabled: true}
+		app.Spec.NetworkPolicy.MetricsFrom = []lstmappsv1.NetworkPolicyPeer{{Namespace: "monitoring"}}
+		Expect(k8sClient.Update(ctx, app)).To(Succeed())
+		Expect(controllerReconciler.reconcileNetworkPolicy(ctx, app)).To(Equal(reconcile.Result{}))
+		Expect(k8sClient.Get(ctx, typeNamespacedName, policy)).To(Succeed())
+		Expect(policy.Spec.Ingress).To(HaveLen(2))
+		Expect(policy.Spec.Ingress[1].Ports).To(HaveLen(1))
+		Expect(policy.Spec.Ingress[1].Ports[0].Port.IntValue()).To(Equal(9100))
+		Expect(policy.Spec.Ingress[1].From[0].NamespaceSelector.MatchLabels).To(Equal(map[string]string{corev1.LabelMetadataName: "monitoring"}))
+
+		By("removing spec.networkPolicy")
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		app.Spec.NetworkPolicy = nil
+		Expect(k8sClient.Update(ctx, app)).To(Succeed())
+		reconcileOnce()
+		Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, policy))).To(BeTrue())
+	})
+})
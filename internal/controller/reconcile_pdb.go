@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"context"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// 以下默认值一般由Webhook注入，这里作为兜底
+var DefaultMaxUnavailable = intstr.FromString("25%")
+
+// reconcilePDB 副本数大于1时维护与LSTMPredictApp同名的PodDisruptionBudget，副本数降到1时删除。
+// 与其他字段管理者冲突时返回applyConflictError
+func (r *LSTMPredictAppReconciler) reconcilePDB(ctx context.Context, app *lstmappsv1.LSTMPredictApp) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	if desiredReplicas(app) <= 1 {
+		pdb := &policyv1.PodDisruptionBudget{}
+		err := r.Get(ctx, types.NamespacedName{Namespace: app.Namespace, Name: app.Name}, pdb)
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		if err != nil {
+			log.Error(err, "Failed to get PodDisruptionBudget, will requeue after a short time.")
+			return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+		}
+		if !metav1.IsControlledBy(pdb, app) {
+			return ctrl.Result{}, nil
+		}
+		if err := r.Delete(ctx, pdb); err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "Failed to delete PodDisruptionBudget, will requeue after a short time.")
+			return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+		}
+		log.Info("The PodDisruptionBudget has been deleted.")
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.applyOwned(ctx, app, pdbForApply(app), false); err != nil {
+		if isApplyConflict(err) {
+			return ctrl.Result{}, err
+		}
+		log.Error(err, "Failed to apply PodDisruptionBudget, will requeue, after a short time.")
+		return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// pdbForApply 构造PodDisruptionBudget中控制器负责的字段：标签、选择器以及minAvailable或maxUnavailable。
+// 选择器使用所有版本的Pod都带有的AppNameLabel，覆盖稳定版本、金丝雀与蓝绿发布的绿色版本
+func pdbForApply(app *lstmappsv1.LSTMPredictApp) *policyv1.PodDisruptionBudget {
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      app.Name,
+			Namespace: app.Namespace,
+			Labels:    app.Labels,
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{AppNameLabel: app.Name}},
+		},
+	}
+	disruption := app.Spec.Disruption
+	switch {
+	case disruption != nil && disruption.MinAvailable != nil:
+		pdb.Spec.MinAvailable = disruption.MinAvailable
+	case disruption != nil && disruption.MaxUnavailable != nil:
+		pdb.Spec.MaxUnavailable = disruption.MaxUnavailable
+	default:
+		maxUnavailable := DefaultMaxUnavailable
+		pdb.Spec.MaxUnavailable = &maxUnavailable
+	}
+	return pdb
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+var _ = Describe("LSTMPredictApp PodDisruptionBudget", func() {
+	const resourceName = "pdb-app"
+
+	ctx := context.Background()
+	typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+	var controllerReconciler *LSTMPredictAppReconciler
+
+	reconcileOnce := func() {
+		_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		controllerReconciler = &LSTMPredictAppReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		app := &lstmappsv1.LSTMPredictApp{
+			ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+			Spec: lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				ContainerPort:      8080,
+				BackendAppReplicas: ptr.To[int32](3),
+				ServicePort:        80,
+				ServiceType:        corev1.ServiceTypeClusterIP,
+			},
+		}
+		Expect(k8sClient.Create(ctx, app)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		deleteLSTMPredictApp(ctx, typeNamespacedName)
+		meta := metav1.ObjectMeta{Name: resourceName, Namespace: "default"}
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &appsv1.Deployment{ObjectMeta: meta}))).To(Succeed())
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &corev1.Service{ObjectMeta: meta}))).To(Succeed())
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &policyv1.PodDisruptionBudget{ObjectMeta: meta}))).To(Succeed())
+	})
+
+	It("should protect the pods while there is more than one replica", func() {
+		reconcileOnce()
+
+		app := &lstmappsv1.LSTMPredictApp{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		pdb := &policyv1.PodDisruptionBudget{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, pdb)).To(Succeed())
+		Expect(metav1.IsControlledBy(pdb, app)).To(BeTrue())
+		Expect(pdb.Spec.Selector.MatchLabels).To(Equal(map[string]string{AppNameLabel: resourceName}))
+		Expect(pdb.Spec.MaxUnavailable).To(Equal(ptr.To(intstr.FromString("25%"))))
+
+		By("switching to minAvailable")
+		app.Spec.Disruption = &lstmappsv1.DisruptionSpec{MinAvailable: ptr.To(intstr.FromInt32(2))}
+		Expect(k8sClient.Update(ctx, app)).To(Succeed())
+		reconcileOnce()
+		Expect(k8sClient.Get(ctx, typeNamespacedName, pdb)).To(Succeed())
+		Expect(pdb.Spec.MinAvailable).To(Equal(ptr.To(intstr.FromInt32(2))))
+		Expect(pdb.Spec.MaxUnavailable).To(BeNil())
+	})
+
+	It("should delete the PodDisruptionBudget when the replicas drop to 1", func() {
+		reconcileOnce()
+		pdb := &policyv1.PodDisruptionBudget{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, pdb)).To(Succeed())
+
+		app := &lstmappsv1.LSTMPredictApp{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		app.Spec.BackendAppReplicas = ptr.To[int32](1)
+		Expect(k8sClient.Update(ctx, app)).To(Succeed())
+		reconcileOnce()
+		Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, pdb))).To(BeTrue())
+	})
+})
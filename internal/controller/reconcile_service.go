@@ -2,13 +2,16 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/apimachinery/pkg/util/intstr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -16,97 +19,145 @@ import (
 func (r *LSTMPredictAppReconciler) reconcileService(ctx context.Context, app *lstmappsv1.LSTMPredictApp) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 
-	// 先根据LSTMPredictApp中的Namespace和Name信息查询对应的Service是否存在
-	var svc = &corev1.Service{}
-	// types.NamespaceedName用于唯一标识Kubernetes集群中的资源,svc是一个指针，如果Get方法成功执行，这个指针指向从API服务器中获取的Service对象
-	err := r.Get(ctx, types.NamespacedName{
-		Namespace: app.Namespace,
-		Name:      app.Name,
-	}, svc)
+	// clusterIP创建后不可修改，切换Headless时需要先删除原来的Service再重新创建
+	existing := &corev1.Service{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: app.Namespace, Name: app.Name}, existing)
+	if err != nil && !apierrors.IsNotFound(err) {
+		log.Error(err, "Failed to get Service, will requeue after a short time.")
+		return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+	}
+	created := apierrors.IsNotFound(err)
+	if err == nil && metav1.IsControlledBy(existing, app) && existing.Spec.ClusterIP != "" &&
+		(existing.Spec.ClusterIP == corev1.ClusterIPNone) != isHeadless(app) {
+		if err := r.Delete(ctx, existing); err != nil && !apierrors.IsNotFound(err) {
+			log.Error(err, "Failed to delete Service for recreation, will requeue after a short time.")
+			return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+		}
+		log.Info("The Service has been deleted to switch its clusterIP.", "headless", isHeadless(app))
+		created = true
+	}
 
-	// 没有错误发生时，更新状态
-	if err == nil {
-		log.Info("The Service has already exist.")
+	// 以服务端应用的方式创建或更新Service，只写入控制器负责的字段，ClusterIP、NodePort等由API Server分配的字段不受影响
+	svc := serviceForApply(app)
+	if err := r.applyOwned(ctx, app, svc, false); err != nil {
+		if isApplyConflict(err) {
+			return ctrl.Result{}, err
+		}
+		log.Error(err, "Failed to apply Service, will requeue, after a short time.")
+		return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+	}
+	if created {
+		r.event(app, corev1.EventTypeNormal, ReasonServiceCreated, fmt.Sprintf("Created %s Service %s", svc.Spec.Type, svc.Name))
+	}
 
-		// 属性更新,逐个属性判断是否有变更,如果有变更再更新现存资源
-		var isChanged bool = false
-		svc.Spec.Ports[0].TargetPort = intstr.FromInt32(app.Spec.ContainerPort)
-		if app.Spec.ServicePort != svc.Spec.Ports[0].Port {
-			svc.Spec.Ports[0].Port = app.Spec.ServicePort
-			isChanged = true
+	// 按spec.expose维护Ingress或HTTPRoute，冲突与Service的冲突一样记录在Synced条件中
+	var exposeConflict, exposePending error
+	if err := r.reconcileExpose(ctx, app); err != nil {
+		switch {
+		case isApplyConflict(err):
+			exposeConflict = err
+		case meta.IsNoMatchError(err) || errors.Is(err, errGatewayNotSpecified):
+			// 安装Gateway API或者修改Spec之后才能恢复，不重新排队
+			exposePending = err
+		default:
+			log.Error(err, "Failed to reconcile the routing object, will requeue, after a short time.")
+			return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
 		}
-		if app.Spec.ServiceType != svc.Spec.Type {
-			svc.Spec.Type = app.Spec.ServiceType
-			isChanged = true
+	}
+
+	// 状态更新，svc是应用后的完整对象；对集群外暴露时报告外部地址，否则报告主端口的地址
+	var serviceEndpoint string
+	port := findServicePort(svc, primaryPort(app).Name)
+	switch {
+	case app.Spec.Expose != nil:
+		if exposePending == nil {
+			serviceEndpoint = exposeURL(app.Spec.Expose)
 		}
-		if isChanged {
-			if err = r.Update(ctx, svc); err != nil {
-				log.Error(err, "Failed to Update Service, will requeue, after a short time.")
-				return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+	case port == nil:
+	case svc.Spec.Type == corev1.ServiceTypeClusterIP:
+		serviceEndpoint = fmt.Sprintf("%s.%s.svc.cluster.local:%d", svc.Name, svc.Namespace, port.Port)
+	case svc.Spec.Type == corev1.ServiceTypeNodePort:
+		serviceEndpoint = fmt.Sprintf("%s:%d", svc.Spec.ClusterIP, port.NodePort)
+	case svc.Spec.Type == corev1.ServiceTypeLoadBalancer:
+		// 负载均衡器的地址由云厂商异步分配，分配前为空
+		if ingress := svc.Status.LoadBalancer.Ingress; len(ingress) > 0 {
+			host := ingress[0].IP
+			if host == "" {
+				host = ingress[0].Hostname
+			}
+			if host != "" {
+				serviceEndpoint = fmt.Sprintf("%s:%d", host, port.Port)
 			}
-			log.Info("The LSTMPredictApp ServiceSpec has been updated.")
 		}
-
-		// 状态更新
-		var serviceEndpoint string
-		switch svc.Spec.Type {
-		case corev1.ServiceTypeClusterIP:
-			serviceEndpoint = fmt.Sprintf("%s.%s.svc.cluster.local:%d", svc.Name, svc.Namespace, svc.Spec.Ports[0].Port)
-		case corev1.ServiceTypeNodePort:
-			serviceEndpoint = fmt.Sprintf("%s:%d", svc.Spec.ClusterIP, svc.Spec.Ports[0].NodePort)
+	}
+	// 端点地址分配后Service才算就绪
+	var conditionChanged bool
+	switch {
+	case serviceEndpoint != "":
+		conditionChanged = setCondition(app, lstmappsv1.ConditionTypeServiceReady, metav1.ConditionTrue, ReasonEndpointAssigned, serviceEndpoint)
+	case exposePending != nil:
+		conditionChanged = setCondition(app, lstmappsv1.ConditionTypeServiceReady, metav1.ConditionFalse, ReasonExposeFailed, exposePending.Error())
+		if conditionChanged {
+			r.event(app, corev1.EventTypeWarning, ReasonExposeFailed, exposePending.Error())
 		}
-		if serviceEndpoint != app.Status.ServiceEndPoint {
-			app.Status.ServiceEndPoint = serviceEndpoint
-			// 如果不同，需要更新LSTMPredictApp的状态
-			// 调用r.Status().Update更新LSTMPredictApp资源的状态
-			if err = r.Status().Update(ctx, app); err != nil {
-				log.Error(err, "Failed to update LSTMPredictApp status")
-				// 返回一个带有重新排队时间的结果和错误，表示需要在一段时间后重试
-				return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
-			}
-			log.Info("The LSTMPredictApp ServiceStatus has been updated.")
+	default:
+		conditionChanged = setCondition(app, lstmappsv1.ConditionTypeServiceReady, metav1.ConditionFalse, ReasonEndpointPending, "Waiting for the Service endpoint to be assigned")
+	}
+	if serviceEndpoint != app.Status.ServiceEndPoint || conditionChanged {
+		app.Status.ServiceEndPoint = serviceEndpoint
+		// 如果不同，需要更新LSTMPredictApp的状态
+		// 调用r.Status().Update更新LSTMPredictApp资源的状态
+		if err := r.Status().Update(ctx, app); err != nil {
+			log.Error(err, "Failed to update LSTMPredictApp status")
+			// 返回一个带有重新排队时间的结果和错误，表示需要在一段时间后重试
+			return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
 		}
-
-		log.Info("The LSTMPredictApp ServiceStatus and ServiceSpec has not been changed.")
-		return ctrl.Result{}, nil
+		log.Info("The LSTMPredictApp ServiceStatus has been updated.")
 	}
-
-	// 如果不是NotFound的错误，即发生了其他错误，结束本轮调谐，一段时间后重试
-	if !errors.IsNotFound(err) {
-		log.Error(err, "Failed to get Service, will requeue after a short time.")
-		return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+	// 负载均衡器的地址分配不会改变Service的Spec，需要重新排队直到地址出现
+	if serviceEndpoint == "" && svc.Spec.Type == corev1.ServiceTypeLoadBalancer && app.Spec.Expose == nil {
+		log.Info("Waiting for the load balancer address to be assigned.")
+		return ctrl.Result{RequeueAfter: LoadBalancerRequeueDuration}, exposeConflict
 	}
+	return ctrl.Result{}, exposeConflict
+}
 
-	// 根据LSTMPredictApp资源实例信息来构造Service实例
-	newService := &corev1.Service{}
-	newService.SetName(app.Name)
-	newService.SetNamespace(app.Namespace)
-	newService.SetLabels(app.Labels)
+// LoadBalancerRequeueDuration 等待负载均衡器分配地址时重新排队的时间间隔
+const LoadBalancerRequeueDuration = 10 * time.Second
+
+// isHeadless 判断是否需要创建Headless Service，只对ClusterIP类型生效
+func isHeadless(app *lstmappsv1.LSTMPredictApp) bool {
+	return app.Spec.Headless && (app.Spec.ServiceType == corev1.ServiceTypeClusterIP || app.Spec.ServiceType == "")
+}
 
-	newService.Spec = corev1.ServiceSpec{
-		Type:     app.Spec.ServiceType,
-		Selector: map[string]string{"app": app.Name},
-		Ports: []corev1.ServicePort{
-			{
-				Port:       app.Spec.ServicePort,
-				TargetPort: intstr.FromInt32(app.Spec.ContainerPort),
-			},
+// serviceForApply 构造Service中控制器负责的字段：标签、类型、选择器与全部端口，
+// 以及Headless的clusterIP与负载均衡器的设置
+func serviceForApply(app *lstmappsv1.LSTMPredictApp) *corev1.Service {
+	// Service额外带上应用名标签，供ServiceMonitor选择
+	labels := map[string]string{AppNameLabel: app.Name}
+	for k, v := range app.Labels {
+		labels[k] = v
+	}
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      app.Name,
+			Namespace: app.Namespace,
+			Labels:    labels,
+		},
+		Spec: corev1.ServiceSpec{
+			Type: app.Spec.ServiceType,
+			// 蓝绿发布晋升期间选择器指向绿色版本，其余时间指向稳定版本
+			Selector: serviceSelector(app),
+			Ports:    servicePorts(app),
 		},
 	}
-
-	// 用于建立App里擦同与Service之间的父子关系：Kubernetes通过owner Reference实现级联删除，当LSTMPredictApp被删除时，Kubernetes
-	// 会自动删除它创建的Service; r.scheme用来识别资源类型的Scheme，确保类型正确
-	if err := ctrl.SetControllerReference(app, newService, r.Scheme); err != nil {
-		log.Error(err, "Failed to SetControllerReference, will requeue after a short time.")
-		return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+	if isHeadless(app) {
+		svc.Spec.ClusterIP = corev1.ClusterIPNone
 	}
-
-	// 在集群中创建Service：调用客户端的Create方法，将newDp提交到Kubernetes API Server
-	if err := r.Create(ctx, newService); err != nil {
-		log.Error(err, "Failed to create Service, will requeue, after a short time.")
-		return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+	if lb := app.Spec.LoadBalancer; lb != nil && app.Spec.ServiceType == corev1.ServiceTypeLoadBalancer {
+		svc.Annotations = lb.Annotations
+		svc.Spec.LoadBalancerClass = lb.LoadBalancerClass
+		svc.Spec.LoadBalancerSourceRanges = lb.SourceRanges
 	}
-
-	log.Info("The Service has been created.")
-	return ctrl.Result{}, nil
+	return svc
 }
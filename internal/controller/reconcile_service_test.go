@@ -0,0 +1,205 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+var _ = Describe("LSTMPredictApp Service types", func() {
+	const resourceName = "service-app"
+
+	ctx := context.Background()
+	typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+	var controllerReconciler *LSTMPredictAppReconciler
+
+	BeforeEach(func() {
+		controllerReconciler = &LSTMPredictAppReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		app := &lstmappsv1.LSTMPredictApp{
+			ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+			Spec: lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				ContainerPort:      8080,
+				BackendAppReplicas: ptr.To[int32](1),
+				ServicePort:        80,
+				ServiceType:        corev1.ServiceTypeLoadBalancer,
+				LoadBalancer: &lstmappsv1.LoadBalancerSpec{
+					LoadBalancerClass: ptr.To("service.k8s.aws/nlb"),
+					SourceRanges:      []string{"10.0.0.0/8"},
+					Annotations:       map[string]string{"service.beta.kubernetes.io/aws-load-balancer-scheme": "internet-facing"},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, app)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		deleteLSTMPredictApp(ctx, typeNamespacedName)
+		meta := metav1.ObjectMeta{Name: resourceName, Namespace: "default"}
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &appsv1.Deployment{ObjectMeta: meta}))).To(Succeed())
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &corev1.Service{ObjectMeta: meta}))).To(Succeed())
+	})
+
+	It("should requeue until the load balancer address is assigned", func() {
+		result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(LoadBalancerRequeueDuration))
+
+		svc := &corev1.Service{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, svc)).To(Succeed())
+		Expect(svc.Spec.Type).To(Equal(corev1.ServiceTypeLoadBalancer))
+		Expect(svc.Spec.LoadBalancerClass).To(Equal(ptr.To("service.k8s.aws/nlb")))
+		Expect(svc.Spec.LoadBalancerSourceRanges).To(Equal([]string{"10.0.0.0/8"}))
+		Expect(svc.Annotations).To(HaveKeyWithValue("service.beta.kubernetes.io/aws-load-balancer-scheme", "internet-facing"))
+
+		app := &lstmappsv1.LSTMPredictApp{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		Expect(app.Status.ServiceEndPoint).To(BeEmpty())
+		Expect(meta.IsStatusConditionFalse(app.Status.Conditions, lstmappsv1.ConditionTypeServiceReady)).To(BeTrue())
+
+		By("assigning the load balancer address")
+		svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{Hostname: "lstm.elb.example.com"}}
+		Expect(k8sClient.Status().Update(ctx, svc)).To(Succeed())
+		result, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeZero())
+
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		Expect(app.Status.ServiceEndPoint).To(Equal("lstm.elb.example.com:80"))
+		Expect(meta.IsStatusConditionTrue(app.Status.Conditions, lstmappsv1.ConditionTypeServiceReady)).To(BeTrue())
+	})
+
+	It("should recreate the Service when switching to headless", func() {
+		app := &lstmappsv1.LSTMPredictApp{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		app.Spec.ServiceType = corev1.ServiceTypeClusterIP
+		app.Spec.LoadBalancer = nil
+		Expect(k8sClient.Update(ctx, app)).To(Succeed())
+		_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).NotTo(HaveOccurred())
+
+		// 用其他管理者添加的注解标记原来的Service，重新创建后注解消失；envtest中没有分配ClusterIP时手动模拟
+		svc := &corev1.Service{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, svc)).To(Succeed())
+		if svc.Spec.ClusterIP == "" {
+			svc.Spec.ClusterIP = "10.96.0.10"
+		}
+		svc.Annotations = map[string]string{"example.com/marker": "old"}
+		Expect(k8sClient.Update(ctx, svc)).To(Succeed())
+
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		app.Spec.Headless = true
+		Expect(k8sClient.Update(ctx, app)).To(Succeed())
+		_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(k8sClient.Get(ctx, typeNamespacedName, svc)).To(Succeed())
+		Expect(svc.Spec.ClusterIP).To(Equal(corev1.ClusterIPNone))
+		Expect(svc.Annotations).NotTo(HaveKey("example.com/marker"))
+
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		Expect(app.Status.ServiceEndPoint).To(Equal("service-app.default.svc.cluster.local:80"))
+	})
+})
+
+var _ = Describe("LSTMPredictApp named ports", func() {
+	const resourceName = "ports-app"
+
+	ctx := context.Background()
+	typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+	BeforeEach(func() {
+		app := &lstmappsv1.LSTMPredictApp{
+			ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+			Spec: lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				BackendAppReplicas: ptr.To[int32](1),
+				ServiceType:        corev1.ServiceTypeNodePort,
+				Ports: []lstmappsv1.PortSpec{
+					{Name: "http", ContainerPort: 8080, ServicePort: 80},
+					{Name: "grpc", ContainerPort: 9090, AppProtocol: ptr.To("grpc")},
+					{Name: "metrics", ContainerPort: 9100},
+				},
+				Probes: &lstmappsv1.ProbesSpec{Path: "/healthz"},
+			},
+		}
+		Expect(k8sClient.Create(ctx, app)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		deleteLSTMPredictApp(ctx, typeNamespacedName)
+		meta := metav1.ObjectMeta{Name: resourceName, Namespace: "default"}
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &appsv1.Deployment{ObjectMeta: meta}))).To(Succeed())
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &corev1.Service{ObjectMeta: meta}))).To(Succeed())
+	})
+
+	It("should carry every port to the container and the Service", func() {
+		controllerReconciler := &LSTMPredictAppReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+		_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).NotTo(HaveOccurred())
+
+		dp := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, dp)).To(Succeed())
+		container := dp.Spec.Template.Spec.Containers[0]
+		Expect(container.Ports).To(ConsistOf(
+			corev1.ContainerPort{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP},
+			corev1.ContainerPort{Name: "grpc", ContainerPort: 9090, Protocol: corev1.ProtocolTCP},
+			corev1.ContainerPort{Name: "metrics", ContainerPort: 9100, Protocol: corev1.ProtocolTCP},
+		))
+		// 探针访问主端口
+		Expect(container.LivenessProbe.HTTPGet.Port.IntValue()).To(Equal(8080))
+
+		svc := &corev1.Service{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, svc)).To(Succeed())
+		Expect(svc.Spec.Ports).To(HaveLen(3))
+		grpc := findServicePort(svc, "grpc")
+		Expect(grpc).NotTo(BeNil())
+		Expect(grpc.Port).To(Equal(int32(9090)))
+		Expect(grpc.AppProtocol).To(Equal(ptr.To("grpc")))
+		Expect(findServicePort(svc, "http").TargetPort.IntValue()).To(Equal(8080))
+
+		// envtest中由API Server分配NodePort，这里手动模拟
+		for i := range svc.Spec.Ports {
+			if svc.Spec.Ports[i].NodePort == 0 {
+				svc.Spec.Ports[i].NodePort = 30080 + int32(i)
+			}
+		}
+		svc.Spec.ClusterIP = "10.96.0.20"
+		Expect(k8sClient.Update(ctx, svc)).To(Succeed())
+		_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).NotTo(HaveOccurred())
+
+		app := &lstmappsv1.LSTMPredictApp{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		Expect(app.Status.ServiceEndPoint).To(Equal(fmt.Sprintf("10.96.0.20:%d", findServicePort(svc, "http").NodePort)))
+	})
+})
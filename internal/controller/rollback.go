@@ -0,0 +1,177 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// 每个应用最多保留的ControllerRevision数量
+const revisionHistoryLimit = 10
+
+// revisionName 返回记录某个Pod模板版本的ControllerRevision的名字
+func revisionName(app *lstmappsv1.LSTMPredictApp, revision string) string {
+	return app.Name + "-" + revision
+}
+
+// deploymentRevision 返回Deployment当前Pod模板的版本，优先使用控制器更新时记录的注解
+func deploymentRevision(dp *appsv1.Deployment) string {
+	if revision := dp.Annotations[TemplateHashAnnotation]; revision != "" {
+		return revision
+	}
+	return computeTemplateHash(&dp.Spec.Template)
+}
+
+// setDeploymentRevision 在Deployment上记录Pod模板的版本。
+// 版本按控制器计算出的模板（未经过API Server默认值填充）计算，下一轮由同样的Spec计算出的模板得到相同的版本
+func setDeploymentRevision(dp *appsv1.Deployment, revision string) {
+	if dp.Annotations == nil {
+		dp.Annotations = map[string]string{}
+	}
+	dp.Annotations[TemplateHashAnnotation] = revision
+}
+
+// recordGoodRevision 稳定版本的Deployment完成滚动并且全部就绪后，将其Pod模板保存为ControllerRevision，作为回滚的目标
+func (r *LSTMPredictAppReconciler) recordGoodRevision(ctx context.Context, app *lstmappsv1.LSTMPredictApp, dp *appsv1.Deployment, replicas int32) error {
+	if !deploymentRolledOut(dp, replicas) {
+		return nil
+	}
+	if exceeded, _ := deploymentDeadlineExceeded(dp); exceeded {
+		return nil
+	}
+	revision := deploymentRevision(dp)
+	if app.Status.LastGoodRevision == revision {
+		return nil
+	}
+
+	revisions, err := r.listRevisions(ctx, app)
+	if err != nil {
+		return err
+	}
+	var next int64 = 1
+	if len(revisions) > 0 {
+		next = revisions[len(revisions)-1].Revision + 1
+	}
+
+	data, err := json.Marshal(&dp.Spec.Template)
+	if err != nil {
+		return err
+	}
+	// ControllerRevision不使用服务端应用：它是只由控制器写入的Pod模板快照，data创建后不可修改，
+	// 不存在与其他字段管理者共同维护的字段，只在重新回到历史版本时更新revision
+	cr := &appsv1.ControllerRevision{}
+	err = r.Get(ctx, types.NamespacedName{Namespace: app.Namespace, Name: revisionName(app, revision)}, cr)
+	switch {
+	case errors.IsNotFound(err):
+		cr = &appsv1.ControllerRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      revisionName(app, revision),
+				Namespace: app.Namespace,
+				Labels:    map[string]string{AppNameLabel: app.Name},
+			},
+			Data:     runtime.RawExtension{Raw: data},
+			Revision: next,
+		}
+		if err := ctrl.SetControllerReference(app, cr, r.Scheme); err != nil {
+			return err
+		}
+		if err := r.Create(ctx, cr); err != nil {
+			return err
+		}
+		revisions = append(revisions, *cr)
+	case err != nil:
+		return err
+	case !metav1.IsControlledBy(cr, app):
+		return notControlledError(app, "ControllerRevision", cr.Name)
+	default:
+		// 重新回到了历史上的某个版本，把它移到最新
+		cr.Data = runtime.RawExtension{Raw: data}
+		cr.Revision = next
+		if err := r.Update(ctx, cr); err != nil {
+			return err
+		}
+	}
+	log.FromContext(ctx).Info("Recorded the last known-good revision.", "revision", revision)
+	app.Status.LastGoodRevision = revision
+
+	// 清理超出数量限制的旧版本
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Revision < revisions[j].Revision })
+	for i := 0; i < len(revisions)-revisionHistoryLimit; i++ {
+		if revisions[i].Name == cr.Name {
+			continue
+		}
+		if err := r.Delete(ctx, &revisions[i]); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollbackDeployment 稳定版本的Deployment因超过progressDeadlineSeconds而停止滚动时，
+// 将Pod模板恢复为最近一次就绪的版本，并记录被回滚的版本，返回是否发生了回滚
+func (r *LSTMPredictAppReconciler) rollbackDeployment(ctx context.Context, app *lstmappsv1.LSTMPredictApp, dp *appsv1.Deployment) (bool, error) {
+	log := log.FromContext(ctx)
+
+	exceeded, reason := deploymentDeadlineExceeded(dp)
+	if !exceeded || app.Status.LastGoodRevision == "" {
+		return false, nil
+	}
+	failed := deploymentRevision(dp)
+	if failed == app.Status.LastGoodRevision {
+		// 最近一次就绪的版本本身无法就绪（如节点资源不足），回滚也无济于事
+		return false, nil
+	}
+
+	cr := &appsv1.ControllerRevision{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: app.Namespace, Name: revisionName(app, app.Status.LastGoodRevision)}, cr); err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("The last known-good revision no longer exists, skip rollback.", "revision", app.Status.LastGoodRevision)
+			return false, nil
+		}
+		return false, err
+	}
+	template := corev1.PodTemplateSpec{}
+	if err := json.Unmarshal(cr.Data.Raw, &template); err != nil {
+		return false, fmt.Errorf("failed to decode ControllerRevision %s: %w", cr.Name, err)
+	}
+
+	dp.Spec.Template = template
+	setDeploymentRevision(dp, app.Status.LastGoodRevision)
+	if err := r.applyOwned(ctx, app, deploymentForApply(app, dp), true); err != nil {
+		return false, err
+	}
+
+	message := fmt.Sprintf("Revision %s failed to become ready (%s), rolled back to revision %s", failed, reason, app.Status.LastGoodRevision)
+	log.Info("The Deployment has been rolled back.", "failedRevision", failed, "revision", app.Status.LastGoodRevision)
+	app.Status.Rollback = &lstmappsv1.RollbackStatus{
+		FailedRevision: failed,
+		Revision:       app.Status.LastGoodRevision,
+		Time:           metav1.Now(),
+		Message:        message,
+	}
+	r.event(app, corev1.EventTypeWarning, ReasonRolledBack, message)
+	rollbacksTotal.WithLabelValues(app.Namespace, app.Name).Inc()
+	return true, nil
+}
+
+// listRevisions 按版本号从小到大列出属于该应用的ControllerRevision
+func (r *LSTMPredictAppReconciler) listRevisions(ctx context.Context, app *lstmappsv1.LSTMPredictApp) ([]appsv1.ControllerRevision, error) {
+	list := &appsv1.ControllerRevisionList{}
+	if err := r.List(ctx, list, client.InNamespace(app.Namespace), client.MatchingLabels{AppNameLabel: app.Name}); err != nil {
+		return nil, err
+	}
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].Revision < list.Items[j].Revision })
+	return list.Items, nil
+}
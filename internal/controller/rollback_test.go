@@ -0,0 +1,145 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+var _ = Describe("Automatic rollback", func() {
+	const resourceName = "rollback-app"
+
+	ctx := context.Background()
+	typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+
+	var (
+		controllerReconciler *LSTMPredictAppReconciler
+		recorder             *record.FakeRecorder
+	)
+
+	reconcileOnce := func() {
+		_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	// setDeploymentStatus 模拟Deployment控制器更新状态
+	setDeploymentStatus := func(mutate func(dp *appsv1.Deployment)) {
+		dp := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, dp)).To(Succeed())
+		mutate(dp)
+		Expect(k8sClient.Status().Update(ctx, dp)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		recorder = record.NewFakeRecorder(10)
+		controllerReconciler = &LSTMPredictAppReconciler{
+			Client:   k8sClient,
+			Scheme:   k8sClient.Scheme(),
+			Recorder: recorder,
+		}
+
+		app := &lstmappsv1.LSTMPredictApp{
+			ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+			Spec: lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				ContainerPort:      8080,
+				BackendAppReplicas: ptr.To[int32](2),
+				ServicePort:        80,
+				ServiceType:        corev1.ServiceTypeClusterIP,
+			},
+		}
+		Expect(k8sClient.Create(ctx, app)).To(Succeed())
+		reconcileOnce()
+		// 取走创建Deployment与Service的事件
+		Expect(drainEvents(recorder)).To(ContainElements(ContainSubstring(ReasonDeploymentCreated), ContainSubstring(ReasonServiceCreated)))
+	})
+
+	AfterEach(func() {
+		deleteLSTMPredictApp(ctx, typeNamespacedName)
+		// envtest中没有垃圾回收，手动删除子资源
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"}}))).To(Succeed())
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"}}))).To(Succeed())
+		Expect(k8sClient.DeleteAllOf(ctx, &appsv1.ControllerRevision{}, client.InNamespace("default"),
+			client.MatchingLabels{AppNameLabel: resourceName})).To(Succeed())
+	})
+
+	It("should revert to the last known-good revision when the new one exceeds its progress deadline", func() {
+		By("marking the first revision as rolled out")
+		setDeploymentStatus(func(dp *appsv1.Deployment) {
+			dp.Status = appsv1.DeploymentStatus{
+				ObservedGeneration: dp.Generation,
+				Replicas:           2,
+				UpdatedReplicas:    2,
+				ReadyReplicas:      2,
+				AvailableReplicas:  2,
+			}
+		})
+		reconcileOnce()
+
+		app := &lstmappsv1.LSTMPredictApp{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		Expect(app.Status.LastGoodRevision).NotTo(BeEmpty())
+		revisions := &appsv1.ControllerRevisionList{}
+		Expect(k8sClient.List(ctx, revisions, client.InNamespace("default"), client.MatchingLabels{AppNameLabel: resourceName})).To(Succeed())
+		Expect(revisions.Items).To(HaveLen(1))
+
+		By("rolling out a broken image")
+		app.Spec.AppImage = "lstm-predict-server:broken"
+		Expect(k8sClient.Update(ctx, app)).To(Succeed())
+		reconcileOnce()
+		Expect(recorder.Events).To(Receive(ContainSubstring(ReasonDriftCorrected)))
+		setDeploymentStatus(func(dp *appsv1.Deployment) {
+			Expect(dp.Spec.Template.Spec.Containers[0].Image).To(Equal("lstm-predict-server:broken"))
+			dp.Status.ObservedGeneration = dp.Generation
+			dp.Status.Conditions = []appsv1.DeploymentCondition{{
+				Type:    appsv1.DeploymentProgressing,
+				Status:  corev1.ConditionFalse,
+				Reason:  ReasonProgressDeadlineExceeded,
+				Message: "ReplicaSet has timed out progressing.",
+			}}
+		})
+		reconcileOnce()
+
+		dp := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, dp)).To(Succeed())
+		Expect(dp.Spec.Template.Spec.Containers[0].Image).To(Equal("lstm-predict-server:v1.0"))
+		Expect(recorder.Events).To(Receive(ContainSubstring(ReasonRolledBack)))
+
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		Expect(app.Status.Rollback).NotTo(BeNil())
+		Expect(meta.IsStatusConditionTrue(app.Status.Conditions, lstmappsv1.ConditionTypeDegraded)).To(BeTrue())
+
+		By("keeping the known-good revision while the spec still asks for the broken one")
+		reconcileOnce()
+		Expect(k8sClient.Get(ctx, typeNamespacedName, dp)).To(Succeed())
+		Expect(dp.Spec.Template.Spec.Containers[0].Image).To(Equal("lstm-predict-server:v1.0"))
+	})
+})
@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// TrackLabel 区分稳定版本与发布中的新版本Pod的标签
+	TrackLabel = "lstmapps.wuyong7240.com/track"
+	// TemplateHashAnnotation 记录Deployment所使用的Pod模板哈希的注解
+	TemplateHashAnnotation = "lstmapps.wuyong7240.com/template-hash"
+	// 等待新版本就绪时的重新排队间隔
+	rolloutPollInterval = 10 * time.Second
+)
+
+// rolloutResult 一轮金丝雀或蓝绿发布调谐的结果
+type rolloutResult struct {
+	// 稳定版本Deployment应有的副本数
+	stableReplicas int32
+	// 是否将新版本的Pod模板更新到稳定版本的Deployment上，即全量发布
+	promote bool
+	// 下一次检查的时间间隔，0表示依赖Deployment的状态变化触发
+	requeueAfter time.Duration
+}
+
+// computeTemplateHash 计算Pod模板的哈希，用于标识一次发布的版本
+func computeTemplateHash(template *corev1.PodTemplateSpec) string {
+	hasher := fnv.New32a()
+	data, err := json.Marshal(template)
+	if err != nil {
+		// PodTemplateSpec总是可以序列化的，这里只是兜底
+		data = []byte(fmt.Sprintf("%#v", template))
+	}
+	hasher.Write(data) //nolint:errcheck
+	return rand.SafeEncodeString(fmt.Sprint(hasher.Sum32()))
+}
+
+// deploymentRolledOut 判断Deployment是否已经以replicas个副本完成滚动
+func deploymentRolledOut(dp *appsv1.Deployment, replicas int32) bool {
+	return dp.Status.ObservedGeneration >= dp.Generation &&
+		dp.Status.UpdatedReplicas >= replicas &&
+		dp.Status.ReadyReplicas >= replicas &&
+		dp.Status.Replicas == dp.Status.UpdatedReplicas
+}
+
+// deploymentDeadlineExceeded 判断Deployment是否因超过progressDeadlineSeconds而停止滚动
+func deploymentDeadlineExceeded(dp *appsv1.Deployment) (bool, string) {
+	for _, cond := range dp.Status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing && cond.Status == corev1.ConditionFalse &&
+			cond.Reason == ReasonProgressDeadlineExceeded {
+			return true, cond.Message
+		}
+	}
+	return false, ""
+}
+
+// sameRollout 判断status中记录的发布是否就是指定策略下该版本的发布，并且处于指定阶段
+func sameRollout(st *lstmappsv1.RolloutStatus, strategy lstmappsv1.RolloutStrategy, revision string, phase lstmappsv1.RolloutPhase) bool {
+	return st != nil && st.Strategy == strategy && st.Revision == revision && st.Phase == phase
+}
+
+// recordRollout 比较调谐前后的发布状态，发布开始、进入下一步、全量或中止时产生事件并计数
+func (r *LSTMPredictAppReconciler) recordRollout(app *lstmappsv1.LSTMPredictApp, before *lstmappsv1.RolloutStatus) {
+	after := app.Status.Rollout
+	observeRollout(before, after)
+	if after == nil || equality.Semantic.DeepEqual(before, after) {
+		return
+	}
+	sameRevision := before != nil && before.Revision == after.Revision
+	switch after.Phase {
+	case lstmappsv1.RolloutPhaseProgressing:
+		if !sameRevision || before.Phase != after.Phase {
+			r.event(app, corev1.EventTypeNormal, ReasonRolloutStarted,
+				fmt.Sprintf("Started %s rollout of revision %s", after.Strategy, after.Revision))
+		} else if before.CurrentStep != after.CurrentStep {
+			r.event(app, corev1.EventTypeNormal, ReasonRolloutStep,
+				fmt.Sprintf("Rollout of revision %s moved to step %d with %d%% of the traffic", after.Revision, after.CurrentStep, after.CurrentWeight))
+		}
+	case lstmappsv1.RolloutPhasePromoted:
+		if !sameRevision || before.Phase != after.Phase {
+			r.event(app, corev1.EventTypeNormal, ReasonRolloutPromoted, fmt.Sprintf("Revision %s has been promoted to all replicas", after.Revision))
+		}
+	case lstmappsv1.RolloutPhaseAborted:
+		if !sameRevision || before.Phase != after.Phase {
+			r.event(app, corev1.EventTypeWarning, ReasonRolloutAborted, after.Message)
+		}
+	}
+}
+
+// finishRollout 在稳定版本的Pod模板已经与期望一致时，按上一次发布的策略收尾
+func (r *LSTMPredictAppReconciler) finishRollout(ctx context.Context, app *lstmappsv1.LSTMPredictApp, stable *appsv1.Deployment) (time.Duration, error) {
+	st := app.Status.Rollout
+	if st == nil {
+		return 0, nil
+	}
+	switch st.Strategy {
+	case lstmappsv1.RolloutStrategyCanary:
+		return r.finishCanary(ctx, app, stable)
+	case lstmappsv1.RolloutStrategyBlueGreen:
+		return r.finishBlueGreen(ctx, app, stable)
+	}
+	return 0, nil
+}
+
+// abortRollout 中止发布，删除承载新版本的Deployment，稳定版本恢复全部副本
+func (r *LSTMPredictAppReconciler) abortRollout(ctx context.Context, app *lstmappsv1.LSTMPredictApp, name, message string) (rolloutResult, error) {
+	log.FromContext(ctx).Info("Abort rollout.", "reason", message)
+	st := app.Status.Rollout
+	st.Phase = lstmappsv1.RolloutPhaseAborted
+	st.Message = message
+	if len(st.Steps) > 0 {
+		st.Steps[len(st.Steps)-1].Result = "Failed"
+		st.Steps[len(st.Steps)-1].Message = message
+	}
+	return rolloutResult{stableReplicas: desiredReplicas(app)}, r.deleteRolloutDeployment(ctx, app, name)
+}
+
+// applyRolloutDeployment 创建或更新承载新版本的Deployment（金丝雀或者蓝绿发布的绿色版本），返回集群中最新的对象。
+// template中的标签须与selector一致，同名的Deployment不受该应用控制时不会修改它，返回applyConflictError
+func (r *LSTMPredictAppReconciler) applyRolloutDeployment(ctx context.Context, app *lstmappsv1.LSTMPredictApp, name string, selector map[string]string,
+	template *corev1.PodTemplateSpec, revision string, replicas int32) (*appsv1.Deployment, error) {
+	existing := &appsv1.Deployment{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: app.Namespace, Name: name}, existing)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	created := errors.IsNotFound(err)
+	if !created && !metav1.IsControlledBy(existing, app) {
+		return nil, notControlledError(app, "Deployment", name)
+	}
+
+	// 与稳定版本的Deployment一样以服务端应用的方式写入控制器负责的字段，与其他管理者冲突时返回applyConflictError
+	dp := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   app.Namespace,
+			Labels:      app.Labels,
+			Annotations: map[string]string{TemplateHashAnnotation: revision},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: selector},
+			Template: *template.DeepCopy(),
+		},
+	}
+	if err := r.applyOwned(ctx, app, dp, false); err != nil {
+		return nil, err
+	}
+	if created {
+		log.FromContext(ctx).Info("The rollout Deployment has been created.", "name", name, "replicas", replicas)
+	} else if existing.Annotations[TemplateHashAnnotation] != revision || existing.Spec.Replicas == nil || *existing.Spec.Replicas != replicas {
+		log.FromContext(ctx).Info("The rollout Deployment has been updated.", "name", name, "replicas", replicas)
+	}
+	return dp, nil
+}
+
+// deleteRolloutDeployment 删除承载新版本的Deployment，不存在或者不受该应用控制时忽略
+func (r *LSTMPredictAppReconciler) deleteRolloutDeployment(ctx context.Context, app *lstmappsv1.LSTMPredictApp, name string) error {
+	return r.deleteOwned(ctx, app, &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name}})
+}
@@ -0,0 +1,228 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// TrackGreen 蓝绿发布中绿色版本Pod的track标签值
+	TrackGreen = "green"
+	// AppNameLabel 所有版本的Pod都带有该标签；绿色版本的Pod不带app标签（避免被主Service选中），通过该标签关联所属的应用
+	AppNameLabel = "lstmapps.wuyong7240.com/app"
+	// PromoteAnnotation 值为"true"时晋升已经就绪的绿色版本，晋升开始后由控制器移除
+	PromoteAnnotation = "lstmapps.wuyong7240.com/promote"
+	// 主Service切换回稳定版本后，等待Endpoints同步再删除绿色版本
+	blueGreenScaleDownDelay = 5 * time.Second
+)
+
+// greenName 返回绿色版本Deployment的名字
+func greenName(app *lstmappsv1.LSTMPredictApp) string {
+	return app.Name + "-green"
+}
+
+// previewName 返回暴露绿色版本的预览Service的名字
+func previewName(app *lstmappsv1.LSTMPredictApp) string {
+	return app.Name + "-preview"
+}
+
+// greenLabels 绿色版本Pod的标签，也是预览Service以及晋升期间主Service的选择器
+func greenLabels(app *lstmappsv1.LSTMPredictApp) map[string]string {
+	return map[string]string{AppNameLabel: app.Name, TrackLabel: TrackGreen}
+}
+
+// isBlueGreenRollout 判断LSTMPredictApp是否使用蓝绿发布
+func isBlueGreenRollout(app *lstmappsv1.LSTMPredictApp) bool {
+	return app.Spec.Rollout != nil && app.Spec.Rollout.Strategy == lstmappsv1.RolloutStrategyBlueGreen
+}
+
+// isGreenActive 蓝绿发布晋升中，主Service需要选中绿色版本
+func isGreenActive(app *lstmappsv1.LSTMPredictApp) bool {
+	st := app.Status.Rollout
+	return st != nil && st.Strategy == lstmappsv1.RolloutStrategyBlueGreen && st.Phase == lstmappsv1.RolloutPhasePromoting
+}
+
+// serviceSelector 返回主Service当前应使用的选择器
+func serviceSelector(app *lstmappsv1.LSTMPredictApp) map[string]string {
+	if isGreenActive(app) {
+		return greenLabels(app)
+	}
+	return map[string]string{"app": app.Name}
+}
+
+// reconcileBlueGreen 在稳定版本的Pod模板与期望的Pod模板不一致时推进蓝绿发布：
+// 以全部副本创建绿色版本，就绪后等待注解或者自动晋升；晋升时主Service先切到绿色版本，下一轮再更新稳定版本
+func (r *LSTMPredictAppReconciler) reconcileBlueGreen(ctx context.Context, app *lstmappsv1.LSTMPredictApp, desired *corev1.PodTemplateSpec) (rolloutResult, error) {
+	log := log.FromContext(ctx)
+
+	replicas := desiredReplicas(app)
+	revision := computeTemplateHash(desired)
+	result := rolloutResult{stableReplicas: replicas}
+
+	st := app.Status.Rollout
+	// 该版本已经被中止，保持稳定版本，直到Pod模板再次发生变化
+	if sameRollout(st, lstmappsv1.RolloutStrategyBlueGreen, revision, lstmappsv1.RolloutPhaseAborted) {
+		return result, r.deleteRolloutDeployment(ctx, app, greenName(app))
+	}
+	// 流量已经切到绿色版本，可以更新稳定版本了
+	if sameRollout(st, lstmappsv1.RolloutStrategyBlueGreen, revision, lstmappsv1.RolloutPhasePromoting) {
+		result.promote = true
+		return result, nil
+	}
+	if st == nil || st.Revision != revision || st.Strategy != lstmappsv1.RolloutStrategyBlueGreen ||
+		st.Phase != lstmappsv1.RolloutPhaseProgressing {
+		log.Info("Start blue/green rollout.", "revision", revision)
+		// 切换了发布策略时，清理上一次金丝雀发布留下的金丝雀
+		if err := r.deleteRolloutDeployment(ctx, app, canaryName(app)); err != nil {
+			return result, err
+		}
+		st = &lstmappsv1.RolloutStatus{
+			Strategy:       lstmappsv1.RolloutStrategyBlueGreen,
+			Phase:          lstmappsv1.RolloutPhaseProgressing,
+			Revision:       revision,
+			PreviewService: previewName(app),
+		}
+		app.Status.Rollout = st
+	}
+
+	template := desired.DeepCopy()
+	template.Labels = greenLabels(app)
+	green, err := r.applyRolloutDeployment(ctx, app, greenName(app), greenLabels(app), template, revision, replicas)
+	if err != nil {
+		return result, err
+	}
+
+	// 绿色版本无法就绪，中止发布
+	if exceeded, message := deploymentDeadlineExceeded(green); exceeded {
+		return r.abortRollout(ctx, app, greenName(app), fmt.Sprintf("green failed to become ready: %s", message))
+	}
+	if !deploymentRolledOut(green, replicas) {
+		st.StepStartTime = nil
+		st.Message = fmt.Sprintf("waiting for green replicas to become ready (%d/%d)", green.Status.ReadyReplicas, replicas)
+		result.requeueAfter = rolloutPollInterval
+		return result, nil
+	}
+	if st.StepStartTime == nil {
+		now := metav1.Now()
+		st.StepStartTime = &now
+	}
+
+	// 手动晋升：注解；自动晋升：绿色版本就绪后经过了autoPromoteAfter
+	promote := app.Annotations[PromoteAnnotation] == "true"
+	if bg := app.Spec.Rollout.BlueGreen; !promote && bg != nil && bg.AutoPromoteAfter != nil {
+		if elapsed := time.Since(st.StepStartTime.Time); elapsed < bg.AutoPromoteAfter.Duration {
+			result.requeueAfter = bg.AutoPromoteAfter.Duration - elapsed
+		} else {
+			promote = true
+		}
+	}
+	if !promote {
+		st.Message = fmt.Sprintf("green is ready and exposed by Service %s, waiting for promotion", previewName(app))
+		return result, nil
+	}
+
+	// 晋升：本轮只切换主Service，稳定版本在下一轮再更新，保证切换时稳定版本还在提供服务
+	log.Info("Promote the green revision.", "revision", revision)
+	st.Phase = lstmappsv1.RolloutPhasePromoting
+	st.CurrentWeight = 100
+	st.Message = "traffic has been switched to green, updating the stable revision"
+	if _, ok := app.Annotations[PromoteAnnotation]; ok {
+		// Patch会用服务端返回的对象覆盖app，保留本轮计算出的状态
+		status := app.Status.DeepCopy()
+		patch := client.MergeFrom(app.DeepCopy())
+		delete(app.Annotations, PromoteAnnotation)
+		if err := r.Patch(ctx, app, patch); err != nil {
+			return result, err
+		}
+		app.Status = *status
+	}
+	result.requeueAfter = time.Second
+	return result, nil
+}
+
+// finishBlueGreen 在稳定版本的Pod模板已经与期望一致时收尾蓝绿发布：
+// 稳定版本完成滚动后主Service切换回稳定版本，稍后再删除绿色版本
+func (r *LSTMPredictAppReconciler) finishBlueGreen(ctx context.Context, app *lstmappsv1.LSTMPredictApp, stable *appsv1.Deployment) (time.Duration, error) {
+	st := app.Status.Rollout
+	switch st.Phase {
+	case lstmappsv1.RolloutPhasePromoting:
+		if !deploymentRolledOut(stable, desiredReplicas(app)) {
+			return rolloutPollInterval, nil
+		}
+		// 先把流量切回稳定版本，下一轮再删除绿色版本
+		st.Phase = lstmappsv1.RolloutPhasePromoted
+		st.CurrentWeight = 0
+		st.Message = "the new revision has been promoted"
+		log.FromContext(ctx).Info("Blue/green rollout has been promoted.", "revision", st.Revision)
+		return blueGreenScaleDownDelay, nil
+	case lstmappsv1.RolloutPhaseProgressing:
+		st.Phase = lstmappsv1.RolloutPhaseAborted
+		st.Message = "rollout cancelled because the stable revision already matches the spec"
+	}
+	return 0, r.deleteRolloutDeployment(ctx, app, greenName(app))
+}
+
+// reconcilePreviewService 蓝绿发布进行中时维护暴露绿色版本的预览Service，其他情况下删除。
+// 同名的Service不受该应用控制时不会修改或删除它，更新时返回applyConflictError
+func (r *LSTMPredictAppReconciler) reconcilePreviewService(ctx context.Context, app *lstmappsv1.LSTMPredictApp) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	st := app.Status.Rollout
+	needed := st != nil && st.Strategy == lstmappsv1.RolloutStrategyBlueGreen &&
+		(st.Phase == lstmappsv1.RolloutPhaseProgressing || st.Phase == lstmappsv1.RolloutPhasePromoting)
+
+	if !needed {
+		if err := r.deleteOwned(ctx, app, &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: previewName(app)}}); err != nil {
+			log.Error(err, "Failed to delete preview Service, will requeue after a short time.")
+			return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	existing := &corev1.Service{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: app.Namespace, Name: previewName(app)}, existing)
+	if err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "Failed to get preview Service, will requeue after a short time.")
+		return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+	}
+	if err == nil && !metav1.IsControlledBy(existing, app) {
+		return ctrl.Result{}, notControlledError(app, "Service", previewName(app))
+	}
+
+	// 以服务端应用的方式创建或更新预览Service，与主Service一样只写入控制器负责的字段
+	if err := r.applyOwned(ctx, app, previewServiceForApply(app), false); err != nil {
+		if isApplyConflict(err) {
+			return ctrl.Result{}, err
+		}
+		log.Error(err, "Failed to apply preview Service, will requeue, after a short time.")
+		return ctrl.Result{RequeueAfter: GenericRequeueDuration}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// previewServiceForApply 构造预览Service中控制器负责的字段：标签、类型、指向绿色版本的选择器，以及与主Service相同的全部端口
+func previewServiceForApply(app *lstmappsv1.LSTMPredictApp) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      previewName(app),
+			Namespace: app.Namespace,
+			Labels:    app.Labels,
+		},
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceTypeClusterIP,
+			Selector: greenLabels(app),
+			Ports:    servicePorts(app),
+		},
+	}
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/config"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+var _ = Describe("Blue/green rollout", func() {
+	const resourceName = "bluegreen-app"
+
+	ctx := context.Background()
+	typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+	greenNamespacedName := types.NamespacedName{Name: resourceName + "-green", Namespace: "default"}
+	previewNamespacedName := types.NamespacedName{Name: resourceName + "-preview", Namespace: "default"}
+
+	var controllerReconciler *LSTMPredictAppReconciler
+
+	reconcileOnce := func() {
+		_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		controllerReconciler = &LSTMPredictAppReconciler{
+			Client: k8sClient,
+			Scheme: k8sClient.Scheme(),
+		}
+
+		By("creating a LSTMPredictApp using the blue/green strategy")
+		app := &lstmappsv1.LSTMPredictApp{
+			ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+			Spec: lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				ContainerPort:      8080,
+				BackendAppReplicas: ptr.To[int32](2),
+				ServicePort:        80,
+				ServiceType:        corev1.ServiceTypeClusterIP,
+				Rollout:            &lstmappsv1.RolloutSpec{Strategy: lstmappsv1.RolloutStrategyBlueGreen},
+			},
+		}
+		Expect(k8sClient.Create(ctx, app)).To(Succeed())
+		reconcileOnce()
+
+		By("changing the image to start a blue/green rollout")
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		app.Spec.AppImage = "lstm-predict-server:v2.0"
+		Expect(k8sClient.Update(ctx, app)).To(Succeed())
+		reconcileOnce()
+	})
+
+	AfterEach(func() {
+		deleteLSTMPredictApp(ctx, typeNamespacedName)
+		// envtest中没有垃圾回收，手动删除子资源
+		for _, name := range []types.NamespacedName{typeNamespacedName, greenNamespacedName} {
+			dp := &appsv1.Deployment{}
+			if err := k8sClient.Get(ctx, name, dp); err == nil {
+				Expect(k8sClient.Delete(ctx, dp)).To(Succeed())
+			}
+		}
+		for _, name := range []types.NamespacedName{typeNamespacedName, previewNamespacedName} {
+			svc := &corev1.Service{}
+			if err := k8sClient.Get(ctx, name, svc); err == nil {
+				Expect(k8sClient.Delete(ctx, svc)).To(Succeed())
+			}
+		}
+	})
+
+	It("should bring up a full green set behind a preview Service", func() {
+		green := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, greenNamespacedName, green)).To(Succeed())
+		Expect(*green.Spec.Replicas).To(Equal(int32(2)))
+		Expect(green.Spec.Template.Spec.Containers[0].Image).To(Equal("lstm-predict-server:v2.0"))
+
+		stable := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, stable)).To(Succeed())
+		Expect(stable.Spec.Template.Spec.Containers[0].Image).To(Equal("lstm-predict-server:v1.0"))
+
+		preview := &corev1.Service{}
+		Expect(k8sClient.Get(ctx, previewNamespacedName, preview)).To(Succeed())
+		Expect(preview.Spec.Selector).To(Equal(green.Spec.Selector.MatchLabels))
+
+		svc := &corev1.Service{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, svc)).To(Succeed())
+		Expect(svc.Spec.Selector).To(Equal(map[string]string{"app": resourceName}))
+	})
+
+	It("should switch the Service to green when promoted by annotation", func() {
+		By("marking the green replicas as ready")
+		green := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, greenNamespacedName, green)).To(Succeed())
+		green.Status = appsv1.DeploymentStatus{
+			ObservedGeneration: green.Generation,
+			Replicas:           2,
+			UpdatedReplicas:    2,
+			ReadyReplicas:      2,
+		}
+		Expect(k8sClient.Status().Update(ctx, green)).To(Succeed())
+
+		By("annotating the LSTMPredictApp for promotion")
+		app := &lstmappsv1.LSTMPredictApp{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		app.Annotations = map[string]string{PromoteAnnotation: "true"}
+		Expect(k8sClient.Update(ctx, app)).To(Succeed())
+		reconcileOnce()
+
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		Expect(app.Status.Rollout.Phase).To(Equal(lstmappsv1.RolloutPhasePromoting))
+		Expect(app.Annotations).NotTo(HaveKey(PromoteAnnotation))
+
+		svc := &corev1.Service{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, svc)).To(Succeed())
+		Expect(svc.Spec.Selector).To(Equal(green.Spec.Selector.MatchLabels))
+
+		By("updating the stable Deployment on the next reconcile")
+		reconcileOnce()
+		stable := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, stable)).To(Succeed())
+		Expect(stable.Spec.Template.Spec.Containers[0].Image).To(Equal("lstm-predict-server:v2.0"))
+	})
+
+	It("should promote as soon as the annotation is added, without a spec change", Label("manager"), func() {
+		By("marking the green replicas as ready")
+		green := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, greenNamespacedName, green)).To(Succeed())
+		green.Status = appsv1.DeploymentStatus{
+			ObservedGeneration: green.Generation,
+			Replicas:           2,
+			UpdatedReplicas:    2,
+			ReadyReplicas:      2,
+		}
+		Expect(k8sClient.Status().Update(ctx, green)).To(Succeed())
+
+		By("running the controller with its watches and predicates")
+		mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+			Scheme:     k8sClient.Scheme(),
+			Metrics:    metricsserver.Options{BindAddress: "0"},
+			Controller: config.Controller{SkipNameValidation: ptr.To(true)},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect((&LSTMPredictAppReconciler{
+			Client:   mgr.GetClient(),
+			Scheme:   mgr.GetScheme(),
+			Recorder: mgr.GetEventRecorderFor("lstmpredictapp-controller"),
+		}).SetupWithManager(mgr)).To(Succeed())
+		mgrCtx, stop := context.WithCancel(ctx)
+		defer stop()
+		go func() {
+			defer GinkgoRecover()
+			Expect(mgr.Start(mgrCtx)).To(Succeed())
+		}()
+
+		By("waiting for the green revision to be exposed by the preview Service only")
+		app := &lstmappsv1.LSTMPredictApp{}
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+			g.Expect(app.Status.Rollout).NotTo(BeNil())
+			g.Expect(app.Status.Rollout.Message).To(ContainSubstring("waiting for promotion"))
+		}, 10*time.Second, 100*time.Millisecond).Should(Succeed())
+
+		By("only annotating the LSTMPredictApp")
+		patch := client.MergeFrom(app.DeepCopy())
+		app.Annotations = map[string]string{PromoteAnnotation: "true"}
+		Expect(k8sClient.Patch(ctx, app, patch)).To(Succeed())
+
+		Eventually(func(g Gomega) {
+			svc := &corev1.Service{}
+			g.Expect(k8sClient.Get(ctx, typeNamespacedName, svc)).To(Succeed())
+			g.Expect(svc.Spec.Selector).To(Equal(green.Spec.Selector.MatchLabels))
+		}, 10*time.Second, 100*time.Millisecond).Should(Succeed())
+	})
+
+	It("should neither update nor delete a preview Service it does not control", func() {
+		By("replacing the preview Service with one created by the user")
+		preview := &corev1.Service{}
+		Expect(k8sClient.Get(ctx, previewNamespacedName, preview)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, preview)).To(Succeed())
+		userSelector := map[string]string{"app": "user-preview"}
+		Expect(k8sClient.Create(ctx, &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: previewNamespacedName.Name, Namespace: "default"},
+			Spec: corev1.ServiceSpec{
+				Selector: userSelector,
+				Ports:    []corev1.ServicePort{{Name: "http", Port: 9090}},
+			},
+		})).To(Succeed())
+		reconcileOnce()
+
+		preview = &corev1.Service{}
+		Expect(k8sClient.Get(ctx, previewNamespacedName, preview)).To(Succeed())
+		Expect(preview.Spec.Selector).To(Equal(userSelector))
+		app := &lstmappsv1.LSTMPredictApp{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		condition := meta.FindStatusCondition(app.Status.Conditions, lstmappsv1.ConditionTypeSynced)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Message).To(ContainSubstring("not controlled by LSTMPredictApp"))
+
+		By("cancelling the rollout by restoring the stable image")
+		app.Spec.AppImage = "lstm-predict-server:v1.0"
+		Expect(k8sClient.Update(ctx, app)).To(Succeed())
+		reconcileOnce()
+		Expect(k8sClient.Get(ctx, previewNamespacedName, &corev1.Service{})).To(Succeed())
+	})
+})
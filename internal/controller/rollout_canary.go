@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// TrackCanary 金丝雀Pod的track标签值
+const TrackCanary = "canary"
+
+// canaryName 返回金丝雀Deployment的名字
+func canaryName(app *lstmappsv1.LSTMPredictApp) string {
+	return app.Name + "-canary"
+}
+
+// isCanaryRollout 判断LSTMPredictApp是否使用金丝雀发布
+func isCanaryRollout(app *lstmappsv1.LSTMPredictApp) bool {
+	return app.Spec.Rollout != nil && app.Spec.Rollout.Strategy == lstmappsv1.RolloutStrategyCanary &&
+		app.Spec.Rollout.Canary != nil && len(app.Spec.Rollout.Canary.Steps) > 0
+}
+
+// reconcileCanary 在稳定版本的Pod模板与期望的Pod模板不一致时推进金丝雀发布：
+// 创建或更新金丝雀Deployment，按步骤提升权重，金丝雀不健康或指标超过阈值时中止，全部步骤通过后全量发布
+func (r *LSTMPredictAppReconciler) reconcileCanary(ctx context.Context, app *lstmappsv1.LSTMPredictApp, desired *corev1.PodTemplateSpec) (rolloutResult, error) {
+	log := log.FromContext(ctx)
+
+	replicas := desiredReplicas(app)
+	steps := app.Spec.Rollout.Canary.Steps
+	revision := computeTemplateHash(desired)
+	now := metav1.Now()
+
+	st := app.Status.Rollout
+	// 该版本已经被中止，保持稳定版本，直到Pod模板再次发生变化
+	if sameRollout(st, lstmappsv1.RolloutStrategyCanary, revision, lstmappsv1.RolloutPhaseAborted) {
+		return rolloutResult{stableReplicas: replicas}, r.deleteRolloutDeployment(ctx, app, canaryName(app))
+	}
+	if sameRollout(st, lstmappsv1.RolloutStrategyCanary, revision, lstmappsv1.RolloutPhasePromoting) {
+		return rolloutResult{stableReplicas: replicas, promote: true}, nil
+	}
+	// 新的版本，从第一步开始
+	if st == nil || st.Revision != revision || st.Strategy != lstmappsv1.RolloutStrategyCanary ||
+		st.Phase != lstmappsv1.RolloutPhaseProgressing {
+		log.Info("Start canary rollout.", "revision", revision)
+		// 切换了发布策略时，清理上一次蓝绿发布留下的绿色版本
+		if err := r.deleteRolloutDeployment(ctx, app, greenName(app)); err != nil {
+			return rolloutResult{stableReplicas: replicas}, err
+		}
+		st = &lstmappsv1.RolloutStatus{
+			Strategy:      lstmappsv1.RolloutStrategyCanary,
+			Phase:         lstmappsv1.RolloutPhaseProgressing,
+			Revision:      revision,
+			StepStartTime: &now,
+			Steps:         []lstmappsv1.RolloutStepStatus{{Step: 0, Weight: steps[0].Weight, StartTime: now, Result: "Running"}},
+		}
+		app.Status.Rollout = st
+	}
+	if int(st.CurrentStep) >= len(steps) {
+		st.CurrentStep = int32(len(steps) - 1)
+	}
+	step := steps[st.CurrentStep]
+
+	// 按权重计算金丝雀与稳定版本的副本数，权重小于100时稳定版本至少保留一个副本
+	canaryReplicas := (replicas*step.Weight + 99) / 100
+	if canaryReplicas < 1 {
+		canaryReplicas = 1
+	}
+	stableReplicas := replicas - canaryReplicas
+	if step.Weight < 100 && stableReplicas < 1 {
+		stableReplicas = 1
+	}
+	st.CurrentWeight = canaryReplicas * 100 / (canaryReplicas + stableReplicas)
+	result := rolloutResult{stableReplicas: stableReplicas}
+
+	template := desired.DeepCopy()
+	if template.Labels == nil {
+		template.Labels = map[string]string{}
+	}
+	template.Labels[TrackLabel] = TrackCanary
+	canary, err := r.applyRolloutDeployment(ctx, app, canaryName(app),
+		map[string]string{"app": app.Name, TrackLabel: TrackCanary}, template, revision, canaryReplicas)
+	if err != nil {
+		return result, err
+	}
+
+	// 金丝雀无法就绪，中止发布
+	if exceeded, message := deploymentDeadlineExceeded(canary); exceeded {
+		return r.abortRollout(ctx, app, canaryName(app), fmt.Sprintf("canary failed to become ready: %s", message))
+	}
+	if canary.Status.ObservedGeneration < canary.Generation || !deploymentRolledOut(canary, canaryReplicas) {
+		st.Message = fmt.Sprintf("waiting for canary replicas to become ready (%d/%d)", canary.Status.ReadyReplicas, canaryReplicas)
+		result.requeueAfter = rolloutPollInterval
+		return result, nil
+	}
+
+	// 指标分析，查询失败时不做判断，等待下一次检查
+	if analysis := app.Spec.Rollout.Canary.Analysis; analysis != nil {
+		threshold, err := strconv.ParseFloat(analysis.Threshold, 64)
+		if err != nil {
+			return r.abortRollout(ctx, app, canaryName(app), fmt.Sprintf("analysis threshold %q is illegal", analysis.Threshold))
+		}
+		value, err := r.metricProvider().Query(ctx, analysis.PrometheusURL, analysis.Query)
+		if err != nil {
+			log.Error(err, "Failed to query canary analysis metric, will retry.")
+			st.Message = fmt.Sprintf("failed to query analysis metric: %v", err)
+			result.requeueAfter = rolloutPollInterval
+			return result, nil
+		}
+		if value > threshold {
+			return r.abortRollout(ctx, app, canaryName(app), fmt.Sprintf("analysis metric %v exceeded threshold %v", value, threshold))
+		}
+	}
+
+	// 在当前权重上停留足够的时间后进入下一步
+	if elapsed := time.Since(st.StepStartTime.Time); elapsed < step.Pause.Duration {
+		st.Message = fmt.Sprintf("canary is healthy at weight %d%%, pausing", st.CurrentWeight)
+		result.requeueAfter = step.Pause.Duration - elapsed
+		return result, nil
+	}
+	st.Steps[len(st.Steps)-1].Result = "Passed"
+	if int(st.CurrentStep)+1 < len(steps) {
+		st.CurrentStep++
+		st.StepStartTime = &now
+		st.Steps = append(st.Steps, lstmappsv1.RolloutStepStatus{
+			Step: st.CurrentStep, Weight: steps[st.CurrentStep].Weight, StartTime: now, Result: "Running",
+		})
+		st.Message = fmt.Sprintf("advanced to step %d", st.CurrentStep)
+		log.Info("Canary advanced to the next step.", "step", st.CurrentStep, "weight", steps[st.CurrentStep].Weight)
+		result.requeueAfter = time.Second
+		return result, nil
+	}
+
+	// 所有步骤都已通过，全量发布新版本，金丝雀等稳定版本完成滚动后再删除
+	log.Info("All canary steps passed, promoting.", "revision", revision)
+	st.Phase = lstmappsv1.RolloutPhasePromoting
+	st.Message = "all canary steps passed, promoting the new revision"
+	return rolloutResult{stableReplicas: replicas, promote: true}, nil
+}
+
+// finishCanary 在稳定版本的Pod模板已经与期望一致时清理金丝雀：
+// 全量发布中时等稳定版本完成滚动后再删除金丝雀，其他情况（Spec被改回、切换了发布策略）直接删除
+func (r *LSTMPredictAppReconciler) finishCanary(ctx context.Context, app *lstmappsv1.LSTMPredictApp, stable *appsv1.Deployment) (time.Duration, error) {
+	st := app.Status.Rollout
+	switch st.Phase {
+	case lstmappsv1.RolloutPhasePromoting:
+		if !deploymentRolledOut(stable, desiredReplicas(app)) {
+			return rolloutPollInterval, nil
+		}
+		st.Phase = lstmappsv1.RolloutPhasePromoted
+		st.Message = "the new revision has been promoted"
+		log.FromContext(ctx).Info("Canary rollout has been promoted.", "revision", st.Revision)
+	case lstmappsv1.RolloutPhaseProgressing:
+		st.Phase = lstmappsv1.RolloutPhaseAborted
+		st.Message = "rollout cancelled because the stable revision already matches the spec"
+	}
+	return 0, r.deleteRolloutDeployment(ctx, app, canaryName(app))
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+// staticMetricProvider 返回固定数值的MetricProvider
+type staticMetricProvider struct {
+	value float64
+}
+
+func (p *staticMetricProvider) Query(_ context.Context, _, _ string) (float64, error) {
+	return p.value, nil
+}
+
+var _ = Describe("Canary rollout", func() {
+	const resourceName = "canary-app"
+
+	ctx := context.Background()
+	typeNamespacedName := types.NamespacedName{Name: resourceName, Namespace: "default"}
+	canaryNamespacedName := types.NamespacedName{Name: resourceName + "-canary", Namespace: "default"}
+
+	var (
+		controllerReconciler *LSTMPredictAppReconciler
+		metrics              *staticMetricProvider
+	)
+
+	reconcileOnce := func() {
+		_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		metrics = &staticMetricProvider{}
+		controllerReconciler = &LSTMPredictAppReconciler{
+			Client:         k8sClient,
+			Scheme:         k8sClient.Scheme(),
+			MetricProvider: metrics,
+		}
+
+		By("creating a LSTMPredictApp using the canary strategy")
+		app := &lstmappsv1.LSTMPredictApp{
+			ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+			Spec: lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				ContainerPort:      8080,
+				BackendAppReplicas: ptr.To[int32](4),
+				ServicePort:        80,
+				ServiceType:        corev1.ServiceTypeClusterIP,
+				Rollout: &lstmappsv1.RolloutSpec{
+					Strategy: lstmappsv1.RolloutStrategyCanary,
+					Canary: &lstmappsv1.CanaryStrategy{
+						Steps: []lstmappsv1.CanaryStep{{Weight: 25}, {Weight: 50}},
+						Analysis: &lstmappsv1.CanaryAnalysis{
+							PrometheusURL: "http://prometheus.monitoring.svc:9090",
+							Query:         "canary_error_rate",
+							Threshold:     "0.05",
+						},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, app)).To(Succeed())
+		reconcileOnce()
+
+		By("changing the image to start a canary rollout")
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		app.Spec.AppImage = "lstm-predict-server:v2.0"
+		Expect(k8sClient.Update(ctx, app)).To(Succeed())
+		reconcileOnce()
+	})
+
+	AfterEach(func() {
+		deleteLSTMPredictApp(ctx, typeNamespacedName)
+		// envtest中没有垃圾回收，手动删除子资源
+		for _, name := range []types.NamespacedName{typeNamespacedName, canaryNamespacedName} {
+			dp := &appsv1.Deployment{}
+			if err := k8sClient.Get(ctx, name, dp); err == nil {
+				Expect(k8sClient.Delete(ctx, dp)).To(Succeed())
+			}
+		}
+		svc := &corev1.Service{}
+		if err := k8sClient.Get(ctx, typeNamespacedName, svc); err == nil {
+			Expect(k8sClient.Delete(ctx, svc)).To(Succeed())
+		}
+	})
+
+	It("should move the first step of traffic to the canary and keep the stable template", func() {
+		stable := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, stable)).To(Succeed())
+		Expect(stable.Spec.Template.Spec.Containers[0].Image).To(Equal("lstm-predict-server:v1.0"))
+		Expect(*stable.Spec.Replicas).To(Equal(int32(3)))
+
+		canary := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, canaryNamespacedName, canary)).To(Succeed())
+		Expect(canary.Spec.Template.Spec.Containers[0].Image).To(Equal("lstm-predict-server:v2.0"))
+		Expect(canary.Spec.Template.Labels).To(HaveKeyWithValue(TrackLabel, TrackCanary))
+		Expect(*canary.Spec.Replicas).To(Equal(int32(1)))
+
+		app := &lstmappsv1.LSTMPredictApp{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		Expect(app.Status.Rollout).NotTo(BeNil())
+		Expect(app.Status.Rollout.Phase).To(Equal(lstmappsv1.RolloutPhaseProgressing))
+		Expect(app.Status.Rollout.CurrentWeight).To(Equal(int32(25)))
+	})
+
+	It("should abort and remove the canary when the analysis exceeds the threshold", func() {
+		By("marking the canary replicas as ready")
+		canary := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, canaryNamespacedName, canary)).To(Succeed())
+		canary.Status = appsv1.DeploymentStatus{
+			ObservedGeneration: canary.Generation,
+			Replicas:           1,
+			UpdatedReplicas:    1,
+			ReadyReplicas:      1,
+		}
+		Expect(k8sClient.Status().Update(ctx, canary)).To(Succeed())
+
+		metrics.value = 0.2
+		reconcileOnce()
+
+		app := &lstmappsv1.LSTMPredictApp{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		Expect(app.Status.Rollout.Phase).To(Equal(lstmappsv1.RolloutPhaseAborted))
+
+		err := k8sClient.Get(ctx, canaryNamespacedName, &appsv1.Deployment{})
+		Expect(errors.IsNotFound(err)).To(BeTrue())
+		stable := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, stable)).To(Succeed())
+		Expect(*stable.Spec.Replicas).To(Equal(int32(4)))
+		Expect(stable.Spec.Template.Spec.Containers[0].Image).To(Equal("lstm-predict-server:v1.0"))
+	})
+
+	It("should not take over a canary Deployment it does not control", func() {
+		By("replacing the canary with a Deployment created by the user")
+		canary := &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, canaryNamespacedName, canary)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, canary)).To(Succeed())
+		labels := map[string]string{"app": "user-canary"}
+		Expect(k8sClient.Create(ctx, &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: canaryNamespacedName.Name, Namespace: "default"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: ptr.To[int32](2),
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: labels},
+					Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "user", Image: "user-image:v1"}}},
+				},
+			},
+		})).To(Succeed())
+		reconcileOnce()
+
+		canary = &appsv1.Deployment{}
+		Expect(k8sClient.Get(ctx, canaryNamespacedName, canary)).To(Succeed())
+		Expect(canary.Spec.Template.Spec.Containers[0].Image).To(Equal("user-image:v1"))
+		Expect(*canary.Spec.Replicas).To(Equal(int32(2)))
+
+		app := &lstmappsv1.LSTMPredictApp{}
+		Expect(k8sClient.Get(ctx, typeNamespacedName, app)).To(Succeed())
+		condition := meta.FindStatusCondition(app.Status.Conditions, lstmappsv1.ConditionTypeSynced)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(ReasonApplyConflict))
+		Expect(condition.Message).To(ContainSubstring("not controlled by LSTMPredictApp"))
+	})
+})
@@ -0,0 +1,302 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+// ImagePolicyOptions 通过命令行参数设置的镜像策略
+type ImagePolicyOptions struct {
+	// 允许使用的镜像仓库前缀，为空时不限制；LSTMOperatorPolicy中设置了allowedImageRegistries时以策略为准
+	AllowedRegistries []string
+	// 禁止使用latest标签或者不带标签的镜像
+	ForbidMutableTags bool
+	// 要求镜像以digest固定
+	RequireDigest bool
+	// 准入时将镜像标签解析为digest，使Deployment固定使用解析时的镜像
+	ResolveDigests bool
+	// 通过HTTP而不是HTTPS访问的仓库地址，如本地的测试仓库localhost:5000
+	PlainHTTPRegistries []string
+}
+
+// imageReference 镜像引用的各个部分
+type imageReference struct {
+	// 仓库地址，不带仓库地址的镜像为docker.io
+	Registry string
+	// 仓库中的路径
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// parseImageReference 解析镜像引用，不校验各部分的格式
+func parseImageReference(image string) imageReference {
+	ref := imageReference{}
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.Digest = name[i+1:]
+		name = name[:i]
+	}
+	// 最后一个'/'之后的':'是tag，之前的':'是仓库地址中的端口
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		ref.Tag = name[i+1:]
+		name = name[:i]
+	}
+	first, rest, found := strings.Cut(name, "/")
+	if !found || (!strings.ContainsAny(first, ".:") && first != "localhost") {
+		ref.Registry, ref.Repository = "docker.io", name
+	} else {
+		ref.Registry, ref.Repository = first, rest
+	}
+	return ref
+}
+
+// imageRegistryPath 返回镜像去掉tag与digest后带仓库地址的路径，不带仓库地址的镜像按docker.io处理
+func imageRegistryPath(image string) string {
+	ref := parseImageReference(image)
+	return ref.Registry + "/" + ref.Repository
+}
+
+// validateImageRegistry 校验镜像来自允许的仓库前缀，allowed为空时不限制
+func validateImageRegistry(field, image string, allowed []string) error {
+	if len(allowed) == 0 {
+		return nil
+	}
+	path := imageRegistryPath(image)
+	for _, prefix := range allowed {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s %q is not from an allowed registry, should be in {%s}", field, image, strings.Join(allowed, "/"))
+}
+
+var imageDigestRegexp = regexp.MustCompile(`^(sha256:[a-f0-9]{64}|sha512:[a-f0-9]{128})$`)
+
+// validateImageTag 校验镜像的标签与digest；以digest固定的镜像拉取的内容不会变化，不再检查标签
+func validateImageTag(field, image string, forbidMutableTags, requireDigest bool) error {
+	ref := parseImageReference(image)
+	if ref.Digest != "" {
+		if !imageDigestRegexp.MatchString(ref.Digest) {
+			return fmt.Errorf("%s %q is illeagle, the digest need to be like sha256:<64 hex characters>", field, image)
+		}
+		return nil
+	}
+	if requireDigest {
+		return fmt.Errorf("%s %q must be pinned by digest, like %s@sha256:<digest>", field, image, image)
+	}
+	if forbidMutableTags && (ref.Tag == "" || ref.Tag == "latest") {
+		return fmt.Errorf("%s %q uses a mutable tag, need an explicit version tag other than latest or a digest", field, image)
+	}
+	return nil
+}
+
+// appImage LSTMPredictApp中的一个容器镜像字段
+type appImage struct {
+	field string
+	image *string
+}
+
+// appImages 返回LSTMPredictApp中设置了的全部容器镜像，包括拉取模型的Init容器镜像
+func appImages(lstmpredictapp *lstmappsv1.LSTMPredictApp) []appImage {
+	images := []appImage{{field: "AppImage", image: &lstmpredictapp.Spec.AppImage}}
+	if model := lstmpredictapp.Spec.Model; model != nil {
+		if model.OCI != nil && model.OCI.PullerImage != "" {
+			images = append(images, appImage{field: "Model.OCI.PullerImage", image: &model.OCI.PullerImage})
+		}
+		if model.HTTP != nil && model.HTTP.DownloaderImage != "" {
+			images = append(images, appImage{field: "Model.HTTP.DownloaderImage", image: &model.HTTP.DownloaderImage})
+		}
+	}
+	return images
+}
+
+// validateImages 按镜像策略校验全部容器镜像；更新时跳过没有变化的镜像，收紧策略后已有的应用仍然可以修改其他字段
+func (v *LSTMPredictAppCustomValidator) validateImages(lstmpredictapp, old *lstmappsv1.LSTMPredictApp) error {
+	unchanged := map[string]string{}
+	if old != nil {
+		for _, image := range appImages(old) {
+			unchanged[image.field] = *image.image
+		}
+	}
+	for _, image := range appImages(lstmpredictapp) {
+		if previous, ok := unchanged[image.field]; ok && previous == *image.image {
+			continue
+		}
+		if err := validateImageRegistry(image.field, *image.image, v.AllowedImageRegistries); err != nil {
+			return err
+		}
+		if err := validateImageTag(image.field, *image.image, v.ForbidMutableImageTags, v.RequireImageDigest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImageDigestResolver 将镜像标签解析为仓库中当前的digest
+type ImageDigestResolver interface {
+	// Resolve 返回image当前指向的digest，如sha256:...
+	Resolve(ctx context.Context, image string) (string, error)
+}
+
+// RegistryDigestResolver 通过OCI Distribution API查询镜像的digest，需要认证时使用匿名的Bearer Token
+type RegistryDigestResolver struct {
+	Client *http.Client
+	// 通过HTTP访问的仓库地址
+	PlainHTTPRegistries []string
+}
+
+var _ ImageDigestResolver = &RegistryDigestResolver{}
+
+// 默认的仓库请求超时时间，解析一次最多发出三个请求，需要在Webhook的10秒超时之内完成
+const defaultRegistryTimeout = 3 * time.Second
+
+// 依次接受多架构的镜像索引与单架构的镜像清单，多架构镜像解析为索引的digest
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+func (r *RegistryDigestResolver) Resolve(ctx context.Context, image string) (string, error) {
+	ref := parseImageReference(image)
+	if ref.Digest != "" {
+		return ref.Digest, nil
+	}
+	tag := ref.Tag
+	if tag == "" {
+		tag = "latest"
+	}
+	host, repository := ref.Registry, ref.Repository
+	if host == "docker.io" {
+		host = "registry-1.docker.io"
+		if !strings.Contains(repository, "/") {
+			repository = "library/" + repository
+		}
+	}
+	scheme := "https"
+	if slices.Contains(r.PlainHTTPRegistries, ref.Registry) {
+		scheme = "http"
+	}
+	manifestURL := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", scheme, host, repository, tag)
+
+	resp, err := r.do(ctx, http.MethodHead, manifestURL, "")
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		token, err := r.token(ctx, resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return "", fmt.Errorf("failed to authenticate to %s: %w", host, err)
+		}
+		if resp, err = r.do(ctx, http.MethodHead, manifestURL, token); err != nil {
+			return "", err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry %s returned %s for %s", host, resp.Status, image)
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if !imageDigestRegexp.MatchString(digest) {
+		return "", fmt.Errorf("registry %s returned an invalid digest %q for %s", host, digest, image)
+	}
+	return digest, nil
+}
+
+func (r *RegistryDigestResolver) do(ctx context.Context, method, u, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	httpClient := r.Client
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultRegistryTimeout}
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	// 只使用响应头，调用方无需关闭Body
+	resp.Body.Close() //nolint:errcheck
+	return resp, nil
+}
+
+var challengeParamRegexp = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// token 按WWW-Authenticate中的Bearer质询获取匿名Token
+func (r *RegistryDigestResolver) token(ctx context.Context, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+	values := url.Values{}
+	realm := ""
+	for _, match := range challengeParamRegexp.FindAllStringSubmatch(params, -1) {
+		if match[1] == "realm" {
+			realm = match[2]
+		} else {
+			values.Set(match[1], match[2])
+		}
+	}
+	if realm == "" {
+		return "", fmt.Errorf("authentication challenge %q has no realm", challenge)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm+"?"+values.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	httpClient := r.Client
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultRegistryTimeout}
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint %s returned %s", realm, resp.Status)
+	}
+	body := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode token from %s: %w", realm, err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
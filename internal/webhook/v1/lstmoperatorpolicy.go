@@ -0,0 +1,185 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+// Webhook在准入时读取LSTMOperatorPolicy，修改策略无需重新构建Operator
+// +kubebuilder:rbac:groups=lstmapps.wuyong7240.com,resources=lstmoperatorpolicies,verbs=get;list;watch
+
+// effectivePolicy 合并对namespace生效的全部LSTMOperatorPolicy：先合并集群默认策略，再合并列出了该命名空间的策略，
+// 同一层按名字顺序合并，后者覆盖前者；reader为空时返回空策略，即只使用内置的默认值
+func effectivePolicy(ctx context.Context, reader client.Reader, namespace string) (*lstmappsv1.LSTMOperatorPolicySpec, error) {
+	policy := &lstmappsv1.LSTMOperatorPolicySpec{}
+	if reader == nil {
+		return policy, nil
+	}
+	list := &lstmappsv1.LSTMOperatorPolicyList{}
+	if err := reader.List(ctx, list); err != nil {
+		return nil, fmt.Errorf("failed to list LSTMOperatorPolicies: %w", err)
+	}
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].Name < list.Items[j].Name })
+	for _, item := range list.Items {
+		if len(item.Spec.Namespaces) == 0 {
+			mergePolicy(policy, &item.Spec)
+		}
+	}
+	for _, item := range list.Items {
+		if slices.Contains(item.Spec.Namespaces, namespace) {
+			mergePolicy(policy, &item.Spec)
+		}
+	}
+	return policy, nil
+}
+
+// mergePolicy 用src中设置了的字段覆盖dst
+func mergePolicy(dst, src *lstmappsv1.LSTMOperatorPolicySpec) {
+	if src.MinReplicas != nil {
+		dst.MinReplicas = src.MinReplicas
+	}
+	if src.MaxReplicas != nil {
+		dst.MaxReplicas = src.MaxReplicas
+	}
+	if src.DefaultReplicas != nil {
+		dst.DefaultReplicas = src.DefaultReplicas
+	}
+	if src.DefaultServicePort != nil {
+		dst.DefaultServicePort = src.DefaultServicePort
+	}
+	if src.MaxServicePort != nil {
+		dst.MaxServicePort = src.MaxServicePort
+	}
+	if src.DefaultServiceType != "" {
+		dst.DefaultServiceType = src.DefaultServiceType
+	}
+	if len(src.AllowedServiceTypes) > 0 {
+		dst.AllowedServiceTypes = src.AllowedServiceTypes
+	}
+	if len(src.AllowedImageRegistries) > 0 {
+		dst.AllowedImageRegistries = src.AllowedImageRegistries
+	}
+	if src.DefaultResources != nil {
+		dst.DefaultResources = src.DefaultResources
+	}
+	if len(src.MaxResources) > 0 {
+		dst.MaxResources = src.MaxResources
+	}
+	if len(src.RequiredLabels) > 0 {
+		dst.RequiredLabels = src.RequiredLabels
+	}
+}
+
+// requestNamespace 返回LSTMPredictApp所在的命名空间，清单中没有写namespace时从准入请求中获取
+func requestNamespace(ctx context.Context, lstmpredictapp *lstmappsv1.LSTMPredictApp) string {
+	if lstmpredictapp.Namespace != "" {
+		return lstmpredictapp.Namespace
+	}
+	if req, err := admission.RequestFromContext(ctx); err == nil {
+		return req.Namespace
+	}
+	return ""
+}
+
+// forNamespace 返回按namespace生效的策略覆盖了内置默认值之后的Defaulter
+func (d *LSTMPredictAppCustomDefaulter) forNamespace(ctx context.Context, namespace string) (*LSTMPredictAppCustomDefaulter, error) {
+	policy, err := effectivePolicy(ctx, d.Client, namespace)
+	if err != nil {
+		return nil, err
+	}
+	effective := *d
+	if policy.DefaultReplicas != nil {
+		effective.DefaultBackendAppReplicas = *policy.DefaultReplicas
+	}
+	if policy.DefaultServicePort != nil {
+		effective.DefaultServicePort = *policy.DefaultServicePort
+	}
+	if policy.DefaultServiceType != "" {
+		effective.DefaultServiceType = string(policy.DefaultServiceType)
+	}
+	if policy.DefaultResources != nil {
+		effective.MinResourcesLimit = *policy.DefaultResources
+	}
+	return &effective, nil
+}
+
+// forNamespace 返回按namespace生效的策略覆盖了内置限制之后的Validator
+func (v *LSTMPredictAppCustomValidator) forNamespace(ctx context.Context, namespace string) (*LSTMPredictAppCustomValidator, error) {
+	policy, err := effectivePolicy(ctx, v.Client, namespace)
+	if err != nil {
+		return nil, err
+	}
+	effective := *v
+	if policy.MinReplicas != nil {
+		effective.MinBackendAppReplicas = *policy.MinReplicas
+	}
+	if policy.MaxReplicas != nil {
+		effective.MaxBackendAppReplicas = *policy.MaxReplicas
+		effective.MaxAutoscalingReplicas = *policy.MaxReplicas
+	}
+	if policy.MaxServicePort != nil {
+		effective.MaxPortID = *policy.MaxServicePort
+	}
+	if len(policy.AllowedServiceTypes) > 0 {
+		effective.AvailableServiceType = nil
+		for _, serviceType := range policy.AllowedServiceTypes {
+			effective.AvailableServiceType = append(effective.AvailableServiceType, string(serviceType))
+		}
+	}
+	if len(policy.AllowedImageRegistries) > 0 {
+		effective.AllowedImageRegistries = policy.AllowedImageRegistries
+	}
+	if len(policy.MaxResources) > 0 {
+		effective.MaxResources = policy.MaxResources
+	}
+	if len(policy.RequiredLabels) > 0 {
+		effective.RequiredLabels = policy.RequiredLabels
+	}
+	return &effective, nil
+}
+
+// validateMaxResources 校验每种资源的请求与限制都不超过max
+func validateMaxResources(resources corev1.ResourceRequirements, max corev1.ResourceList) error {
+	for name, limit := range max {
+		for kind, list := range map[string]corev1.ResourceList{"Requests": resources.Requests, "Limits": resources.Limits} {
+			if quantity, ok := list[name]; ok && quantity.Cmp(limit) > 0 {
+				return fmt.Errorf("ResourcesLimit.%s.%s can't > %s", kind, name, limit.String())
+			}
+		}
+	}
+	return nil
+}
+
+// validateRequiredLabels 校验LSTMPredictApp带有策略要求的全部标签
+func validateRequiredLabels(labels map[string]string, required []string) error {
+	for _, key := range required {
+		if labels[key] == "" {
+			return fmt.Errorf("label %q is required by the LSTMOperatorPolicy", key)
+		}
+	}
+	return nil
+}
@@ -19,15 +19,33 @@ package v1
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+	admissionv1 "k8s.io/api/admission/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
 	corev1 "k8s.io/api/core/v1"
 )
 
@@ -36,24 +54,51 @@ import (
 var lstmpredictapplog = logf.Log.WithName("lstmpredictapp-resource")
 
 // SetupLSTMPredictAppWebhookWithManager registers the webhook for LSTMPredictApp in the manager.
-func SetupLSTMPredictAppWebhookWithManager(mgr ctrl.Manager) error {
+func SetupLSTMPredictAppWebhookWithManager(mgr ctrl.Manager, imagePolicy ImagePolicyOptions) error {
+	validator := &LSTMPredictAppCustomValidator{
+		Client:                 mgr.GetClient(),
+		MaxBackendAppReplicas:  10,
+		MinBackendAppReplicas:  1,
+		MaxPortID:              30000,
+		AvailableServiceType:   []string{"ClusterIP", "NodePort", "LoadBalancer"},
+		AllowedImageRegistries: imagePolicy.AllowedRegistries,
+		ForbidMutableImageTags: imagePolicy.ForbidMutableTags,
+		RequireImageDigest:     imagePolicy.RequireDigest,
+	}
+	// 开启digest解析时由Defaulter将镜像标签替换为digest
+	var digestResolver ImageDigestResolver
+	if imagePolicy.ResolveDigests {
+		digestResolver = &RegistryDigestResolver{PlainHTTPRegistries: imagePolicy.PlainHTTPRegistries}
+	}
+	// 通过/scale子资源修改副本数时API Server发送的是Scale对象，需要单独的Webhook校验
+	mgr.GetWebhookServer().Register(ScaleValidatingPath, &webhook.Admission{Handler: &LSTMPredictAppScaleValidator{
+		Validator: validator,
+		Decoder:   admission.NewDecoder(mgr.GetScheme()),
+	}})
 	return ctrl.NewWebhookManagedBy(mgr).For(&lstmappsv1.LSTMPredictApp{}).
-		WithValidator(&LSTMPredictAppCustomValidator{
-			MaxBackendAppReplicas: 10,
-			MinBackendAppReplicas: 1,
-			MaxPortID:             30000,
-			AvailableServiceType:  []string{"ClusterIP", "NodePort"},
-		}).
+		WithValidator(validator).
 		WithDefaulter(&LSTMPredictAppCustomDefaulter{
-			DefaultBackendAppReplicas: 1,
-			DefaultServicePort:        8001,
-			DefaultServiceType:        "ClusterIP",
+			Client:                      mgr.GetClient(),
+			DefaultBackendAppReplicas:   1,
+			DefaultServicePort:          8001,
+			DefaultServiceType:          "ClusterIP",
+			DefaultModelMountPath:       "/models",
+			DefaultModelPullerImage:     "ghcr.io/oras-project/oras:v1.2.0",
+			DefaultModelDownloaderImage: "busybox:1.36",
+			DefaultMaxUnavailable:       intstr.FromString("25%"),
+			DefaultProbePath:            "/healthz",
+			DefaultMetricsPath:          "/metrics",
+			DefaultLivenessProbe:        lstmappsv1.ProbeTiming{PeriodSeconds: 10, TimeoutSeconds: 1, FailureThreshold: 3},
+			DefaultReadinessProbe:       lstmappsv1.ProbeTiming{PeriodSeconds: 5, TimeoutSeconds: 1, FailureThreshold: 3},
+			// 启动探针给模型加载留出10分钟
+			DefaultStartupProbe: lstmappsv1.ProbeTiming{PeriodSeconds: 10, TimeoutSeconds: 1, FailureThreshold: 60},
 			MinResourcesLimit: corev1.ResourceRequirements{
 				Requests: corev1.ResourceList{
 					corev1.ResourceCPU:    resource.MustParse("100m"),
 					corev1.ResourceMemory: resource.MustParse("128Mi"),
 				},
 			},
+			DigestResolver: digestResolver,
 		}).
 		Complete()
 }
@@ -69,16 +114,39 @@ func SetupLSTMPredictAppWebhookWithManager(mgr ctrl.Manager) error {
 // as it is used only for temporary operations and does not need to be deeply copied.
 type LSTMPredictAppCustomDefaulter struct {
 	// TODO(user): Add more fields as needed for defaulting
+	// 用于读取LSTMOperatorPolicy，为空时只使用以下内置的默认值
+	Client                    client.Reader
 	DefaultBackendAppReplicas int32
 	DefaultServicePort        int32
 	DefaultServiceType        string
 	MinResourcesLimit         corev1.ResourceRequirements
+	// 模型相关的默认值
+	DefaultModelMountPath       string
+	DefaultModelPullerImage     string
+	DefaultModelDownloaderImage string
+	// 副本数大于1时PodDisruptionBudget的默认值，按百分比计算，副本数变化后无需重新设置
+	DefaultMaxUnavailable intstr.IntOrString
+	// 探针相关的默认值
+	DefaultProbePath      string
+	DefaultLivenessProbe  lstmappsv1.ProbeTiming
+	DefaultReadinessProbe lstmappsv1.ProbeTiming
+	DefaultStartupProbe   lstmappsv1.ProbeTiming
+	// 开启监控时指标接口路径的默认值
+	DefaultMetricsPath string
+	// 将镜像标签解析为digest，为空时不解析
+	DigestResolver ImageDigestResolver
 }
 
 var _ webhook.CustomDefaulter = &LSTMPredictAppCustomDefaulter{}
 
+// isCreateRequest 判断是否为新建对象的准入请求，上下文中没有准入请求（直接调用Default）时按新建处理
+func isCreateRequest(ctx context.Context) bool {
+	req, err := admission.RequestFromContext(ctx)
+	return err != nil || req.Operation == admissionv1.Create
+}
+
 // Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind LSTMPredictApp.
-func (d *LSTMPredictAppCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+func (d *LSTMPredictAppCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
 	lstmpredictapp, ok := obj.(*lstmappsv1.LSTMPredictApp)
 
 	if !ok {
@@ -86,6 +154,17 @@ func (d *LSTMPredictAppCustomDefaulter) Default(_ context.Context, obj runtime.O
 	}
 	lstmpredictapplog.Info("Defaulting for LSTMPredictApp", "name", lstmpredictapp.GetName())
 
+	// 正在删除的对象只会被移除finalizer，不再注入默认值
+	if !lstmpredictapp.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	// 使用对该命名空间生效的LSTMOperatorPolicy中的默认值
+	d, err := d.forNamespace(ctx, requestNamespace(ctx, lstmpredictapp))
+	if err != nil {
+		return err
+	}
+
 	// TODO(user): fill in your defaulting logic.
 	// 后端应用副本数默认值注入
 	if lstmpredictapp.Spec.BackendAppReplicas == nil {
@@ -100,18 +179,92 @@ func (d *LSTMPredictAppCustomDefaulter) Default(_ context.Context, obj runtime.O
 	if lstmpredictapp.Spec.ServiceType == "" {
 		lstmpredictapp.Spec.ServiceType = corev1.ServiceType(d.DefaultServiceType)
 	}
-	// 服务端口默认值注入
-	if lstmpredictapp.Spec.ServicePort == 0 {
+	// 服务端口默认值注入，使用spec.ports时每个端口的Service端口默认与容器端口相同
+	if len(lstmpredictapp.Spec.Ports) == 0 && lstmpredictapp.Spec.ServicePort == 0 {
 		lstmpredictapp.Spec.ServicePort = d.DefaultServicePort
 	}
+	for i := range lstmpredictapp.Spec.Ports {
+		port := &lstmpredictapp.Spec.Ports[i]
+		if port.ServicePort == 0 {
+			port.ServicePort = port.ContainerPort
+		}
+		if port.Protocol == "" {
+			port.Protocol = corev1.ProtocolTCP
+		}
+	}
+	// 模型挂载目录与拉取模型的Init容器镜像默认值注入
+	if model := lstmpredictapp.Spec.Model; model != nil {
+		if model.MountPath == "" {
+			model.MountPath = d.DefaultModelMountPath
+		}
+		if model.OCI != nil && model.OCI.PullerImage == "" {
+			model.OCI.PullerImage = d.DefaultModelPullerImage
+		}
+		if model.HTTP != nil && model.HTTP.DownloaderImage == "" {
+			model.HTTP.DownloaderImage = d.DefaultModelDownloaderImage
+		}
+	}
+	// 自动扩缩容最小副本数默认值注入
+	if autoscaling := lstmpredictapp.Spec.Autoscaling; autoscaling != nil && autoscaling.MinReplicas == nil {
+		autoscaling.MinReplicas = new(int32)
+		*autoscaling.MinReplicas = d.DefaultBackendAppReplicas
+	}
+	// PodDisruptionBudget默认值注入，只有一个副本时不需要
+	if lstmpredictapp.Spec.Disruption == nil && (*lstmpredictapp.Spec.BackendAppReplicas > 1 || lstmpredictapp.Spec.Autoscaling != nil) {
+		maxUnavailable := d.DefaultMaxUnavailable
+		lstmpredictapp.Spec.Disruption = &lstmappsv1.DisruptionSpec{MaxUnavailable: &maxUnavailable}
+	}
+	// 发布策略默认值注入
+	if rollout := lstmpredictapp.Spec.Rollout; rollout != nil && rollout.Strategy == "" {
+		rollout.Strategy = lstmappsv1.RolloutStrategyRollingUpdate
+	}
+	// 探针默认值注入：新建时未指定spec.probes同样开启探针；更新时只补全用户指定的spec.probes，
+	// 避免引入探针之前创建的应用在任意一次更新后被加上探针而滚动重启
+	if lstmpredictapp.Spec.Probes == nil && isCreateRequest(ctx) {
+		lstmpredictapp.Spec.Probes = &lstmappsv1.ProbesSpec{}
+	}
+	if probes := lstmpredictapp.Spec.Probes; probes != nil && !probes.Disabled {
+		if probes.Path == "" {
+			probes.Path = d.DefaultProbePath
+		}
+		if probes.ReadinessPath == "" {
+			probes.ReadinessPath = probes.Path
+		}
+		if probes.Liveness == nil {
+			probes.Liveness = d.DefaultLivenessProbe.DeepCopy()
+		}
+		if probes.Readiness == nil {
+			probes.Readiness = d.DefaultReadinessProbe.DeepCopy()
+		}
+		if probes.Startup == nil {
+			probes.Startup = d.DefaultStartupProbe.DeepCopy()
+		}
+	}
+	// 指标接口路径默认值注入
+	if monitoring := lstmpredictapp.Spec.Monitoring; monitoring != nil && monitoring.Enabled && monitoring.Path == "" {
+		monitoring.Path = d.DefaultMetricsPath
+	}
+	// 将镜像标签解析为digest，保留标签便于阅读，Deployment固定使用解析时的镜像
+	if d.DigestResolver != nil {
+		for _, image := range appImages(lstmpredictapp) {
+			if *image.image == "" || parseImageReference(*image.image).Digest != "" {
+				continue
+			}
+			digest, err := d.DigestResolver.Resolve(ctx, *image.image)
+			if err != nil {
+				return fmt.Errorf("failed to resolve the digest of %s %q: %w", image.field, *image.image, err)
+			}
+			*image.image += "@" + digest
+		}
+	}
 
 	return nil
 }
 
-// TODO(user): change verbs to "verbs=create;update;delete" if you want to enable deletion validation.
+// 删除时只检查spec.deletionProtection，因此verbs中包含delete
 // NOTE: The 'path' attribute must follow a specific pattern and should not be modified directly here.
 // Modifying the path for an invalid path can cause API server errors; failing to locate the webhook.
-// +kubebuilder:webhook:path=/validate-lstmapps-wuyong7240-com-v1-lstmpredictapp,mutating=false,failurePolicy=fail,sideEffects=None,groups=lstmapps.wuyong7240.com,resources=lstmpredictapps,verbs=create;update,versions=v1,name=vlstmpredictapp-v1.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-lstmapps-wuyong7240-com-v1-lstmpredictapp,mutating=false,failurePolicy=fail,sideEffects=None,groups=lstmapps.wuyong7240.com,resources=lstmpredictapps,verbs=create;update;delete,versions=v1,name=vlstmpredictapp-v1.kb.io,admissionReviewVersions=v1
 
 // LSTMPredictAppCustomValidator struct is responsible for validating the LSTMPredictApp resource
 // when it is created, updated, or deleted.
@@ -120,16 +273,31 @@ func (d *LSTMPredictAppCustomDefaulter) Default(_ context.Context, obj runtime.O
 // as this struct is used only for temporary operations and does not need to be deeply copied.
 type LSTMPredictAppCustomValidator struct {
 	// TODO(user): Add more fields as needed for validation
+	// 用于查询spec.modelRef引用的LSTMModel，为空时跳过该校验
+	// 同时用于读取LSTMOperatorPolicy，策略中设置了的字段覆盖以下内置的限制
+	Client                client.Reader
 	MaxBackendAppReplicas int32
 	MinBackendAppReplicas int32
-	MaxPortID             int32
-	AvailableServiceType  []string
+	// 开启自动扩缩容时Autoscaling.MaxReplicas不能超过的值，为0时不限制；只由LSTMOperatorPolicy中的maxReplicas设置
+	MaxAutoscalingReplicas int32
+	MaxPortID              int32
+	AvailableServiceType   []string
+	// 允许使用的镜像仓库前缀，为空时不限制
+	AllowedImageRegistries []string
+	// 禁止使用latest标签或者不带标签的镜像
+	ForbidMutableImageTags bool
+	// 要求镜像以digest固定
+	RequireImageDigest bool
+	// 每种资源的请求与限制都不能超过的值，为空时不限制
+	MaxResources corev1.ResourceList
+	// LSTMPredictApp必须带有的标签
+	RequiredLabels []string
 }
 
 var _ webhook.CustomValidator = &LSTMPredictAppCustomValidator{}
 
 // ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type LSTMPredictApp.
-func (v *LSTMPredictAppCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+func (v *LSTMPredictAppCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
 	lstmpredictapp, ok := obj.(*lstmappsv1.LSTMPredictApp)
 	if !ok {
 		return nil, fmt.Errorf("expected a LSTMPredictApp object but got %T", obj)
@@ -137,24 +305,57 @@ func (v *LSTMPredictAppCustomValidator) ValidateCreate(_ context.Context, obj ru
 	lstmpredictapplog.Info("Validation for LSTMPredictApp upon creation", "name", lstmpredictapp.GetName())
 
 	// TODO(user): fill in your validation logic upon object creation.
+	v, err := v.forNamespace(ctx, requestNamespace(ctx, lstmpredictapp))
+	if err != nil {
+		return nil, err
+	}
 	if err := v.validateLSTMPredictAppSpec(lstmpredictapp); err != nil {
 		return admission.Warnings{"LSTMPredictApp Webhook v1 Errors!"}, err
 	}
+	if err := v.validateImages(lstmpredictapp, nil); err != nil {
+		return admission.Warnings{"LSTMPredictApp Webhook v1 Errors!"}, err
+	}
+	if err := v.validateModelRef(ctx, lstmpredictapp); err != nil {
+		return admission.Warnings{"LSTMPredictApp Webhook v1 Errors!"}, err
+	}
 	return nil, nil
 }
 
 // ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type LSTMPredictApp.
-func (v *LSTMPredictAppCustomValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+func (v *LSTMPredictAppCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
 	lstmpredictapp, ok := newObj.(*lstmappsv1.LSTMPredictApp)
 	if !ok {
 		return nil, fmt.Errorf("expected a LSTMPredictApp object for the newObj but got %T", newObj)
 	}
 	lstmpredictapplog.Info("Validation for LSTMPredictApp upon update", "name", lstmpredictapp.GetName())
+	oldLSTMPredictApp, ok := oldObj.(*lstmappsv1.LSTMPredictApp)
+	if !ok {
+		return nil, fmt.Errorf("expected a LSTMPredictApp object for the oldObj but got %T", oldObj)
+	}
 
 	// TODO(user): fill in your validation logic upon object update.
+	// 与删除时一样，正在删除的对象以及只修改了注解、finalizer等元数据的更新不再校验，
+	// 否则校验规则变化之前创建的对象将无法移除finalizer，也无法被控制器添加注解
+	if !lstmpredictapp.DeletionTimestamp.IsZero() {
+		return nil, nil
+	}
+	if equality.Semantic.DeepEqual(oldLSTMPredictApp.Spec, lstmpredictapp.Spec) &&
+		equality.Semantic.DeepEqual(oldLSTMPredictApp.Labels, lstmpredictapp.Labels) {
+		return nil, nil
+	}
+	v, err := v.forNamespace(ctx, requestNamespace(ctx, lstmpredictapp))
+	if err != nil {
+		return nil, err
+	}
 	if err := v.validateLSTMPredictAppSpec(lstmpredictapp); err != nil {
 		return admission.Warnings{"LSTMPredictApp Webhook v1 Errors!"}, err
 	}
+	if err := v.validateImages(lstmpredictapp, oldLSTMPredictApp); err != nil {
+		return admission.Warnings{"LSTMPredictApp Webhook v1 Errors!"}, err
+	}
+	if err := v.validateModelRef(ctx, lstmpredictapp); err != nil {
+		return admission.Warnings{"LSTMPredictApp Webhook v1 Errors!"}, err
+	}
 	return nil, nil
 }
 
@@ -166,30 +367,92 @@ func (v *LSTMPredictAppCustomValidator) ValidateDelete(ctx context.Context, obj
 	}
 	lstmpredictapplog.Info("Validation for LSTMPredictApp upon deletion", "name", lstmpredictapp.GetName())
 
-	// TODO(user): fill in your validation logic upon object deletion.
-	if err := v.validateLSTMPredictAppSpec(lstmpredictapp); err != nil {
-		return admission.Warnings{"LSTMPredictApp Webhook v1 Errors!"}, err
+	// 删除时不再校验Spec，否则校验规则变化之前创建的对象将无法删除，只检查删除保护
+	if lstmpredictapp.Spec.DeletionProtection {
+		return nil, fmt.Errorf("LSTMPredictApp %s is protected from deletion, set spec.deletionProtection to false first", lstmpredictapp.GetName())
 	}
 	return nil, nil
 }
 
+// ScaleValidatingPath /scale子资源Webhook的路径
+const ScaleValidatingPath = "/validate-lstmapps-wuyong7240-com-v1-lstmpredictapp-scale"
+
+// +kubebuilder:webhook:path=/validate-lstmapps-wuyong7240-com-v1-lstmpredictapp-scale,mutating=false,failurePolicy=fail,sideEffects=None,groups=lstmapps.wuyong7240.com,resources=lstmpredictapps/scale,verbs=update,versions=v1,name=vlstmpredictapp-scale-v1.kb.io,admissionReviewVersions=v1
+
+// LSTMPredictAppScaleValidator 校验kubectl scale、HPA、KEDA等通过/scale子资源设置的副本数，
+// 与直接修改spec.backendAppReplicas时使用相同的上下限
+type LSTMPredictAppScaleValidator struct {
+	Validator *LSTMPredictAppCustomValidator
+	Decoder   admission.Decoder
+}
+
+var _ admission.Handler = &LSTMPredictAppScaleValidator{}
+
+// Handle implements admission.Handler, the object in the request is an autoscaling/v1 Scale.
+func (v *LSTMPredictAppScaleValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	scale := &autoscalingv1.Scale{}
+	if err := v.Decoder.Decode(req, scale); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	lstmpredictapplog.Info("Validation for LSTMPredictApp upon scale", "name", req.Name, "replicas", scale.Spec.Replicas)
+
+	// 开启自动扩缩容时副本数由控制器创建的HPA决定，通过/scale设置的副本数会与HPA互相覆盖
+	if v.Validator.Client != nil {
+		lstmpredictapp := &lstmappsv1.LSTMPredictApp{}
+		err := v.Validator.Client.Get(ctx, types.NamespacedName{Namespace: req.Namespace, Name: req.Name}, lstmpredictapp)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		if err == nil && lstmpredictapp.Spec.Autoscaling != nil {
+			return admission.Denied(fmt.Sprintf("LSTMPredictApp %s has spec.autoscaling enabled and its replicas are managed by the HPA, "+
+				"change spec.autoscaling.minReplicas/maxReplicas instead of scaling it", req.Name))
+		}
+	}
+
+	validator, err := v.Validator.forNamespace(ctx, req.Namespace)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if err := validator.validateReplicas(scale.Spec.Replicas); err != nil {
+		return admission.Denied(err.Error())
+	}
+	return admission.Allowed("")
+}
+
 func isEmptyResourceRequirements(r corev1.ResourceRequirements) bool {
 	return len(r.Limits) == 0 && len(r.Requests) == 0
 }
 
-func (v *LSTMPredictAppCustomValidator) validateLSTMPredictAppSpec(lstmpredictapp *lstmappsv1.LSTMPredictApp) error {
+// validateReplicas 校验副本数量在设定的最小值与最大值之间，/scale子资源的Webhook同样使用
+func (v *LSTMPredictAppCustomValidator) validateReplicas(replicas int32) error {
 	// 校验副本数量，不小于设定的最小值
-	if *lstmpredictapp.Spec.BackendAppReplicas < v.MinBackendAppReplicas {
+	if replicas < v.MinBackendAppReplicas {
 		return fmt.Errorf("BackendAppReplicas can't < %d", v.MinBackendAppReplicas)
 	}
 	// 校验副本数量，不超过设定的最大值
-	if *lstmpredictapp.Spec.BackendAppReplicas > v.MaxBackendAppReplicas {
+	if replicas > v.MaxBackendAppReplicas {
 		return fmt.Errorf("BackendAppReplicas can't > %d", v.MaxBackendAppReplicas)
 	}
+	return nil
+}
+
+func (v *LSTMPredictAppCustomValidator) validateLSTMPredictAppSpec(lstmpredictapp *lstmappsv1.LSTMPredictApp) error {
+	if err := v.validateReplicas(*lstmpredictapp.Spec.BackendAppReplicas); err != nil {
+		return err
+	}
 	// 校验服务端口号，须小于设定的最大端口号
 	if lstmpredictapp.Spec.ServicePort >= v.MaxPortID {
 		return fmt.Errorf("ServicePort is illeagle, need to < %d", v.MaxPortID)
 	}
+	// 校验多端口，ContainerPort与ServicePort只是单个端口的简写，不能同时使用
+	if len(lstmpredictapp.Spec.Ports) > 0 {
+		if lstmpredictapp.Spec.ContainerPort != 0 || lstmpredictapp.Spec.ServicePort != 0 {
+			return fmt.Errorf("ContainerPort and ServicePort can't be set together with Ports")
+		}
+		if err := v.validatePorts(lstmpredictapp.Spec.Ports); err != nil {
+			return err
+		}
+	}
 
 	// 校验服务类型，保证只在目前支持的服务类型中
 	var isAvailable bool = false
@@ -205,5 +468,484 @@ func (v *LSTMPredictAppCustomValidator) validateLSTMPredictAppSpec(lstmpredictap
 		return fmt.Errorf("ServiceType is Unsupport, should be in %s", currentAvailabelType)
 	}
 
+	// 校验LSTMOperatorPolicy要求的资源上限与标签
+	if err := validateMaxResources(lstmpredictapp.Spec.ResourcesLimit, v.MaxResources); err != nil {
+		return err
+	}
+	if err := validateRequiredLabels(lstmpredictapp.Labels, v.RequiredLabels); err != nil {
+		return err
+	}
+
+	// 校验Headless与负载均衡器的设置，只能与对应的服务类型一起使用
+	if lstmpredictapp.Spec.Headless && lstmpredictapp.Spec.ServiceType != corev1.ServiceTypeClusterIP {
+		return fmt.Errorf("Headless can only be set when ServiceType is ClusterIP")
+	}
+	if lstmpredictapp.Spec.LoadBalancer != nil {
+		if lstmpredictapp.Spec.ServiceType != corev1.ServiceTypeLoadBalancer {
+			return fmt.Errorf("LoadBalancer can only be set when ServiceType is LoadBalancer")
+		}
+		for i, cidr := range lstmpredictapp.Spec.LoadBalancer.SourceRanges {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return fmt.Errorf("LoadBalancer.SourceRanges[%d] is illeagle, need to be a CIDR, got %q", i, cidr)
+			}
+		}
+	}
+
+	// 校验模型来源
+	if lstmpredictapp.Spec.Model != nil {
+		if err := validateModelSpec(lstmpredictapp.Spec.Model, lstmpredictapp.Spec.ModelRef != nil); err != nil {
+			return err
+		}
+	}
+
+	// 校验自动扩缩容
+	if lstmpredictapp.Spec.Autoscaling != nil {
+		if err := validateAutoscalingSpec(lstmpredictapp.Spec.Autoscaling); err != nil {
+			return err
+		}
+		if v.MaxAutoscalingReplicas > 0 && lstmpredictapp.Spec.Autoscaling.MaxReplicas > v.MaxAutoscalingReplicas {
+			return fmt.Errorf("Autoscaling.MaxReplicas can't > %d", v.MaxAutoscalingReplicas)
+		}
+	}
+
+	// 校验发布策略
+	if lstmpredictapp.Spec.Rollout != nil {
+		if err := validateRolloutSpec(lstmpredictapp.Spec.Rollout); err != nil {
+			return err
+		}
+	}
+
+	// 校验PodDisruptionBudget
+	if lstmpredictapp.Spec.Disruption != nil {
+		if err := validateDisruptionSpec(lstmpredictapp); err != nil {
+			return err
+		}
+	}
+
+	// 校验访问来源
+	if lstmpredictapp.Spec.NetworkPolicy != nil {
+		if err := validateNetworkPolicySpec(lstmpredictapp.Spec.NetworkPolicy); err != nil {
+			return err
+		}
+	}
+
+	// 校验指标抓取配置
+	if lstmpredictapp.Spec.Monitoring != nil {
+		if err := validateMonitoringSpec(lstmpredictapp); err != nil {
+			return err
+		}
+	}
+
+	// 校验对外暴露方式
+	if lstmpredictapp.Spec.Expose != nil {
+		if err := validateExposeSpec(lstmpredictapp.Spec.Expose); err != nil {
+			return err
+		}
+	}
+
+	// 校验探针
+	if lstmpredictapp.Spec.Probes != nil {
+		if err := validateProbesSpec(lstmpredictapp.Spec.Probes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateModelRef 校验spec.modelRef引用的LSTMModel存在，并且没有校验失败
+func (v *LSTMPredictAppCustomValidator) validateModelRef(ctx context.Context, lstmpredictapp *lstmappsv1.LSTMPredictApp) error {
+	ref := lstmpredictapp.Spec.ModelRef
+	if ref == nil || v.Client == nil {
+		return nil
+	}
+	if ref.Name == "" {
+		return fmt.Errorf("ModelRef.Name can't be empty")
+	}
+
+	model := &lstmappsv1.LSTMModel{}
+	if err := v.Client.Get(ctx, types.NamespacedName{Namespace: lstmpredictapp.Namespace, Name: ref.Name}, model); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("ModelRef is illeagle, LSTMModel %s not found in namespace %s", ref.Name, lstmpredictapp.Namespace)
+		}
+		return err
+	}
+	// 尚未校验的LSTMModel允许引用，控制器会等到校验通过后再部署
+	if cond := meta.FindStatusCondition(model.Status.Conditions, lstmappsv1.ConditionTypeValidated); cond != nil &&
+		cond.Status == metav1.ConditionFalse && cond.ObservedGeneration == model.Generation {
+		return fmt.Errorf("ModelRef is illeagle, LSTMModel %s failed validation: %s", ref.Name, cond.Message)
+	}
+	return nil
+}
+
+var sha256Pattern = regexp.MustCompile(`^[a-fA-F0-9]{64}$`)
+
+func validateModelSpec(model *lstmappsv1.ModelSpec, hasModelRef bool) error {
+	// 模型来源必须且只能指定一种；引用LSTMModel时来源和版本都由LSTMModel决定
+	var sources int
+	for _, specified := range []bool{model.PVC != nil, model.ConfigMap != nil, model.Secret != nil, model.OCI != nil, model.HTTP != nil} {
+		if specified {
+			sources++
+		}
+	}
+	if hasModelRef {
+		if sources != 0 || model.Version != "" {
+			return fmt.Errorf("Model can only set mountPath and reloadEndpoint when ModelRef is specified")
+		}
+	} else if sources != 1 {
+		return fmt.Errorf("Model must specify exactly one source of {pvc/configMap/secret/oci/http}, got %d", sources)
+	}
+
+	if model.MountPath != "" && !path.IsAbs(model.MountPath) {
+		return fmt.Errorf("Model.MountPath must be an absolute path, got %q", model.MountPath)
+	}
+	if model.ReloadEndpoint != "" && !strings.HasPrefix(model.ReloadEndpoint, "/") {
+		return fmt.Errorf("Model.ReloadEndpoint must start with '/', got %q", model.ReloadEndpoint)
+	}
+
+	switch {
+	case model.PVC != nil:
+		if model.PVC.ClaimName == "" {
+			return fmt.Errorf("Model.PVC.ClaimName can't be empty")
+		}
+		if err := validateRelativePath(model.PVC.Path); err != nil {
+			return fmt.Errorf("Model.PVC.Path is illeagle: %w", err)
+		}
+	case model.ConfigMap != nil:
+		if model.ConfigMap.Name == "" {
+			return fmt.Errorf("Model.ConfigMap.Name can't be empty")
+		}
+	case model.Secret != nil:
+		if model.Secret.Name == "" {
+			return fmt.Errorf("Model.Secret.Name can't be empty")
+		}
+	case model.OCI != nil:
+		if model.OCI.Artifact == "" {
+			return fmt.Errorf("Model.OCI.Artifact can't be empty")
+		}
+		if err := validateRelativePath(model.OCI.Path); err != nil {
+			return fmt.Errorf("Model.OCI.Path is illeagle: %w", err)
+		}
+	case model.HTTP != nil:
+		u, err := url.Parse(model.HTTP.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("Model.HTTP.URL is illeagle, need to be a http or https URL, got %q", model.HTTP.URL)
+		}
+		if !sha256Pattern.MatchString(model.HTTP.SHA256) {
+			return fmt.Errorf("Model.HTTP.SHA256 is illeagle, need to be 64 hex characters")
+		}
+		if strings.Contains(model.HTTP.FileName, "/") || model.HTTP.FileName == "." || model.HTTP.FileName == ".." {
+			return fmt.Errorf("Model.HTTP.FileName is illeagle, need to be a plain file name, got %q", model.HTTP.FileName)
+		}
+	}
+
+	return nil
+}
+
+// validateRelativePath 校验卷内的相对路径，不允许绝对路径以及跳出卷目录
+func validateRelativePath(p string) error {
+	if p == "" {
+		return nil
+	}
+	if path.IsAbs(p) {
+		return fmt.Errorf("%q must be a relative path", p)
+	}
+	for _, part := range strings.Split(p, "/") {
+		if part == ".." {
+			return fmt.Errorf("%q can't contain '..'", p)
+		}
+	}
+	return nil
+}
+
+func validateRolloutSpec(rollout *lstmappsv1.RolloutSpec) error {
+	if rollout.Canary != nil && rollout.Strategy != lstmappsv1.RolloutStrategyCanary {
+		return fmt.Errorf("Rollout.Canary can only be set when Rollout.Strategy is Canary")
+	}
+	if rollout.BlueGreen != nil && rollout.Strategy != lstmappsv1.RolloutStrategyBlueGreen {
+		return fmt.Errorf("Rollout.BlueGreen can only be set when Rollout.Strategy is BlueGreen")
+	}
+	switch rollout.Strategy {
+	case lstmappsv1.RolloutStrategyRollingUpdate, "":
+		return nil
+	case lstmappsv1.RolloutStrategyBlueGreen:
+		if bg := rollout.BlueGreen; bg != nil && bg.AutoPromoteAfter != nil && bg.AutoPromoteAfter.Duration < 0 {
+			return fmt.Errorf("Rollout.BlueGreen.AutoPromoteAfter can't < 0")
+		}
+		return nil
+	case lstmappsv1.RolloutStrategyCanary:
+	default:
+		return fmt.Errorf("Rollout.Strategy %q is Unsupport", rollout.Strategy)
+	}
+
+	canary := rollout.Canary
+	if canary == nil || len(canary.Steps) == 0 {
+		return fmt.Errorf("Rollout.Canary.Steps can't be empty when Rollout.Strategy is Canary")
+	}
+	// 权重须在1~100之间并且严格递增
+	var last int32
+	for i, step := range canary.Steps {
+		if step.Weight < 1 || step.Weight > 100 {
+			return fmt.Errorf("Rollout.Canary.Steps[%d].Weight is illeagle, need to be in [1, 100]", i)
+		}
+		if step.Weight <= last {
+			return fmt.Errorf("Rollout.Canary.Steps[%d].Weight is illeagle, need to > %d", i, last)
+		}
+		if step.Pause.Duration < 0 {
+			return fmt.Errorf("Rollout.Canary.Steps[%d].Pause can't < 0", i)
+		}
+		last = step.Weight
+	}
+	if analysis := canary.Analysis; analysis != nil {
+		u, err := url.Parse(analysis.PrometheusURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("Rollout.Canary.Analysis.PrometheusURL is illeagle, need to be a http or https URL, got %q", analysis.PrometheusURL)
+		}
+		if strings.TrimSpace(analysis.Query) == "" {
+			return fmt.Errorf("Rollout.Canary.Analysis.Query can't be empty")
+		}
+		if _, err := strconv.ParseFloat(analysis.Threshold, 64); err != nil {
+			return fmt.Errorf("Rollout.Canary.Analysis.Threshold is illeagle, need to be a number, got %q", analysis.Threshold)
+		}
+	}
+	return nil
+}
+
+func validateAutoscalingSpec(autoscaling *lstmappsv1.AutoscalingSpec) error {
+	if autoscaling.MinReplicas != nil && *autoscaling.MinReplicas < 1 {
+		return fmt.Errorf("Autoscaling.MinReplicas can't < 1")
+	}
+	if autoscaling.MaxReplicas < 1 {
+		return fmt.Errorf("Autoscaling.MaxReplicas can't < 1")
+	}
+	if autoscaling.MinReplicas != nil && *autoscaling.MinReplicas > autoscaling.MaxReplicas {
+		return fmt.Errorf("Autoscaling.MinReplicas can't > Autoscaling.MaxReplicas")
+	}
+	if autoscaling.TargetCPUUtilizationPercentage == nil && autoscaling.TargetMemoryUtilizationPercentage == nil &&
+		len(autoscaling.CustomMetrics) == 0 {
+		return fmt.Errorf("Autoscaling must specify at least one of {targetCPUUtilizationPercentage/targetMemoryUtilizationPercentage/customMetrics}")
+	}
+	if p := autoscaling.TargetCPUUtilizationPercentage; p != nil && *p < 1 {
+		return fmt.Errorf("Autoscaling.TargetCPUUtilizationPercentage can't < 1")
+	}
+	if p := autoscaling.TargetMemoryUtilizationPercentage; p != nil && *p < 1 {
+		return fmt.Errorf("Autoscaling.TargetMemoryUtilizationPercentage can't < 1")
+	}
+	seen := map[string]bool{}
+	for i, metric := range autoscaling.CustomMetrics {
+		if metric.Name == "" {
+			return fmt.Errorf("Autoscaling.CustomMetrics[%d].Name can't be empty", i)
+		}
+		if seen[metric.Name] {
+			return fmt.Errorf("Autoscaling.CustomMetrics[%d].Name %q is duplicated", i, metric.Name)
+		}
+		seen[metric.Name] = true
+		if metric.TargetAverageValue.Sign() <= 0 {
+			return fmt.Errorf("Autoscaling.CustomMetrics[%d].TargetAverageValue must > 0", i)
+		}
+	}
+	return nil
+}
+
+func (v *LSTMPredictAppCustomValidator) validatePorts(ports []lstmappsv1.PortSpec) error {
+	names := map[string]bool{}
+	containerPorts := map[string]bool{}
+	servicePorts := map[string]bool{}
+	for i, port := range ports {
+		if errs := validation.IsValidPortName(port.Name); len(errs) > 0 {
+			return fmt.Errorf("Ports[%d].Name is illeagle, %s", i, strings.Join(errs, "; "))
+		}
+		if names[port.Name] {
+			return fmt.Errorf("Ports[%d].Name %q is duplicated", i, port.Name)
+		}
+		names[port.Name] = true
+		if errs := validation.IsValidPortNum(int(port.ContainerPort)); len(errs) > 0 {
+			return fmt.Errorf("Ports[%d].ContainerPort is illeagle, %s", i, strings.Join(errs, "; "))
+		}
+		// 服务端口号须小于设定的最大端口号
+		if port.ServicePort < 0 || port.ServicePort >= v.MaxPortID {
+			return fmt.Errorf("Ports[%d].ServicePort is illeagle, need to < %d", i, v.MaxPortID)
+		}
+		servicePort := port.ServicePort
+		if servicePort == 0 {
+			servicePort = port.ContainerPort
+		}
+		// 同一协议下端口号不能重复
+		protocol := port.Protocol
+		if protocol == "" {
+			protocol = corev1.ProtocolTCP
+		}
+		containerKey := fmt.Sprintf("%d/%s", port.ContainerPort, protocol)
+		if containerPorts[containerKey] {
+			return fmt.Errorf("Ports[%d].ContainerPort %s is duplicated", i, containerKey)
+		}
+		containerPorts[containerKey] = true
+		serviceKey := fmt.Sprintf("%d/%s", servicePort, protocol)
+		if servicePorts[serviceKey] {
+			return fmt.Errorf("Ports[%d].ServicePort %s is duplicated", i, serviceKey)
+		}
+		servicePorts[serviceKey] = true
+	}
+	return nil
+}
+
+func validateDisruptionSpec(lstmpredictapp *lstmappsv1.LSTMPredictApp) error {
+	disruption := lstmpredictapp.Spec.Disruption
+	if (disruption.MinAvailable == nil) == (disruption.MaxUnavailable == nil) {
+		return fmt.Errorf("Disruption must specify exactly one of {minAvailable/maxUnavailable}")
+	}
+	if disruption.MinAvailable != nil {
+		minAvailable, err := disruptionValue(disruption.MinAvailable)
+		if err != nil {
+			return fmt.Errorf("Disruption.MinAvailable is illeagle, %w", err)
+		}
+		// 不启用自动扩缩容时副本数固定，minAvailable不小于副本数会使Pod永远无法被驱逐，阻塞节点排空；
+		// 副本数不大于1时控制器删除PodDisruptionBudget，不再校验，缩容到1个副本时不必同时修改disruption
+		if disruption.MinAvailable.Type == intstr.Int && lstmpredictapp.Spec.Autoscaling == nil &&
+			*lstmpredictapp.Spec.BackendAppReplicas > 1 && int32(minAvailable) >= *lstmpredictapp.Spec.BackendAppReplicas {
+			return fmt.Errorf("Disruption.MinAvailable can't >= BackendAppReplicas, or pods can never be evicted")
+		}
+		if disruption.MinAvailable.Type == intstr.String && minAvailable >= 100 {
+			return fmt.Errorf("Disruption.MinAvailable can't be 100%%, or pods can never be evicted")
+		}
+	}
+	if disruption.MaxUnavailable != nil {
+		maxUnavailable, err := disruptionValue(disruption.MaxUnavailable)
+		if err != nil {
+			return fmt.Errorf("Disruption.MaxUnavailable is illeagle, %w", err)
+		}
+		if maxUnavailable < 1 {
+			return fmt.Errorf("Disruption.MaxUnavailable can't < 1, or pods can never be evicted")
+		}
+	}
+	return nil
+}
+
+// disruptionValue 解析整数或者百分比形式的值，百分比返回百分数
+func disruptionValue(v *intstr.IntOrString) (int, error) {
+	if v.Type == intstr.Int {
+		if v.IntVal < 0 {
+			return 0, fmt.Errorf("need to >= 0, got %d", v.IntVal)
+		}
+		return v.IntValue(), nil
+	}
+	percent, err := strconv.Atoi(strings.TrimSuffix(v.StrVal, "%"))
+	if err != nil || !strings.HasSuffix(v.StrVal, "%") || percent < 0 || percent > 100 {
+		return 0, fmt.Errorf("need to be an integer or a percentage in [0%%, 100%%], got %q", v.StrVal)
+	}
+	return percent, nil
+}
+
+func validateNetworkPolicySpec(policy *lstmappsv1.NetworkPolicySpec) error {
+	if len(policy.From) == 0 {
+		return fmt.Errorf("NetworkPolicy.From can't be empty")
+	}
+	for i, peer := range policy.From {
+		if peer.Namespace != "" && peer.NamespaceSelector != nil {
+			return fmt.Errorf("NetworkPolicy.From[%d] can't set both namespace and namespaceSelector", i)
+		}
+		if peer.Namespace == "" && peer.NamespaceSelector == nil && peer.PodSelector == nil {
+			return fmt.Errorf("NetworkPolicy.From[%d] must specify at least one of {namespace/namespaceSelector/podSelector}", i)
+		}
+		if errs := validation.IsDNS1123Label(peer.Namespace); peer.Namespace != "" && len(errs) > 0 {
+			return fmt.Errorf("NetworkPolicy.From[%d].Namespace is illeagle, %s", i, strings.Join(errs, "; "))
+		}
+		for _, selector := range []*metav1.LabelSelector{peer.NamespaceSelector, peer.PodSelector} {
+			if selector == nil {
+				continue
+			}
+			if _, err := metav1.LabelSelectorAsSelector(selector); err != nil {
+				return fmt.Errorf("NetworkPolicy.From[%d] has an illeagle selector, %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateMonitoringSpec(lstmpredictapp *lstmappsv1.LSTMPredictApp) error {
+	monitoring := lstmpredictapp.Spec.Monitoring
+	if monitoring.Path != "" && !strings.HasPrefix(monitoring.Path, "/") {
+		return fmt.Errorf("Monitoring.Path must start with '/', got %q", monitoring.Path)
+	}
+	if monitoring.Port != "" {
+		found := false
+		for _, port := range lstmpredictapp.Spec.Ports {
+			found = found || port.Name == monitoring.Port
+		}
+		if !found {
+			return fmt.Errorf("Monitoring.Port %q is not found in spec.ports", monitoring.Port)
+		}
+	}
+	// Prometheus的抓取间隔以秒为最小单位
+	if interval := monitoring.Interval; interval != nil {
+		if interval.Duration < time.Second || interval.Duration%time.Second != 0 {
+			return fmt.Errorf("Monitoring.Interval must be a whole number of seconds and >= 1s, got %s", interval.Duration)
+		}
+	}
+	return nil
+}
+
+func validateExposeSpec(expose *lstmappsv1.ExposeSpec) error {
+	if errs := validation.IsDNS1123Subdomain(strings.TrimPrefix(expose.Host, "*.")); len(errs) > 0 {
+		return fmt.Errorf("Expose.Host is illeagle, %s", strings.Join(errs, "; "))
+	}
+	if expose.Path != "" && !strings.HasPrefix(expose.Path, "/") {
+		return fmt.Errorf("Expose.Path must start with '/', got %q", expose.Path)
+	}
+	switch expose.Type {
+	case lstmappsv1.ExposeTypeIngress:
+		if expose.Gateway != nil {
+			return fmt.Errorf("Expose.Gateway can only be set when Expose.Type is Gateway")
+		}
+	case lstmappsv1.ExposeTypeGateway:
+		if expose.Gateway == nil || expose.Gateway.Name == "" {
+			return fmt.Errorf("Expose.Gateway.Name can't be empty when Expose.Type is Gateway")
+		}
+		// Gateway的TLS由其监听器终止，HTTPRoute上无法指定证书
+		if expose.TLSSecretName != "" {
+			return fmt.Errorf("Expose.TLSSecretName can only be set when Expose.Type is Ingress, set Expose.Gateway.TLS instead")
+		}
+		if expose.IngressClassName != nil {
+			return fmt.Errorf("Expose.IngressClassName can only be set when Expose.Type is Ingress")
+		}
+	default:
+		return fmt.Errorf("Expose.Type %q is Unsupport, should be in {Ingress/Gateway}", expose.Type)
+	}
+	return nil
+}
+
+func validateProbesSpec(probes *lstmappsv1.ProbesSpec) error {
+	if probes.Disabled {
+		return nil
+	}
+	if probes.Path != "" && !strings.HasPrefix(probes.Path, "/") {
+		return fmt.Errorf("Probes.Path must start with '/', got %q", probes.Path)
+	}
+	if probes.ReadinessPath != "" && !strings.HasPrefix(probes.ReadinessPath, "/") {
+		return fmt.Errorf("Probes.ReadinessPath must start with '/', got %q", probes.ReadinessPath)
+	}
+	for _, probe := range []struct {
+		name   string
+		timing *lstmappsv1.ProbeTiming
+	}{
+		{"Liveness", probes.Liveness},
+		{"Readiness", probes.Readiness},
+		{"Startup", probes.Startup},
+	} {
+		name, timing := probe.name, probe.timing
+		if timing == nil {
+			continue
+		}
+		if timing.InitialDelaySeconds < 0 {
+			return fmt.Errorf("Probes.%s.InitialDelaySeconds can't < 0", name)
+		}
+		if timing.PeriodSeconds < 1 || timing.TimeoutSeconds < 1 || timing.FailureThreshold < 1 {
+			return fmt.Errorf("Probes.%s is illeagle, periodSeconds, timeoutSeconds and failureThreshold can't < 1", name)
+		}
+		// 超时大于检查周期时，上一次检查尚未结束就开始下一次检查
+		if timing.TimeoutSeconds > timing.PeriodSeconds {
+			return fmt.Errorf("Probes.%s.TimeoutSeconds can't > Probes.%s.PeriodSeconds", name, name)
+		}
+	}
 	return nil
 }
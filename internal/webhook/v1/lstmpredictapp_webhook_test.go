@@ -17,8 +17,27 @@ limitations under the License.
 package v1
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
 	// TODO (user): Add any additional imports if needed
@@ -84,4 +103,1056 @@ var _ = Describe("LSTMPredictApp Webhook", func() {
 		// })
 	})
 
+	Context("When validating the model source of LSTMPredictApp", func() {
+		BeforeEach(func() {
+			validator = LSTMPredictAppCustomValidator{
+				MaxBackendAppReplicas: 10,
+				MinBackendAppReplicas: 1,
+				MaxPortID:             30000,
+				AvailableServiceType:  []string{"ClusterIP", "NodePort"},
+			}
+			obj.Spec = lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				ContainerPort:      8080,
+				BackendAppReplicas: ptr.To[int32](1),
+				ServicePort:        80,
+				ServiceType:        corev1.ServiceTypeClusterIP,
+			}
+		})
+
+		It("Should admit a single HTTP source with a valid checksum", func() {
+			obj.Spec.Model = &lstmappsv1.ModelSpec{HTTP: &lstmappsv1.HTTPModelSource{
+				URL:    "https://models.example.com/lstm/v3.pt",
+				SHA256: strings.Repeat("a", 64),
+			}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should deny a model with more than one source", func() {
+			obj.Spec.Model = &lstmappsv1.ModelSpec{
+				PVC:       &lstmappsv1.PVCModelSource{ClaimName: "models"},
+				ConfigMap: &lstmappsv1.ObjectModelSource{Name: "lstm-weights"},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should deny an HTTP source with a malformed checksum", func() {
+			obj.Spec.Model = &lstmappsv1.ModelSpec{HTTP: &lstmappsv1.HTTPModelSource{
+				URL:    "https://models.example.com/lstm/v3.pt",
+				SHA256: "not-a-checksum",
+			}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should deny an HTTP source that isn't a http or https URL", func() {
+			for _, u := range []string{"ftp://models.example.com/lstm/v3.pt", "models.example.com/lstm/v3.pt", "https://"} {
+				obj.Spec.Model = &lstmappsv1.ModelSpec{HTTP: &lstmappsv1.HTTPModelSource{
+					URL:    u,
+					SHA256: strings.Repeat("a", 64),
+				}}
+				_, err := validator.ValidateCreate(ctx, obj)
+				Expect(err).To(MatchError(ContainSubstring("Model.HTTP.URL is illeagle")), u)
+			}
+		})
+
+		It("Should deny a PVC path escaping the volume", func() {
+			obj.Spec.Model = &lstmappsv1.ModelSpec{PVC: &lstmappsv1.PVCModelSource{ClaimName: "models", Path: "../etc"}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should deny a reference to a LSTMModel that doesn't exist", func() {
+			scheme := runtime.NewScheme()
+			Expect(lstmappsv1.AddToScheme(scheme)).To(Succeed())
+			validator.Client = fake.NewClientBuilder().WithScheme(scheme).Build()
+			obj.Namespace = "default"
+			obj.Spec.ModelRef = &lstmappsv1.LSTMModelReference{Name: "missing-model"}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should deny a reference to a LSTMModel that failed validation", func() {
+			scheme := runtime.NewScheme()
+			Expect(lstmappsv1.AddToScheme(scheme)).To(Succeed())
+			model := &lstmappsv1.LSTMModel{
+				ObjectMeta: metav1.ObjectMeta{Name: "broken-model", Namespace: "default"},
+				Status: lstmappsv1.LSTMModelStatus{Conditions: []metav1.Condition{{
+					Type:   lstmappsv1.ConditionTypeValidated,
+					Status: metav1.ConditionFalse,
+					Reason: "ValidationFailed",
+				}}},
+			}
+			validator.Client = fake.NewClientBuilder().WithScheme(scheme).WithObjects(model).Build()
+			obj.Namespace = "default"
+			obj.Spec.ModelRef = &lstmappsv1.LSTMModelReference{Name: "broken-model"}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should default the mount path and fetcher images", func() {
+			defaulter = LSTMPredictAppCustomDefaulter{
+				DefaultModelMountPath:       "/models",
+				DefaultModelPullerImage:     "ghcr.io/oras-project/oras:v1.2.0",
+				DefaultModelDownloaderImage: "busybox:1.36",
+			}
+			obj.Spec.Model = &lstmappsv1.ModelSpec{OCI: &lstmappsv1.OCIModelSource{Artifact: "registry.example.com/models/lstm:v3"}}
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.Model.MountPath).To(Equal("/models"))
+			Expect(obj.Spec.Model.OCI.PullerImage).To(Equal("ghcr.io/oras-project/oras:v1.2.0"))
+		})
+	})
+
+	Context("When validating the rollout strategy of LSTMPredictApp", func() {
+		BeforeEach(func() {
+			validator = LSTMPredictAppCustomValidator{
+				MaxBackendAppReplicas: 10,
+				MinBackendAppReplicas: 1,
+				MaxPortID:             30000,
+				AvailableServiceType:  []string{"ClusterIP", "NodePort"},
+			}
+			obj.Spec = lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				ContainerPort:      8080,
+				BackendAppReplicas: ptr.To[int32](4),
+				ServicePort:        80,
+				ServiceType:        corev1.ServiceTypeClusterIP,
+			}
+		})
+
+		It("Should admit canary steps with increasing weights and an analysis", func() {
+			obj.Spec.Rollout = &lstmappsv1.RolloutSpec{
+				Strategy: lstmappsv1.RolloutStrategyCanary,
+				Canary: &lstmappsv1.CanaryStrategy{
+					Steps: []lstmappsv1.CanaryStep{{Weight: 25}, {Weight: 50}},
+					Analysis: &lstmappsv1.CanaryAnalysis{
+						PrometheusURL: "http://prometheus.monitoring.svc:9090",
+						Query:         `sum(rate(http_requests_total{track="canary",code=~"5.."}[1m]))`,
+						Threshold:     "0.05",
+					},
+				},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should deny canary steps whose weights don't increase", func() {
+			obj.Spec.Rollout = &lstmappsv1.RolloutSpec{
+				Strategy: lstmappsv1.RolloutStrategyCanary,
+				Canary:   &lstmappsv1.CanaryStrategy{Steps: []lstmappsv1.CanaryStep{{Weight: 50}, {Weight: 20}}},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should deny the canary strategy without steps", func() {
+			obj.Spec.Rollout = &lstmappsv1.RolloutSpec{Strategy: lstmappsv1.RolloutStrategyCanary}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should deny canary settings on the blue/green strategy", func() {
+			obj.Spec.Rollout = &lstmappsv1.RolloutSpec{
+				Strategy: lstmappsv1.RolloutStrategyBlueGreen,
+				Canary:   &lstmappsv1.CanaryStrategy{Steps: []lstmappsv1.CanaryStep{{Weight: 50}}},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should default the strategy to RollingUpdate", func() {
+			obj.Spec.Rollout = &lstmappsv1.RolloutSpec{}
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.Rollout.Strategy).To(Equal(lstmappsv1.RolloutStrategyRollingUpdate))
+		})
+	})
+
+	Context("When validating the autoscaling of LSTMPredictApp", func() {
+		BeforeEach(func() {
+			validator = LSTMPredictAppCustomValidator{
+				MaxBackendAppReplicas: 10,
+				MinBackendAppReplicas: 1,
+				MaxPortID:             30000,
+				AvailableServiceType:  []string{"ClusterIP", "NodePort"},
+			}
+			obj.Spec = lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				ContainerPort:      8080,
+				BackendAppReplicas: ptr.To[int32](1),
+				ServicePort:        80,
+				ServiceType:        corev1.ServiceTypeClusterIP,
+			}
+		})
+
+		It("Should admit a replica range beyond BackendAppReplicas limits", func() {
+			obj.Spec.Autoscaling = &lstmappsv1.AutoscalingSpec{
+				MinReplicas:                    ptr.To[int32](2),
+				MaxReplicas:                    50,
+				TargetCPUUtilizationPercentage: ptr.To[int32](70),
+				CustomMetrics: []lstmappsv1.CustomMetricTarget{
+					{Name: "http_requests_per_second", TargetAverageValue: resource.MustParse("100")},
+				},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should deny minReplicas greater than maxReplicas", func() {
+			obj.Spec.Autoscaling = &lstmappsv1.AutoscalingSpec{
+				MinReplicas:                    ptr.To[int32](5),
+				MaxReplicas:                    3,
+				TargetCPUUtilizationPercentage: ptr.To[int32](70),
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should deny autoscaling without any metric", func() {
+			obj.Spec.Autoscaling = &lstmappsv1.AutoscalingSpec{MaxReplicas: 3}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("When validating the network policy of LSTMPredictApp", func() {
+		BeforeEach(func() {
+			validator = LSTMPredictAppCustomValidator{
+				MaxBackendAppReplicas: 10,
+				MinBackendAppReplicas: 1,
+				MaxPortID:             30000,
+				AvailableServiceType:  []string{"ClusterIP", "NodePort"},
+			}
+			obj.Spec = lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				ContainerPort:      8080,
+				BackendAppReplicas: ptr.To[int32](1),
+				ServicePort:        80,
+				ServiceType:        corev1.ServiceTypeClusterIP,
+			}
+		})
+
+		It("Should admit namespaces and pod selectors", func() {
+			obj.Spec.NetworkPolicy = &lstmappsv1.NetworkPolicySpec{From: []lstmappsv1.NetworkPolicyPeer{
+				{Namespace: "forecasting"},
+				{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"role": "scheduler"}}},
+				{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "ml"}}},
+			}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should deny an empty allow-list and empty peers", func() {
+			obj.Spec.NetworkPolicy = &lstmappsv1.NetworkPolicySpec{}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+
+			obj.Spec.NetworkPolicy.From = []lstmappsv1.NetworkPolicyPeer{{}}
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should deny a namespace together with a namespace selector", func() {
+			obj.Spec.NetworkPolicy = &lstmappsv1.NetworkPolicySpec{From: []lstmappsv1.NetworkPolicyPeer{{
+				Namespace:         "forecasting",
+				NamespaceSelector: &metav1.LabelSelector{},
+			}}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("When validating the disruption budget of LSTMPredictApp", func() {
+		BeforeEach(func() {
+			validator = LSTMPredictAppCustomValidator{
+				MaxBackendAppReplicas: 10,
+				MinBackendAppReplicas: 1,
+				MaxPortID:             30000,
+				AvailableServiceType:  []string{"ClusterIP", "NodePort"},
+			}
+			defaulter = LSTMPredictAppCustomDefaulter{DefaultMaxUnavailable: intstr.FromString("25%")}
+			obj.Spec = lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				ContainerPort:      8080,
+				BackendAppReplicas: ptr.To[int32](3),
+				ServicePort:        80,
+				ServiceType:        corev1.ServiceTypeClusterIP,
+				Probes:             &lstmappsv1.ProbesSpec{Disabled: true},
+			}
+		})
+
+		It("Should default maxUnavailable only when there is more than one replica", func() {
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.Disruption).To(Equal(&lstmappsv1.DisruptionSpec{MaxUnavailable: ptr.To(intstr.FromString("25%"))}))
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+
+			obj.Spec.Disruption = nil
+			obj.Spec.BackendAppReplicas = ptr.To[int32](1)
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.Disruption).To(BeNil())
+		})
+
+		It("Should deny a budget that would block every eviction", func() {
+			obj.Spec.Disruption = &lstmappsv1.DisruptionSpec{MinAvailable: ptr.To(intstr.FromInt32(3))}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+
+			obj.Spec.Disruption = &lstmappsv1.DisruptionSpec{MaxUnavailable: ptr.To(intstr.FromString("0%"))}
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should allow scaling down to one replica without changing minAvailable", func() {
+			obj.Spec.Disruption = &lstmappsv1.DisruptionSpec{MinAvailable: ptr.To(intstr.FromInt32(1))}
+			obj.Spec.BackendAppReplicas = ptr.To[int32](1)
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should deny setting both minAvailable and maxUnavailable", func() {
+			obj.Spec.Disruption = &lstmappsv1.DisruptionSpec{
+				MinAvailable:   ptr.To(intstr.FromInt32(1)),
+				MaxUnavailable: ptr.To(intstr.FromInt32(1)),
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("When validating the ports of LSTMPredictApp", func() {
+		BeforeEach(func() {
+			validator = LSTMPredictAppCustomValidator{
+				MaxBackendAppReplicas: 10,
+				MinBackendAppReplicas: 1,
+				MaxPortID:             30000,
+				AvailableServiceType:  []string{"ClusterIP", "NodePort"},
+			}
+			defaulter = LSTMPredictAppCustomDefaulter{DefaultServicePort: 8001}
+			obj.Spec = lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				BackendAppReplicas: ptr.To[int32](1),
+				ServiceType:        corev1.ServiceTypeClusterIP,
+				Ports: []lstmappsv1.PortSpec{
+					{Name: "http", ContainerPort: 8080, ServicePort: 80},
+					{Name: "grpc", ContainerPort: 9090, AppProtocol: ptr.To("grpc")},
+					{Name: "metrics", ContainerPort: 9100},
+				},
+				Probes: &lstmappsv1.ProbesSpec{Disabled: true},
+			}
+		})
+
+		It("Should default the Service ports and admit named ports", func() {
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.ServicePort).To(BeZero())
+			Expect(obj.Spec.Ports[1].ServicePort).To(Equal(int32(9090)))
+			Expect(obj.Spec.Ports[2].Protocol).To(Equal(corev1.ProtocolTCP))
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should deny duplicated names and port numbers", func() {
+			obj.Spec.Ports[2].Name = "grpc"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+
+			obj.Spec.Ports[2].Name = "metrics"
+			obj.Spec.Ports[2].ContainerPort = 9090
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+
+			obj.Spec.Ports[2].ContainerPort = 9100
+			obj.Spec.Ports[2].ServicePort = 80
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should deny mixing ports with the single-port shorthand", func() {
+			obj.Spec.ContainerPort = 8080
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("When validating the Service of LSTMPredictApp", func() {
+		BeforeEach(func() {
+			validator = LSTMPredictAppCustomValidator{
+				MaxBackendAppReplicas: 10,
+				MinBackendAppReplicas: 1,
+				MaxPortID:             30000,
+				AvailableServiceType:  []string{"ClusterIP", "NodePort", "LoadBalancer"},
+			}
+			obj.Spec = lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				ContainerPort:      8080,
+				BackendAppReplicas: ptr.To[int32](1),
+				ServicePort:        80,
+				ServiceType:        corev1.ServiceTypeLoadBalancer,
+			}
+		})
+
+		It("Should admit a LoadBalancer with a class and source ranges", func() {
+			obj.Spec.LoadBalancer = &lstmappsv1.LoadBalancerSpec{
+				LoadBalancerClass: ptr.To("service.k8s.aws/nlb"),
+				SourceRanges:      []string{"10.0.0.0/8", "192.168.1.0/24"},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should deny a malformed source range", func() {
+			obj.Spec.LoadBalancer = &lstmappsv1.LoadBalancerSpec{SourceRanges: []string{"10.0.0.1"}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should deny headless and load balancer settings on other Service types", func() {
+			obj.Spec.Headless = true
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+
+			obj.Spec.Headless = false
+			obj.Spec.ServiceType = corev1.ServiceTypeClusterIP
+			obj.Spec.LoadBalancer = &lstmappsv1.LoadBalancerSpec{}
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("When validating the exposure of LSTMPredictApp", func() {
+		BeforeEach(func() {
+			validator = LSTMPredictAppCustomValidator{
+				MaxBackendAppReplicas: 10,
+				MinBackendAppReplicas: 1,
+				MaxPortID:             30000,
+				AvailableServiceType:  []string{"ClusterIP", "NodePort"},
+			}
+			obj.Spec = lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				ContainerPort:      8080,
+				BackendAppReplicas: ptr.To[int32](1),
+				ServicePort:        80,
+				ServiceType:        corev1.ServiceTypeClusterIP,
+			}
+		})
+
+		It("Should admit an Ingress with TLS and a Gateway route", func() {
+			obj.Spec.Expose = &lstmappsv1.ExposeSpec{
+				Type:          lstmappsv1.ExposeTypeIngress,
+				Host:          "predict.example.com",
+				Path:          "/lstm",
+				TLSSecretName: "predict-tls",
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+
+			obj.Spec.Expose = &lstmappsv1.ExposeSpec{
+				Type:    lstmappsv1.ExposeTypeGateway,
+				Host:    "predict.example.com",
+				Gateway: &lstmappsv1.GatewayReference{Name: "public", Namespace: "gateways", TLS: true},
+			}
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should deny a malformed host", func() {
+			obj.Spec.Expose = &lstmappsv1.ExposeSpec{Type: lstmappsv1.ExposeTypeIngress, Host: "Predict_Example"}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should deny a Gateway route without a Gateway or with a TLS secret", func() {
+			obj.Spec.Expose = &lstmappsv1.ExposeSpec{Type: lstmappsv1.ExposeTypeGateway, Host: "predict.example.com"}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+
+			obj.Spec.Expose.Gateway = &lstmappsv1.GatewayReference{Name: "public"}
+			obj.Spec.Expose.TLSSecretName = "predict-tls"
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("When validating the probes of LSTMPredictApp", func() {
+		BeforeEach(func() {
+			validator = LSTMPredictAppCustomValidator{
+				MaxBackendAppReplicas: 10,
+				MinBackendAppReplicas: 1,
+				MaxPortID:             30000,
+				AvailableServiceType:  []string{"ClusterIP", "NodePort"},
+			}
+			defaulter = LSTMPredictAppCustomDefaulter{
+				DefaultProbePath:      "/healthz",
+				DefaultLivenessProbe:  lstmappsv1.ProbeTiming{PeriodSeconds: 10, TimeoutSeconds: 1, FailureThreshold: 3},
+				DefaultReadinessProbe: lstmappsv1.ProbeTiming{PeriodSeconds: 5, TimeoutSeconds: 1, FailureThreshold: 3},
+				DefaultStartupProbe:   lstmappsv1.ProbeTiming{PeriodSeconds: 10, TimeoutSeconds: 1, FailureThreshold: 60},
+			}
+			obj.Spec = lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				ContainerPort:      8080,
+				BackendAppReplicas: ptr.To[int32](1),
+				ServicePort:        80,
+				ServiceType:        corev1.ServiceTypeClusterIP,
+			}
+		})
+
+		It("Should default the probes and keep the fields that are set", func() {
+			obj.Spec.Probes = &lstmappsv1.ProbesSpec{
+				Path:    "/live",
+				Startup: &lstmappsv1.ProbeTiming{PeriodSeconds: 15, TimeoutSeconds: 2, FailureThreshold: 120},
+			}
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.Probes.Path).To(Equal("/live"))
+			Expect(obj.Spec.Probes.ReadinessPath).To(Equal("/live"))
+			Expect(obj.Spec.Probes.Liveness).To(Equal(&defaulter.DefaultLivenessProbe))
+			Expect(obj.Spec.Probes.Readiness).To(Equal(&defaulter.DefaultReadinessProbe))
+			Expect(obj.Spec.Probes.Startup.FailureThreshold).To(Equal(int32(120)))
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should enable the probes when spec.probes is empty", func() {
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.Probes).NotTo(BeNil())
+			Expect(obj.Spec.Probes.Path).To(Equal("/healthz"))
+			Expect(obj.Spec.Probes.Startup).To(Equal(&defaulter.DefaultStartupProbe))
+		})
+
+		It("Should only enable the probes on update when spec.probes is set", func() {
+			updateCtx := admission.NewContextWithRequest(ctx, admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation: admissionv1.Update,
+			}})
+			Expect(defaulter.Default(updateCtx, obj)).To(Succeed())
+			Expect(obj.Spec.Probes).To(BeNil())
+
+			obj.Spec.Probes = &lstmappsv1.ProbesSpec{}
+			Expect(defaulter.Default(updateCtx, obj)).To(Succeed())
+			Expect(obj.Spec.Probes.Path).To(Equal("/healthz"))
+			Expect(obj.Spec.Probes.Liveness).To(Equal(&defaulter.DefaultLivenessProbe))
+		})
+
+		It("Should deny a health path that isn't absolute", func() {
+			obj.Spec.Probes = &lstmappsv1.ProbesSpec{Path: "healthz"}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should deny a timeout longer than the period", func() {
+			obj.Spec.Probes = &lstmappsv1.ProbesSpec{
+				Readiness: &lstmappsv1.ProbeTiming{PeriodSeconds: 5, TimeoutSeconds: 10, FailureThreshold: 3},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("When validating the monitoring of LSTMPredictApp", func() {
+		BeforeEach(func() {
+			validator = LSTMPredictAppCustomValidator{
+				MaxBackendAppReplicas: 10,
+				MinBackendAppReplicas: 1,
+				MaxPortID:             30000,
+				AvailableServiceType:  []string{"ClusterIP", "NodePort"},
+			}
+			defaulter = LSTMPredictAppCustomDefaulter{DefaultMetricsPath: "/metrics"}
+			obj.Spec = lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				BackendAppReplicas: ptr.To[int32](1),
+				ServiceType:        corev1.ServiceTypeClusterIP,
+				Ports: []lstmappsv1.PortSpec{
+					{Name: "http", ContainerPort: 8080, ServicePort: 80, Protocol: corev1.ProtocolTCP},
+					{Name: "metrics", ContainerPort: 9090, ServicePort: 9090, Protocol: corev1.ProtocolTCP},
+				},
+				Probes: &lstmappsv1.ProbesSpec{Disabled: true},
+			}
+		})
+
+		It("Should default the metrics path only when monitoring is enabled", func() {
+			obj.Spec.Monitoring = &lstmappsv1.MonitoringSpec{}
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.Monitoring.Path).To(BeEmpty())
+
+			obj.Spec.Monitoring.Enabled = true
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.Monitoring.Path).To(Equal("/metrics"))
+		})
+
+		It("Should admit a monitoring spec pointing at a named port", func() {
+			obj.Spec.Monitoring = &lstmappsv1.MonitoringSpec{
+				Enabled:  true,
+				Port:     "metrics",
+				Path:     "/metrics",
+				Interval: &metav1.Duration{Duration: 30 * time.Second},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should deny an unknown port, a relative path or a sub-second interval", func() {
+			obj.Spec.Monitoring = &lstmappsv1.MonitoringSpec{Enabled: true, Port: "admin", Path: "/metrics"}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+
+			obj.Spec.Monitoring.Port = ""
+			obj.Spec.Monitoring.Path = "metrics"
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+
+			obj.Spec.Monitoring.Path = "/metrics"
+			obj.Spec.Monitoring.Interval = &metav1.Duration{Duration: 1500 * time.Millisecond}
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("When scaling LSTMPredictApp through the scale subresource", func() {
+		var scaleValidator *LSTMPredictAppScaleValidator
+
+		scaleRequest := func(replicas int32) admission.Request {
+			scale := &autoscalingv1.Scale{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "autoscaling/v1", Kind: "Scale"},
+				ObjectMeta: metav1.ObjectMeta{Name: "predict", Namespace: "default"},
+				Spec:       autoscalingv1.ScaleSpec{Replicas: replicas},
+			}
+			raw, err := json.Marshal(scale)
+			Expect(err).NotTo(HaveOccurred())
+			return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Name:        "predict",
+				Namespace:   "default",
+				Operation:   admissionv1.Update,
+				SubResource: "scale",
+				Object:      runtime.RawExtension{Raw: raw},
+			}}
+		}
+
+		BeforeEach(func() {
+			scaleValidator = &LSTMPredictAppScaleValidator{
+				Validator: &LSTMPredictAppCustomValidator{MaxBackendAppReplicas: 10, MinBackendAppReplicas: 1},
+				Decoder:   admission.NewDecoder(clientgoscheme.Scheme),
+			}
+		})
+
+		It("Should admit replicas within the bounds", func() {
+			Expect(scaleValidator.Handle(ctx, scaleRequest(4)).Allowed).To(BeTrue())
+		})
+
+		It("Should deny replicas outside the bounds", func() {
+			response := scaleValidator.Handle(ctx, scaleRequest(11))
+			Expect(response.Allowed).To(BeFalse())
+			Expect(response.Result.Message).To(ContainSubstring("can't > 10"))
+
+			Expect(scaleValidator.Handle(ctx, scaleRequest(0)).Allowed).To(BeFalse())
+		})
+
+		It("Should deny scaling an app whose replicas are managed by the HPA", func() {
+			scheme := runtime.NewScheme()
+			Expect(lstmappsv1.AddToScheme(scheme)).To(Succeed())
+			app := &lstmappsv1.LSTMPredictApp{
+				ObjectMeta: metav1.ObjectMeta{Name: "predict", Namespace: "default"},
+				Spec: lstmappsv1.LSTMPredictAppSpec{
+					AppImage:           "lstm-predict-server:v1.0",
+					ContainerPort:      8080,
+					BackendAppReplicas: ptr.To[int32](1),
+					ServicePort:        80,
+					ServiceType:        corev1.ServiceTypeClusterIP,
+					Autoscaling:        &lstmappsv1.AutoscalingSpec{MaxReplicas: 5},
+				},
+			}
+			scaleValidator.Validator.Client = fake.NewClientBuilder().WithScheme(scheme).WithObjects(app).Build()
+
+			response := scaleValidator.Handle(ctx, scaleRequest(4))
+			Expect(response.Allowed).To(BeFalse())
+			Expect(response.Result.Message).To(ContainSubstring("spec.autoscaling enabled"))
+
+			app.Spec.Autoscaling = nil
+			scaleValidator.Validator.Client = fake.NewClientBuilder().WithScheme(scheme).WithObjects(app).Build()
+			Expect(scaleValidator.Handle(ctx, scaleRequest(4)).Allowed).To(BeTrue())
+		})
+	})
+
+	Context("When applying LSTMOperatorPolicies", func() {
+		newPolicyClient := func(policies ...*lstmappsv1.LSTMOperatorPolicy) client.Reader {
+			scheme := runtime.NewScheme()
+			Expect(lstmappsv1.AddToScheme(scheme)).To(Succeed())
+			builder := fake.NewClientBuilder().WithScheme(scheme)
+			for _, policy := range policies {
+				builder = builder.WithObjects(policy)
+			}
+			return builder.Build()
+		}
+		clusterPolicy := &lstmappsv1.LSTMOperatorPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+			Spec: lstmappsv1.LSTMOperatorPolicySpec{
+				MaxReplicas:            ptr.To[int32](20),
+				DefaultServicePort:     ptr.To[int32](9000),
+				AllowedImageRegistries: []string{"registry.example.com/forecast"},
+				MaxResources:           corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+				RequiredLabels:         []string{"team"},
+			},
+		}
+		teamPolicy := &lstmappsv1.LSTMOperatorPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "team-a"},
+			Spec: lstmappsv1.LSTMOperatorPolicySpec{
+				Namespaces:      []string{"team-a"},
+				MaxReplicas:     ptr.To[int32](50),
+				DefaultReplicas: ptr.To[int32](3),
+				DefaultResources: &corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+				},
+			},
+		}
+
+		BeforeEach(func() {
+			validator = LSTMPredictAppCustomValidator{
+				Client:                newPolicyClient(clusterPolicy, teamPolicy),
+				MaxBackendAppReplicas: 10,
+				MinBackendAppReplicas: 1,
+				MaxPortID:             30000,
+				AvailableServiceType:  []string{"ClusterIP", "NodePort"},
+			}
+			defaulter = LSTMPredictAppCustomDefaulter{
+				Client:                    validator.Client,
+				DefaultBackendAppReplicas: 1,
+				DefaultServicePort:        8001,
+				DefaultServiceType:        "ClusterIP",
+				MinResourcesLimit: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+				},
+			}
+			obj = &lstmappsv1.LSTMPredictApp{
+				ObjectMeta: metav1.ObjectMeta{Name: "predict", Namespace: "default", Labels: map[string]string{"team": "forecast"}},
+				Spec: lstmappsv1.LSTMPredictAppSpec{
+					AppImage:      "registry.example.com/forecast/lstm-predict-server:v1.0",
+					ContainerPort: 8080,
+					Probes:        &lstmappsv1.ProbesSpec{Disabled: true},
+				},
+			}
+		})
+
+		It("Should merge the cluster policy and the namespace policy into the defaults", func() {
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(*obj.Spec.BackendAppReplicas).To(Equal(int32(1)))
+			Expect(obj.Spec.ServicePort).To(Equal(int32(9000)))
+			Expect(obj.Spec.ResourcesLimit.Requests.Cpu().String()).To(Equal("100m"))
+
+			teamApp := &lstmappsv1.LSTMPredictApp{
+				ObjectMeta: metav1.ObjectMeta{Name: "predict", Namespace: "team-a"},
+				Spec:       lstmappsv1.LSTMPredictAppSpec{AppImage: obj.Spec.AppImage, ContainerPort: 8080, Probes: obj.Spec.Probes},
+			}
+			Expect(defaulter.Default(ctx, teamApp)).To(Succeed())
+			Expect(*teamApp.Spec.BackendAppReplicas).To(Equal(int32(3)))
+			Expect(teamApp.Spec.ServicePort).To(Equal(int32(9000)))
+			Expect(teamApp.Spec.ResourcesLimit.Requests.Cpu().String()).To(Equal("500m"))
+		})
+
+		It("Should enforce the replica bounds of the namespace", func() {
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			obj.Spec.BackendAppReplicas = ptr.To[int32](30)
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("can't > 20")))
+
+			obj.Namespace = "team-a"
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should bound the autoscaling maxReplicas only by the policy", func() {
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			obj.Spec.Autoscaling = &lstmappsv1.AutoscalingSpec{
+				MaxReplicas:                    30,
+				TargetCPUUtilizationPercentage: ptr.To[int32](80),
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("Autoscaling.MaxReplicas can't > 20")))
+
+			obj.Namespace = "team-a"
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+
+			// 没有策略时只受HPA自身的限制，不使用内置的副本数上限10
+			validator.Client = nil
+			obj.Namespace = "default"
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should deny images from other registries, oversized resources and missing labels", func() {
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+
+			obj.Spec.AppImage = "lstm-predict-server:v1.0"
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("not from an allowed registry")))
+
+			obj.Spec.AppImage = "registry.example.com/forecast-evil/lstm-predict-server:v1.0"
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+
+			obj.Spec.AppImage = "registry.example.com/forecast/lstm-predict-server:v1.0"
+			obj.Spec.ResourcesLimit.Limits = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4")}
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("ResourcesLimit.Limits.cpu")))
+
+			obj.Spec.ResourcesLimit.Limits = nil
+			obj.Labels = nil
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring(`label "team" is required`)))
+		})
+
+		It("Should resolve the registry of short and port-qualified image names", func() {
+			Expect(imageRegistryPath("busybox:1.36")).To(Equal("docker.io/busybox"))
+			Expect(imageRegistryPath("localhost:5000/lstm:v1")).To(Equal("localhost:5000/lstm"))
+			Expect(imageRegistryPath("registry.example.com/forecast/lstm@sha256:abc")).To(Equal("registry.example.com/forecast/lstm"))
+		})
+	})
+
+	Context("When enforcing the image policy", func() {
+		const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+		var (
+			registry *httptest.Server
+			host     string
+		)
+
+		BeforeEach(func() {
+			// 本地的仓库替身：只提供lstm-predict-server:v1.0的清单，并且要求Bearer Token
+			mux := http.NewServeMux()
+			mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.URL.Query().Get("scope")).To(Equal("repository:forecast/lstm-predict-server:pull"))
+				Expect(json.NewEncoder(w).Encode(map[string]string{"token": "anonymous"})).To(Succeed())
+			})
+			mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer anonymous" {
+					w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token",service="registry",scope="repository:forecast/lstm-predict-server:pull"`, host))
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				Expect(r.Method).To(Equal(http.MethodHead))
+				Expect(r.Header.Get("Accept")).To(ContainSubstring("application/vnd.oci.image.index.v1+json"))
+				if r.URL.Path != "/v2/forecast/lstm-predict-server/manifests/v1.0" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.Header().Set("Docker-Content-Digest", digest)
+			})
+			registry = httptest.NewServer(mux)
+			host = strings.TrimPrefix(registry.URL, "http://")
+
+			validator = LSTMPredictAppCustomValidator{
+				MaxBackendAppReplicas:  10,
+				MinBackendAppReplicas:  1,
+				MaxPortID:              30000,
+				AvailableServiceType:   []string{"ClusterIP"},
+				ForbidMutableImageTags: true,
+			}
+			defaulter = LSTMPredictAppCustomDefaulter{
+				DefaultBackendAppReplicas: 1,
+				DefaultServicePort:        8001,
+				DefaultServiceType:        "ClusterIP",
+				DigestResolver:            &RegistryDigestResolver{PlainHTTPRegistries: []string{host}},
+			}
+			obj = &lstmappsv1.LSTMPredictApp{
+				ObjectMeta: metav1.ObjectMeta{Name: "predict", Namespace: "default"},
+				Spec: lstmappsv1.LSTMPredictAppSpec{
+					AppImage:      host + "/forecast/lstm-predict-server:v1.0",
+					ContainerPort: 8080,
+					Probes:        &lstmappsv1.ProbesSpec{Disabled: true},
+				},
+			}
+		})
+
+		AfterEach(func() {
+			registry.Close()
+		})
+
+		It("Should deny latest and untagged images", func() {
+			defaulter.DigestResolver = nil
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, image := range []string{"lstm-predict-server", "lstm-predict-server:latest", "localhost:5000/lstm-predict-server"} {
+				obj.Spec.AppImage = image
+				_, err = validator.ValidateCreate(ctx, obj)
+				Expect(err).To(MatchError(ContainSubstring("uses a mutable tag")), image)
+			}
+
+			obj.Spec.AppImage = "lstm-predict-server:latest@" + digest
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should allow mutable tags unless the rule is enabled", func() {
+			validator.ForbidMutableImageTags = false
+			defaulter.DigestResolver = nil
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			obj.Spec.AppImage = "lstm-predict-server:latest"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should apply the image policy to the model fetcher images", func() {
+			validator.AllowedImageRegistries = []string{host}
+			defaulter.DigestResolver = nil
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			obj.Spec.Model = &lstmappsv1.ModelSpec{HTTP: &lstmappsv1.HTTPModelSource{
+				URL:             "https://models.example.com/lstm.h5",
+				SHA256:          strings.Repeat("a", 64),
+				DownloaderImage: "busybox:1.36",
+			}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("Model.HTTP.DownloaderImage")))
+
+			obj.Spec.Model.HTTP.DownloaderImage = host + "/busybox:latest"
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("uses a mutable tag")))
+
+			obj.Spec.Model = &lstmappsv1.ModelSpec{OCI: &lstmappsv1.OCIModelSource{
+				Artifact:    "registry.example.com/models/lstm:v3",
+				PullerImage: "ghcr.io/oras-project/oras:v1.2.0",
+			}}
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("Model.OCI.PullerImage")))
+		})
+
+		It("Should skip images that are unchanged on update", func() {
+			oldObj = &lstmappsv1.LSTMPredictApp{
+				ObjectMeta: metav1.ObjectMeta{Name: "predict", Namespace: "default"},
+				Spec: lstmappsv1.LSTMPredictAppSpec{
+					AppImage:           "lstm-predict-server:latest",
+					ContainerPort:      8080,
+					BackendAppReplicas: ptr.To[int32](1),
+					ServicePort:        80,
+					ServiceType:        corev1.ServiceTypeClusterIP,
+				},
+			}
+			obj = oldObj.DeepCopy()
+			obj.Spec.BackendAppReplicas = ptr.To[int32](2)
+			_, err := validator.ValidateUpdate(ctx, oldObj, obj)
+			Expect(err).NotTo(HaveOccurred())
+
+			obj.Spec.AppImage = "lstm-predict-server"
+			_, err = validator.ValidateUpdate(ctx, oldObj, obj)
+			Expect(err).To(MatchError(ContainSubstring("uses a mutable tag")))
+		})
+
+		It("Should require digests when configured", func() {
+			validator.RequireImageDigest = true
+			defaulter.DigestResolver = nil
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("must be pinned by digest")))
+
+			obj.Spec.AppImage = "lstm-predict-server@sha256:abc"
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("is illeagle")))
+
+			obj.Spec.AppImage = "lstm-predict-server@" + digest
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should pin the image to the digest resolved from the registry", func() {
+			validator.RequireImageDigest = true
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.AppImage).To(Equal(host + "/forecast/lstm-predict-server:v1.0@" + digest))
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("keeping images that are already pinned")
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.AppImage).To(Equal(host + "/forecast/lstm-predict-server:v1.0@" + digest))
+		})
+
+		It("Should deny the object when the tag can't be resolved", func() {
+			obj.Spec.AppImage = host + "/forecast/lstm-predict-server:v9.9"
+			Expect(defaulter.Default(ctx, obj)).To(MatchError(ContainSubstring("404 Not Found")))
+		})
+
+		It("Should split image references into their parts", func() {
+			Expect(parseImageReference("busybox")).To(Equal(imageReference{Registry: "docker.io", Repository: "busybox"}))
+			Expect(parseImageReference("localhost:5000/forecast/lstm:v1@" + digest)).To(Equal(imageReference{
+				Registry: "localhost:5000", Repository: "forecast/lstm", Tag: "v1", Digest: digest,
+			}))
+		})
+	})
+
+	Context("When updating only the metadata of LSTMPredictApp", func() {
+		BeforeEach(func() {
+			validator = LSTMPredictAppCustomValidator{
+				MaxBackendAppReplicas: 10,
+				MinBackendAppReplicas: 1,
+				MaxPortID:             30000,
+				AvailableServiceType:  []string{"ClusterIP", "NodePort"},
+				RequiredLabels:        []string{"team"},
+			}
+			// 规则变化之前创建的对象，已经不满足当前的副本数上限与标签要求
+			oldObj = &lstmappsv1.LSTMPredictApp{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "predict",
+					Namespace:  "default",
+					Finalizers: []string{"lstmapps.wuyong7240.com/cleanup"},
+				},
+				Spec: lstmappsv1.LSTMPredictAppSpec{
+					AppImage:           "lstm-predict-server:v1.0",
+					ContainerPort:      8080,
+					BackendAppReplicas: ptr.To[int32](50),
+					ServicePort:        80,
+					ServiceType:        corev1.ServiceTypeClusterIP,
+				},
+			}
+			obj = oldObj.DeepCopy()
+		})
+
+		It("Should admit removing the finalizer of an object being deleted", func() {
+			obj.DeletionTimestamp = ptr.To(metav1.Now())
+			obj.Finalizers = nil
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec).To(Equal(oldObj.Spec))
+			_, err := validator.ValidateUpdate(ctx, oldObj, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should admit annotation changes and validate spec changes", func() {
+			obj.Annotations = map[string]string{"lstmapps.wuyong7240.com/promote": "true"}
+			_, err := validator.ValidateUpdate(ctx, oldObj, obj)
+			Expect(err).NotTo(HaveOccurred())
+
+			obj.Spec.BackendAppReplicas = ptr.To[int32](40)
+			_, err = validator.ValidateUpdate(ctx, oldObj, obj)
+			Expect(err).To(MatchError(ContainSubstring("can't > 10")))
+		})
+	})
+
+	Context("When deleting LSTMPredictApp under Validating Webhook", func() {
+		BeforeEach(func() {
+			validator = LSTMPredictAppCustomValidator{
+				MaxBackendAppReplicas: 10,
+				MinBackendAppReplicas: 1,
+				MaxPortID:             30000,
+				AvailableServiceType:  []string{"ClusterIP", "NodePort"},
+			}
+			obj.Spec = lstmappsv1.LSTMPredictAppSpec{
+				AppImage:           "lstm-predict-server:v1.0",
+				ContainerPort:      8080,
+				BackendAppReplicas: ptr.To[int32](1),
+				ServicePort:        80,
+				ServiceType:        corev1.ServiceTypeClusterIP,
+			}
+		})
+
+		It("Should admit deleting an object that no longer passes spec validation", func() {
+			obj.Spec.BackendAppReplicas = ptr.To[int32](50)
+			_, err := validator.ValidateDelete(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should deny deleting an object with deletion protection", func() {
+			obj.Spec.DeletionProtection = true
+			_, err := validator.ValidateDelete(ctx, obj)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
 })
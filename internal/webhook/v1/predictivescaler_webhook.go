@@ -0,0 +1,109 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+// nolint:unused
+// log is for logging in this package.
+var predictivescalerlog = logf.Log.WithName("predictivescaler-resource")
+
+// SetupPredictiveScalerWebhookWithManager registers the webhook for PredictiveScaler in the manager.
+func SetupPredictiveScalerWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&lstmappsv1.PredictiveScaler{}).
+		WithValidator(&PredictiveScalerCustomValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-lstmapps-wuyong7240-com-v1-predictivescaler,mutating=false,failurePolicy=fail,sideEffects=None,groups=lstmapps.wuyong7240.com,resources=predictivescalers,verbs=create;update,versions=v1,name=vpredictivescaler-v1.kb.io,admissionReviewVersions=v1
+
+// PredictiveScalerCustomValidator 按LSTMOperatorPolicy校验PredictiveScaler的副本数上限，
+// minReplicas/maxReplicas之间的关系以及各个时间的取值由CRD中的校验规则保证
+//
+// NOTE: The +kubebuilder:object:generate=false marker prevents controller-gen from generating DeepCopy methods,
+// as this struct is used only for temporary operations and does not need to be deeply copied.
+type PredictiveScalerCustomValidator struct {
+	// 用于读取LSTMOperatorPolicy，为空时不限制
+	Client client.Reader
+}
+
+var _ webhook.CustomValidator = &PredictiveScalerCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type PredictiveScaler.
+func (v *PredictiveScalerCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	predictivescaler, ok := obj.(*lstmappsv1.PredictiveScaler)
+	if !ok {
+		return nil, fmt.Errorf("expected a PredictiveScaler object but got %T", obj)
+	}
+	predictivescalerlog.Info("Validation for PredictiveScaler upon creation", "name", predictivescaler.GetName())
+
+	return nil, v.validateReplicaBounds(ctx, predictivescaler)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type PredictiveScaler.
+func (v *PredictiveScalerCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	predictivescaler, ok := newObj.(*lstmappsv1.PredictiveScaler)
+	if !ok {
+		return nil, fmt.Errorf("expected a PredictiveScaler object for the newObj but got %T", newObj)
+	}
+	predictivescalerlog.Info("Validation for PredictiveScaler upon update", "name", predictivescaler.GetName())
+	oldPredictiveScaler, ok := oldObj.(*lstmappsv1.PredictiveScaler)
+	if !ok {
+		return nil, fmt.Errorf("expected a PredictiveScaler object for the oldObj but got %T", oldObj)
+	}
+
+	// 与LSTMPredictApp一样，正在删除的对象以及没有修改Spec的更新不再校验，收紧策略后已有的对象仍然可以被删除
+	if !predictivescaler.DeletionTimestamp.IsZero() ||
+		equality.Semantic.DeepEqual(oldPredictiveScaler.Spec, predictivescaler.Spec) {
+		return nil, nil
+	}
+	return nil, v.validateReplicaBounds(ctx, predictivescaler)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type PredictiveScaler.
+func (v *PredictiveScalerCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateReplicaBounds 校验maxReplicas不超过对该命名空间生效的策略中的maxReplicas
+func (v *PredictiveScalerCustomValidator) validateReplicaBounds(ctx context.Context, predictivescaler *lstmappsv1.PredictiveScaler) error {
+	namespace := predictivescaler.Namespace
+	if req, err := admission.RequestFromContext(ctx); namespace == "" && err == nil {
+		namespace = req.Namespace
+	}
+	policy, err := effectivePolicy(ctx, v.Client, namespace)
+	if err != nil {
+		return err
+	}
+	if policy.MaxReplicas != nil && predictivescaler.Spec.MaxReplicas > *policy.MaxReplicas {
+		return fmt.Errorf("MaxReplicas can't > %d", *policy.MaxReplicas)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2025 wuyong7240.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	lstmappsv1 "github.com/WyYong7240/LSTMServiceOperator/api/v1"
+)
+
+var _ = Describe("PredictiveScaler Webhook", func() {
+	var (
+		obj       *lstmappsv1.PredictiveScaler
+		validator PredictiveScalerCustomValidator
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(lstmappsv1.AddToScheme(scheme)).To(Succeed())
+		validator = PredictiveScalerCustomValidator{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				&lstmappsv1.LSTMOperatorPolicy{
+					ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+					Spec:       lstmappsv1.LSTMOperatorPolicySpec{MaxReplicas: ptr.To[int32](20)},
+				},
+				&lstmappsv1.LSTMOperatorPolicy{
+					ObjectMeta: metav1.ObjectMeta{Name: "team-a"},
+					Spec: lstmappsv1.LSTMOperatorPolicySpec{
+						Namespaces:  []string{"team-a"},
+						MaxReplicas: ptr.To[int32](50),
+					},
+				},
+			).Build(),
+		}
+		obj = &lstmappsv1.PredictiveScaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "scaler", Namespace: "default"},
+			Spec: lstmappsv1.PredictiveScalerSpec{
+				MinReplicas: 1,
+				MaxReplicas: 30,
+			},
+		}
+	})
+
+	Context("When validating the replica bounds of PredictiveScaler", func() {
+		It("Should deny maxReplicas above the policy of the namespace", func() {
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("MaxReplicas can't > 20")))
+
+			obj.Namespace = "team-a"
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should not limit maxReplicas without a policy", func() {
+			validator.Client = nil
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should only validate updates that change the spec", func() {
+			oldObj := obj.DeepCopy()
+			obj.Labels = map[string]string{"team": "forecast"}
+			_, err := validator.ValidateUpdate(ctx, oldObj, obj)
+			Expect(err).NotTo(HaveOccurred())
+
+			obj.Spec.MaxReplicas = 25
+			_, err = validator.ValidateUpdate(ctx, oldObj, obj)
+			Expect(err).To(MatchError(ContainSubstring("MaxReplicas can't > 20")))
+		})
+	})
+})
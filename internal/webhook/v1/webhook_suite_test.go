@@ -109,7 +109,10 @@ var _ = BeforeSuite(func() {
 	})
 	Expect(err).NotTo(HaveOccurred())
 
-	err = SetupLSTMPredictAppWebhookWithManager(mgr)
+	err = SetupLSTMPredictAppWebhookWithManager(mgr, ImagePolicyOptions{})
+	Expect(err).NotTo(HaveOccurred())
+
+	err = SetupPredictiveScalerWebhookWithManager(mgr)
 	Expect(err).NotTo(HaveOccurred())
 
 	// +kubebuilder:scaffold:webhook